@@ -0,0 +1,34 @@
+// Package auth carries the authenticated user of a request in its context
+package auth
+
+import "context"
+
+// Principal is the user a request was authenticated as
+type Principal struct {
+	UserID      int64
+	Name        string
+	PhoneNumber string
+	// SessionID is the login session the access token belongs to
+	SessionID int64
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored by WithPrincipal, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserID returns the ID of the authenticated user, or 0 for anonymous requests
+func UserID(ctx context.Context) int64 {
+	if p, ok := FromContext(ctx); ok {
+		return p.UserID
+	}
+	return 0
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-rent/utils"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is read when GORENT_CONFIG is not set. It is optional.
+const DefaultPath = "config.yaml"
+
+// Config is the runtime configuration of the server
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	SMS      SMSConfig      `yaml:"sms"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// NodeID is part of every generated primary key. Each process writing
+	// to the same database needs its own.
+	NodeID int `yaml:"node_id"`
+}
+
+type DatabaseConfig struct {
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	Name            string        `yaml:"name"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// DSN returns the go-sql-driver/mysql data source name
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", c.User, c.Password, c.Host, c.Port, c.Name)
+}
+
+type AuthConfig struct {
+	// JWTSecret signs session tokens. It has no default on purpose.
+	JWTSecret string `yaml:"jwt_secret"`
+	// TokenTTL is the lifetime of an access token
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	// RefreshTTL is how long a session lasts without being refreshed
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+	// OTPTTL is how long a one-time code sent by SMS stays valid
+	OTPTTL time.Duration `yaml:"otp_ttl"`
+	// OTPMaxAttempts is how many wrong guesses a one-time code allows
+	OTPMaxAttempts int `yaml:"otp_max_attempts"`
+	// LoginMaxFailures is how many failed logins lock a phone number out
+	LoginMaxFailures int `yaml:"login_max_failures"`
+	// LoginLockout is how long the lockout lasts
+	LoginLockout time.Duration `yaml:"login_lockout"`
+}
+
+type SMSConfig struct {
+	// Sender is "log" to write messages to the server log, with codes
+	// masked, or "file" to append them to File. Both are for development
+	// and there is no default, so a server never falls back to one.
+	Sender string `yaml:"sender"`
+	File   string `yaml:"file"`
+}
+
+type LogConfig struct {
+	// Level is "debug", "info", "warn" or "error". Request and response
+	// bodies are only logged at debug.
+	Level string `yaml:"level"`
+	// Format is "text" or "json"
+	Format string `yaml:"format"`
+	// Redact lists the field, header and attribute names whose values are
+	// never logged. A name matches if it is one of these or contains one
+	// as a part separated by "_", "-" or ".", e.g. "new_password".
+	Redact []string `yaml:"redact"`
+}
+
+// Default returns the configuration used for anything the file and the
+// environment leave unset
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:         ":8080",
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "3306",
+			Name:            "rent",
+			MaxOpenConns:    50,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: time.Hour,
+			ConnMaxIdleTime: 30 * time.Minute,
+		},
+		Auth: AuthConfig{
+			TokenTTL:         15 * time.Minute,
+			RefreshTTL:       30 * 24 * time.Hour,
+			OTPTTL:           5 * time.Minute,
+			OTPMaxAttempts:   5,
+			LoginMaxFailures: 10,
+			LoginLockout:     30 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+			Redact: []string{"password", "token", "nid", "otp", "code", "authorization", "cookie"},
+		},
+	}
+}
+
+// Path returns the config file to load: $GORENT_CONFIG or DefaultPath
+func Path() string {
+	if path := os.Getenv("GORENT_CONFIG"); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// Load builds the configuration from the defaults, the YAML file at path
+// and GORENT_* environment variables, in increasing order of precedence.
+// A missing file is only an error when it was named explicitly through
+// GORENT_CONFIG. Callers validate the parts they use.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && os.Getenv("GORENT_CONFIG") == "":
+		// no file, defaults and environment only
+	default:
+		return nil, fmt.Errorf("error reading config file %s: %v", path, err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyEnv overrides fields with the GORENT_* environment variables that are set
+func (c *Config) applyEnv() error {
+	stringVars := []struct {
+		name string
+		dst  *string
+	}{
+		{"GORENT_SERVER_ADDR", &c.Server.Addr},
+		{"GORENT_DB_USER", &c.Database.User},
+		{"GORENT_DB_PASSWORD", &c.Database.Password},
+		{"GORENT_DB_HOST", &c.Database.Host},
+		{"GORENT_DB_PORT", &c.Database.Port},
+		{"GORENT_DB_NAME", &c.Database.Name},
+		{"GORENT_JWT_SECRET", &c.Auth.JWTSecret},
+		{"GORENT_SMS_SENDER", &c.SMS.Sender},
+		{"GORENT_SMS_FILE", &c.SMS.File},
+		{"GORENT_LOG_LEVEL", &c.Log.Level},
+		{"GORENT_LOG_FORMAT", &c.Log.Format},
+	}
+	for _, v := range stringVars {
+		if value, ok := os.LookupEnv(v.name); ok {
+			*v.dst = value
+		}
+	}
+
+	durationVars := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"GORENT_SERVER_READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"GORENT_SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"GORENT_SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"GORENT_DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime},
+		{"GORENT_DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime},
+		{"GORENT_TOKEN_TTL", &c.Auth.TokenTTL},
+		{"GORENT_REFRESH_TTL", &c.Auth.RefreshTTL},
+		{"GORENT_OTP_TTL", &c.Auth.OTPTTL},
+		{"GORENT_LOGIN_LOCKOUT", &c.Auth.LoginLockout},
+	}
+	for _, v := range durationVars {
+		if value, ok := os.LookupEnv(v.name); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", v.name, err)
+			}
+			*v.dst = d
+		}
+	}
+
+	intVars := []struct {
+		name string
+		dst  *int
+	}{
+		{"GORENT_SERVER_NODE_ID", &c.Server.NodeID},
+		{"GORENT_DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns},
+		{"GORENT_DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns},
+		{"GORENT_OTP_MAX_ATTEMPTS", &c.Auth.OTPMaxAttempts},
+		{"GORENT_LOGIN_MAX_FAILURES", &c.Auth.LoginMaxFailures},
+	}
+	for _, v := range intVars {
+		if value, ok := os.LookupEnv(v.name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", v.name, err)
+			}
+			*v.dst = n
+		}
+	}
+
+	// GORENT_LOG_REDACT is a comma-separated list that replaces log.redact
+	if value, ok := os.LookupEnv("GORENT_LOG_REDACT"); ok {
+		c.Log.Redact = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Log.Redact = append(c.Log.Redact, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports every missing or invalid setting at once
+func (c *Config) Validate() error {
+	var problems []string
+	problems = append(problems, c.Server.problems()...)
+	problems = append(problems, c.Database.problems()...)
+	problems = append(problems, c.Auth.problems()...)
+	problems = append(problems, c.SMS.problems()...)
+	problems = append(problems, c.Log.problems()...)
+	return invalid(problems)
+}
+
+// Validate checks only the database settings, for commands such as
+// "migrate" that never serve requests
+func (c DatabaseConfig) Validate() error {
+	return invalid(c.problems())
+}
+
+func invalid(problems []string) error {
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c ServerConfig) problems() []string {
+	var problems []string
+	if c.Addr == "" {
+		problems = append(problems, "server.addr is required")
+	}
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.IdleTimeout <= 0 {
+		problems = append(problems, "server timeouts must be positive")
+	}
+	if c.NodeID < 0 || c.NodeID > utils.MaxNodeID {
+		problems = append(problems, fmt.Sprintf("server.node_id must be between 0 and %d", utils.MaxNodeID))
+	}
+	return problems
+}
+
+func (c DatabaseConfig) problems() []string {
+	var problems []string
+	if c.User == "" {
+		problems = append(problems, "database.user is required")
+	}
+	if c.Host == "" {
+		problems = append(problems, "database.host is required")
+	}
+	if _, err := strconv.Atoi(c.Port); err != nil {
+		problems = append(problems, "database.port must be a number")
+	}
+	if c.Name == "" {
+		problems = append(problems, "database.name is required")
+	}
+	if c.MaxOpenConns <= 0 || c.MaxIdleConns < 0 {
+		problems = append(problems, "database connection pool sizes must be positive")
+	}
+	return problems
+}
+
+func (c AuthConfig) problems() []string {
+	var problems []string
+	if len(c.JWTSecret) < 32 {
+		problems = append(problems, "auth.jwt_secret must be at least 32 characters")
+	}
+	if c.TokenTTL <= 0 {
+		problems = append(problems, "auth.token_ttl must be positive")
+	}
+	if c.RefreshTTL < c.TokenTTL {
+		problems = append(problems, "auth.refresh_ttl must not be shorter than auth.token_ttl")
+	}
+	if c.OTPTTL <= 0 {
+		problems = append(problems, "auth.otp_ttl must be positive")
+	}
+	if c.OTPMaxAttempts <= 0 {
+		problems = append(problems, "auth.otp_max_attempts must be positive")
+	}
+	if c.LoginMaxFailures <= 0 {
+		problems = append(problems, "auth.login_max_failures must be positive")
+	}
+	if c.LoginLockout <= 0 {
+		problems = append(problems, "auth.login_lockout must be positive")
+	}
+	return problems
+}
+
+func (c SMSConfig) problems() []string {
+	switch c.Sender {
+	case "log":
+		return nil
+	case "file":
+		if c.File == "" {
+			return []string{"sms.file is required when sms.sender is file"}
+		}
+		return nil
+	case "":
+		return []string{`sms.sender is required, "log" or "file" for development`}
+	default:
+		return []string{`sms.sender must be "log" or "file"`}
+	}
+}
+
+func (c LogConfig) problems() []string {
+	var problems []string
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		problems = append(problems, `log.level must be "debug", "info", "warn" or "error"`)
+	}
+	if c.Format != "text" && c.Format != "json" {
+		problems = append(problems, `log.format must be "text" or "json"`)
+	}
+	return problems
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMSSenderHasNoDefault(t *testing.T) {
+	cfg := Default()
+	cfg.Database.User = "rent"
+	cfg.Auth.JWTSecret = strings.Repeat("s", 32)
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "sms.sender is required") {
+		t.Fatalf("Validate of the defaults returned %v, want sms.sender to be required", err)
+	}
+
+	cfg.SMS.Sender = "log"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate with the log sender: %v", err)
+	}
+}
@@ -4,25 +4,19 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
-	"time"
 )
 
-const (
-	DBUser     = "suma"      // Change this to your MySQL username
-	DBPassword = "tMyc6mApj]wgzHl7"          // Change this to your MySQL password
-	DBHost     = "localhost" // Change this if your MySQL is on a different host
-	DBPort     = "3307"      // Default MySQL port
-	DBName     = "rent"      // Your database name
+var (
+	db       *sql.DB
+	dbConfig DatabaseConfig
 )
 
-var db *sql.DB
-
 // InitDB initializes the database connection
-func InitDB() error {
+func InitDB(cfg DatabaseConfig) error {
 	var err error
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", DBUser, DBPassword, DBHost, DBPort, DBName)
-	
-	db, err = sql.Open("mysql", dsn)
+	dbConfig = cfg
+
+	db, err = sql.Open("mysql", cfg.DSN())
 	if err != nil {
 		return fmt.Errorf("error opening database: %v", err)
 	}
@@ -34,10 +28,10 @@ func InitDB() error {
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(50)                // Increased from 25
-	db.SetMaxIdleConns(25)               // Keep idle connections
-	db.SetConnMaxLifetime(time.Hour)     // Maximum lifetime of a connection
-	db.SetConnMaxIdleTime(30 * time.Minute) // Maximum idle time of a connection
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return nil
 }
@@ -51,10 +45,10 @@ func GetDBConnection() (*sql.DB, error) {
 	// Verify the connection is still alive
 	if err := db.Ping(); err != nil {
 		// Try to reinitialize the connection
-		if err := InitDB(); err != nil {
+		if err := InitDB(dbConfig); err != nil {
 			return nil, fmt.Errorf("database connection lost and failed to reconnect: %v", err)
 		}
 	}
 	
 	return db, nil
-} 
\ No newline at end of file
+} 
@@ -1,14 +1,11 @@
 module go-rent
 
-go 1.18
+go 1.21
 
 require (
 	github.com/go-sql-driver/mysql v1.7.1
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/gorilla/mux v1.8.1
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
-)
-
-require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
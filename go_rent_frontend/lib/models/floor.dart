@@ -1,3 +1,5 @@
+import 'unit.dart';
+
 class Floor {
   final int id;
   final String name;
@@ -6,6 +8,11 @@ class Floor {
   final int? tenant;
   final String? status;
   final int? notificationId;
+  // Set for floors that were deleted and can be restored
+  final String? archivedAt;
+  // Rent, tenant, status and notificationId above repeat the unit of a
+  // floor that has only one
+  final List<Unit> units;
 
   Floor({
     required this.id,
@@ -15,6 +22,8 @@ class Floor {
     this.tenant,
     this.status,
     this.notificationId,
+    this.archivedAt,
+    this.units = const [],
   });
 
   factory Floor.fromJson(Map<String, dynamic> json) {
@@ -26,6 +35,10 @@ class Floor {
       tenant: json['tenant'],
       status: json['status'],
       notificationId: json['notification_id'],
+      archivedAt: json['archived_at'],
+      units: (json['units'] as List<dynamic>? ?? [])
+          .map((unit) => Unit.fromJson(unit))
+          .toList(),
     );
   }
 
@@ -38,6 +51,8 @@ class Floor {
       'tenant': tenant,
       'status': status,
       'notification_id': notificationId,
+      'archived_at': archivedAt,
+      'units': units.map((unit) => unit.toJson()).toList(),
     };
   }
 } 
\ No newline at end of file
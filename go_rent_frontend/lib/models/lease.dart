@@ -0,0 +1,58 @@
+// The terms of a tenancy. Dates are YYYY-MM-DD and both are part of the
+// lease; billingDay is the day of the month the rent is due.
+class Lease {
+  final int id;
+  final int tenancyId;
+  final int unitId;
+  final int tenantId;
+  final String startDate;
+  final String endDate;
+  final int rent;
+  final int deposit;
+  final int noticeDays;
+  final int billingDay;
+  final String clauses;
+
+  Lease({
+    required this.id,
+    required this.tenancyId,
+    required this.unitId,
+    required this.tenantId,
+    required this.startDate,
+    required this.endDate,
+    required this.rent,
+    required this.deposit,
+    required this.noticeDays,
+    required this.billingDay,
+    this.clauses = '',
+  });
+
+  factory Lease.fromJson(Map<String, dynamic> json) {
+    return Lease(
+      id: json['id'],
+      tenancyId: json['tenancy_id'],
+      unitId: json['unit_id'],
+      tenantId: json['tenant_id'],
+      startDate: json['start_date'],
+      endDate: json['end_date'],
+      rent: json['rent'],
+      deposit: json['deposit'],
+      noticeDays: json['notice_days'],
+      billingDay: json['billing_day'],
+      clauses: json['clauses'] ?? '',
+    );
+  }
+
+  // The body of a create or update request
+  Map<String, dynamic> toJson() {
+    return {
+      'start_date': startDate,
+      'end_date': endDate,
+      'rent': rent,
+      'deposit': deposit,
+      'notice_days': noticeDays,
+      'billing_day': billingDay,
+      'clauses': clauses,
+    };
+  }
+}
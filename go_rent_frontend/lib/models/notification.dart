@@ -5,6 +5,11 @@ class AppNotification {
   final String createdAt;
   final NotificationProperty property;
   final NotificationFloor floor;
+  // Unit of the floor the notification is about, it has the same id and
+  // name shape as the floor
+  final NotificationFloor? unit;
+  // Role offered by an invitation to join the property, null otherwise
+  final String? role;
   final bool showActions;
 
   AppNotification({
@@ -14,6 +19,8 @@ class AppNotification {
     required this.createdAt,
     required this.property,
     required this.floor,
+    this.unit,
+    this.role,
     required this.showActions,
   });
 
@@ -25,6 +32,8 @@ class AppNotification {
       createdAt: json['created_at'],
       property: NotificationProperty.fromJson(json['property']),
       floor: NotificationFloor.fromJson(json['floor']),
+      unit: json['unit'] != null ? NotificationFloor.fromJson(json['unit']) : null,
+      role: json['role'],
       showActions: json['show_actions'] ?? false,
     );
   }
@@ -37,6 +46,8 @@ class AppNotification {
       'created_at': createdAt,
       'property': property.toJson(),
       'floor': floor.toJson(),
+      if (unit != null) 'unit': unit!.toJson(),
+      if (role != null) 'role': role,
       'show_actions': showActions,
     };
   }
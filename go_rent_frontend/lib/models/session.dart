@@ -0,0 +1,31 @@
+class Session {
+  final int id;
+  final String deviceName;
+  final String userAgent;
+  final String ipAddress;
+  final String createdAt;
+  final String lastSeenAt;
+  final bool current;
+
+  Session({
+    required this.id,
+    required this.deviceName,
+    required this.userAgent,
+    required this.ipAddress,
+    required this.createdAt,
+    required this.lastSeenAt,
+    required this.current,
+  });
+
+  factory Session.fromJson(Map<String, dynamic> json) {
+    return Session(
+      id: json['id'],
+      deviceName: json['device_name'] ?? '',
+      userAgent: json['user_agent'] ?? '',
+      ipAddress: json['ip_address'] ?? '',
+      createdAt: json['created_at'],
+      lastSeenAt: json['last_seen_at'],
+      current: json['current'] ?? false,
+    );
+  }
+}
@@ -0,0 +1,40 @@
+// A tenant's stay in a unit. endDate is null while the tenancy is active.
+class Tenancy {
+  final int id;
+  final int unitId;
+  final String unitName;
+  final int tenantId;
+  final String tenantName;
+  final String startDate;
+  final String? endDate;
+  final int rent;
+  final String status;
+
+  Tenancy({
+    required this.id,
+    required this.unitId,
+    required this.unitName,
+    required this.tenantId,
+    required this.tenantName,
+    required this.startDate,
+    this.endDate,
+    required this.rent,
+    required this.status,
+  });
+
+  bool get isActive => status == 'active';
+
+  factory Tenancy.fromJson(Map<String, dynamic> json) {
+    return Tenancy(
+      id: json['id'],
+      unitId: json['unit_id'],
+      unitName: json['unit_name'],
+      tenantId: json['tenant_id'],
+      tenantName: json['tenant_name'],
+      startDate: json['start_date'],
+      endDate: json['end_date'],
+      rent: json['rent'],
+      status: json['status'],
+    );
+  }
+}
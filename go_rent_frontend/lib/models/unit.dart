@@ -0,0 +1,49 @@
+// A flat, room or shop on a floor. Rent, tenant and tenant requests belong
+// to the unit.
+class Unit {
+  final int id;
+  final int floorId;
+  final String name;
+  final int rent;
+  final String createdAt;
+  final int? tenant;
+  final String? status;
+  final int? notificationId;
+
+  Unit({
+    required this.id,
+    required this.floorId,
+    required this.name,
+    required this.rent,
+    required this.createdAt,
+    this.tenant,
+    this.status,
+    this.notificationId,
+  });
+
+  factory Unit.fromJson(Map<String, dynamic> json) {
+    return Unit(
+      id: json['id'],
+      floorId: json['floor_id'],
+      name: json['name'],
+      rent: json['rent'],
+      createdAt: json['created_at'],
+      tenant: json['tenant'],
+      status: json['status'],
+      notificationId: json['notification_id'],
+    );
+  }
+
+  Map<String, dynamic> toJson() {
+    return {
+      'id': id,
+      'floor_id': floorId,
+      'name': name,
+      'rent': rent,
+      'created_at': createdAt,
+      'tenant': tenant,
+      'status': status,
+      'notification_id': notificationId,
+    };
+  }
+}
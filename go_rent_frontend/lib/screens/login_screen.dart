@@ -135,7 +135,12 @@ class _LoginScreenState extends State<LoginScreen> {
                     ? const CircularProgressIndicator()
                     : const Text('Login'),
               ),
-              const SizedBox(height: 16),
+              TextButton(
+                onPressed: () {
+                  Navigator.pushNamed(context, '/forgot-password');
+                },
+                child: const Text('Forgot password?'),
+              ),
               TextButton(
                 onPressed: () {
                   Navigator.pushReplacementNamed(context, '/register');
@@ -149,6 +149,11 @@ class _NotificationsScreenState extends State<NotificationsScreen> {
                                       'Floor: ${notification.floor.name}',
                                       style: const TextStyle(color: Colors.grey),
                                     ),
+                                  if (notification.role != null)
+                                    Text(
+                                      'Role: ${notification.role!.replaceAll('_', ' ')}',
+                                      style: const TextStyle(color: Colors.grey),
+                                    ),
                                   Text(
                                     'Status: ${notification.status}',
                                     style: TextStyle(
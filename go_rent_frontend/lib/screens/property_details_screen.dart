@@ -21,6 +21,11 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
   Property? _property;
   int _unreadNotifications = 0;
   bool _isManager = false;
+  // What the user's role on the property allows, see policy.Action on the server
+  List<String> _permissions = [];
+
+  bool get _canManageFloors => _permissions.contains('manage_floors');
+  bool get _canManageTenants => _permissions.contains('manage_tenants');
 
   @override
   void initState() {
@@ -53,6 +58,7 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
         _property = result['property'];
         _floors = result['floors'];
         _isManager = result['is_manager'] ?? false;
+        _permissions = result['permissions'] ?? [];
         _isLoading = false;
       });
     } catch (e) {
@@ -254,6 +260,8 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
   Future<void> _showUpdateFloorDialog(Floor floor) async {
     final nameController = TextEditingController(text: floor.name);
     final rentController = TextEditingController(text: floor.rent.toString());
+    // The rent of a floor with several units is set per unit
+    final singleUnit = floor.units.length <= 1;
 
     return showDialog(
       context: context,
@@ -269,15 +277,17 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
                 hintText: 'e.g., Ground Floor, First Floor',
               ),
             ),
-            const SizedBox(height: 16),
-            TextField(
-              controller: rentController,
-              decoration: const InputDecoration(
-                labelText: 'Monthly Rent',
-                hintText: 'Enter amount in rupees',
+            if (singleUnit) ...[
+              const SizedBox(height: 16),
+              TextField(
+                controller: rentController,
+                decoration: const InputDecoration(
+                  labelText: 'Monthly Rent',
+                  hintText: 'Enter amount in rupees',
+                ),
+                keyboardType: TextInputType.number,
               ),
-              keyboardType: TextInputType.number,
-            ),
+            ],
           ],
         ),
         actions: [
@@ -287,7 +297,7 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
           ),
           TextButton(
             onPressed: () async {
-              if (nameController.text.isEmpty || rentController.text.isEmpty) {
+              if (nameController.text.isEmpty || (singleUnit && rentController.text.isEmpty)) {
                 ScaffoldMessenger.of(context).showSnackBar(
                   const SnackBar(content: Text('Please fill all fields')),
                 );
@@ -295,8 +305,8 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
               }
 
               try {
-                final rent = int.tryParse(rentController.text);
-                if (rent == null) {
+                final rent = singleUnit ? int.tryParse(rentController.text) : null;
+                if (singleUnit && rent == null) {
                   throw Exception('Invalid rent amount');
                 }
 
@@ -577,7 +587,7 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
                           mainAxisAlignment: MainAxisAlignment.center,
                           children: [
                             const Text('No floors added yet'),
-                            if (_isManager) ...[
+                            if (_canManageFloors) ...[
                               const SizedBox(height: 16),
                               ElevatedButton(
                                 onPressed: _showAddFloorDialog,
@@ -616,11 +626,14 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
                               trailing: _isManager ? Row(
                                 mainAxisSize: MainAxisSize.min,
                                 children: [
-                                  IconButton(
-                                    icon: const Icon(Icons.edit, color: Colors.blue),
-                                    onPressed: () => _showUpdateFloorDialog(floor),
-                                  ),
-                                  if (floor.tenant != null)
+                                  if (_canManageFloors)
+                                    IconButton(
+                                      icon: const Icon(Icons.edit, color: Colors.blue),
+                                      onPressed: () => _showUpdateFloorDialog(floor),
+                                    ),
+                                  if (!_canManageTenants)
+                                    const SizedBox.shrink()
+                                  else if (floor.tenant != null)
                                     IconButton(
                                       icon: const Icon(Icons.person_remove, color: Colors.red),
                                       onPressed: () => _showRemoveTenantDialog(floor),
@@ -642,7 +655,7 @@ class _PropertyDetailsScreenState extends State<PropertyDetailsScreen> {
                         },
                       ),
       ),
-      floatingActionButton: _isManager ? FloatingActionButton(
+      floatingActionButton: _canManageFloors ? FloatingActionButton(
         onPressed: _showAddFloorDialog,
         child: const Icon(Icons.add),
       ) : null,
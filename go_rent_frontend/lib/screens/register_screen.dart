@@ -1,5 +1,6 @@
 import 'package:flutter/material.dart';
 import '../services/api_service.dart';
+import 'properties_screen.dart';
 
 class RegisterScreen extends StatefulWidget {
   const RegisterScreen({super.key});
@@ -13,15 +14,19 @@ class _RegisterScreenState extends State<RegisterScreen> {
   final _apiService = ApiService();
   bool _isLoading = false;
   String? _error;
+  // Set once the server has texted a verification code
+  bool _codeSent = false;
 
   // Form fields
   final _nameController = TextEditingController();
   final _phoneController = TextEditingController();
   final _passwordController = TextEditingController();
   final _confirmPasswordController = TextEditingController();
+  final _codeController = TextEditingController();
 
   @override
   void dispose() {
+    _codeController.dispose();
     _nameController.dispose();
     _phoneController.dispose();
     _passwordController.dispose();
@@ -38,18 +43,17 @@ class _RegisterScreenState extends State<RegisterScreen> {
     });
 
     try {
-      // Format phone number as +880 XXXX-XXXXXX
-      final phoneNumber = '+880 ${_phoneController.text.substring(0, 4)}-${_phoneController.text.substring(4)}';
-      
       final success = await _apiService.register(
-        phoneNumber,
+        _phoneNumber,
         _passwordController.text,
         name: _nameController.text,
       );
 
       if (success && mounted) {
-        // Navigate to login screen on success
-        Navigator.pushReplacementNamed(context, '/login');
+        // Ask for the code sent by SMS
+        setState(() {
+          _codeSent = true;
+        });
       } else {
         setState(() {
           _error = 'Registration failed. Please try again.';
@@ -68,6 +72,62 @@ class _RegisterScreenState extends State<RegisterScreen> {
     }
   }
 
+  // Format phone number as +880 XXXX-XXXXXX
+  String get _phoneNumber =>
+      '+880 ${_phoneController.text.substring(0, 4)}-${_phoneController.text.substring(4)}';
+
+  Future<void> _verify() async {
+    if (!_formKey.currentState!.validate()) return;
+
+    setState(() {
+      _isLoading = true;
+      _error = null;
+    });
+
+    try {
+      await _apiService.verifyRegistration(
+        _phoneNumber,
+        _passwordController.text,
+        _codeController.text,
+      );
+      if (mounted) {
+        Navigator.pushAndRemoveUntil(
+          context,
+          MaterialPageRoute(builder: (context) => const PropertiesScreen()),
+          (route) => false,
+        );
+      }
+    } catch (e) {
+      setState(() {
+        _error = e.toString();
+      });
+    } finally {
+      if (mounted) {
+        setState(() {
+          _isLoading = false;
+        });
+      }
+    }
+  }
+
+  Future<void> _resendCode() async {
+    setState(() {
+      _error = null;
+    });
+    try {
+      await _apiService.resendRegistrationCode(_phoneNumber);
+      if (mounted) {
+        ScaffoldMessenger.of(context).showSnackBar(
+          const SnackBar(content: Text('A new code has been sent')),
+        );
+      }
+    } catch (e) {
+      setState(() {
+        _error = e.toString();
+      });
+    }
+  }
+
   @override
   Widget build(BuildContext context) {
     return Scaffold(
@@ -159,12 +219,34 @@ class _RegisterScreenState extends State<RegisterScreen> {
                   return null;
                 },
               ),
+              if (_codeSent) ...[
+                const SizedBox(height: 16),
+                TextFormField(
+                  controller: _codeController,
+                  decoration: const InputDecoration(
+                    labelText: 'Verification Code',
+                    helperText: 'Enter the code we sent to your phone',
+                    border: OutlineInputBorder(),
+                  ),
+                  keyboardType: TextInputType.number,
+                  validator: (value) {
+                    if (value == null || value.length != 6) {
+                      return 'Please enter the 6-digit code';
+                    }
+                    return null;
+                  },
+                ),
+                TextButton(
+                  onPressed: _isLoading ? null : _resendCode,
+                  child: const Text('Send a new code'),
+                ),
+              ],
               const SizedBox(height: 24),
               ElevatedButton(
-                onPressed: _isLoading ? null : _register,
+                onPressed: _isLoading ? null : (_codeSent ? _verify : _register),
                 child: _isLoading
                     ? const CircularProgressIndicator()
-                    : const Text('Register'),
+                    : Text(_codeSent ? 'Verify' : 'Register'),
               ),
               const SizedBox(height: 16),
               TextButton(
@@ -2,7 +2,11 @@ import 'dart:convert';
 import 'package:http/http.dart' as http;
 import '../models/property.dart';
 import '../models/floor.dart';
+import '../models/tenancy.dart';
+import '../models/lease.dart';
 import '../models/notification.dart' as models;
+import '../models/session.dart';
+import 'package:flutter/foundation.dart';
 import 'package:shared_preferences/shared_preferences.dart';
 
 class ApiService {
@@ -11,36 +15,80 @@ class ApiService {
   ApiService._internal();
 
   static const String baseUrl = 'http://192.168.0.230:8080';
+  // Access token sent as "Authorization: Bearer", see the README
   String? _sessionToken;
-  final _client = http.Client();
+  // Trades an expired access token for a new one at /token/refresh
+  String? _refreshToken;
+  late final http.Client _client = _RefreshingClient(http.Client(), this);
+  Future<bool>? _refreshing;
 
-  Future<void> setSessionToken(String token) async {
+  Future<void> setSessionToken(String token, {String? refreshToken}) async {
     _sessionToken = token;
     final prefs = await SharedPreferences.getInstance();
     await prefs.setString('session_token', token);
+    if (refreshToken != null) {
+      _refreshToken = refreshToken;
+      await prefs.setString('refresh_token', refreshToken);
+    }
     print('Session token set: $_sessionToken');
   }
 
   Future<void> loadSessionToken() async {
     final prefs = await SharedPreferences.getInstance();
     _sessionToken = prefs.getString('session_token');
+    _refreshToken = prefs.getString('refresh_token');
     print('Loaded session token: $_sessionToken');
   }
 
   Future<void> clearSessionToken() async {
     _sessionToken = null;
+    _refreshToken = null;
     final prefs = await SharedPreferences.getInstance();
     await prefs.remove('session_token');
+    await prefs.remove('refresh_token');
     print('Session token cleared');
   }
 
+  // Gets a new token pair with the refresh token. Concurrent callers share
+  // one request, because the server revokes the session when a refresh
+  // token is used twice.
+  Future<bool> _refreshSession() {
+    return _refreshing ??= _doRefresh().whenComplete(() => _refreshing = null);
+  }
+
+  Future<bool> _doRefresh() async {
+    final refreshToken = _refreshToken;
+    if (refreshToken == null) {
+      return false;
+    }
+    try {
+      final response = await http.post(
+        Uri.parse('$baseUrl/token/refresh'),
+        headers: {'Content-Type': 'application/json'},
+        body: json.encode({'refresh_token': refreshToken}),
+      );
+      print('Refresh response status: ${response.statusCode}');
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        await setSessionToken(data['access_token'], refreshToken: data['refresh_token']);
+        return true;
+      }
+      if (response.statusCode == 401) {
+        await clearSessionToken();
+      }
+    } catch (e) {
+      print('Refresh error: $e');
+    }
+    return false;
+  }
+
   Map<String, String> get _headers {
     final headers = {
       'Content-Type': 'application/json',
     };
     
     if (_sessionToken != null) {
-      headers['Cookie'] = 'sessiontoken=$_sessionToken';
+      headers['Authorization'] = 'Bearer $_sessionToken';
       print('Adding session token to headers: $_sessionToken');
     }
     
@@ -57,6 +105,7 @@ class ApiService {
         body: json.encode({
           'phone_number': phoneNumber,
           'password': password,
+          'device_name': kIsWeb ? 'Web browser' : defaultTargetPlatform.name,
         }),
       );
       
@@ -65,18 +114,17 @@ class ApiService {
       print('Login response body: ${response.body}');
 
       if (response.statusCode == 200) {
-        // Extract session cookie
-        final setCookie = response.headers['set-cookie'];
-        print('Set-Cookie header: $setCookie');
-        
-        if (setCookie != null) {
-          final sessionMatch = RegExp(r'sessiontoken=([^;]+)').firstMatch(setCookie);
-          if (sessionMatch != null) {
-            await setSessionToken(sessionMatch.group(1)!);
-            return true;
-          }
+        final Map<String, dynamic> data = json.decode(response.body);
+        final accessToken = data['access_token'];
+        if (accessToken is String) {
+          await setSessionToken(accessToken, refreshToken: data['refresh_token']);
+          return true;
         }
-        print('No session token found in response');
+        print('No access token found in response');
+      } else if (response.statusCode == 429) {
+        // Locked out or backing off after failed logins
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Too many failed logins');
       }
       return false;
     } catch (e) {
@@ -85,6 +133,192 @@ class ApiService {
     }
   }
 
+  // LOGOUT ends this device's session, or every session of the user when
+  // allDevices is set. The local tokens are dropped either way.
+  Future<bool> logout({bool allDevices = false}) async {
+    try {
+      final response = await _client.post(
+        Uri.parse(allDevices ? '$baseUrl/logout/all' : '$baseUrl/logout'),
+        headers: _headers,
+      );
+      print('Logout response status: ${response.statusCode}');
+      return response.statusCode == 200;
+    } catch (e) {
+      print('Logout error: $e');
+      return false;
+    } finally {
+      await clearSessionToken();
+    }
+  }
+
+  // Sessions of the user, one per device logged in
+  Future<List<Session>> getSessions() async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/me/sessions'),
+        headers: _headers,
+      );
+
+      print('Sessions response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        final List<dynamic> sessionsJson = data['sessions'] ?? [];
+        return sessionsJson.map((json) => Session.fromJson(json)).toList();
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error fetching sessions: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Logs the device of the given session out
+  Future<bool> revokeSession(int sessionId) async {
+    try {
+      final response = await _client.delete(
+        Uri.parse('$baseUrl/me/sessions/$sessionId'),
+        headers: _headers,
+      );
+
+      print('Revoke session response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        return data['success'] ?? false;
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error revoking session: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // PROFILE
+  Future<Map<String, dynamic>> getProfile() async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/me'),
+        headers: _headers,
+      );
+
+      print('Profile response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        return data['profile'] ?? {};
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error fetching profile: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Only the given fields are changed; an empty email or NID removes it
+  Future<Map<String, dynamic>> updateProfile({String? name, String? email, String? nid}) async {
+    try {
+      final Map<String, dynamic> body = {};
+      if (name != null) body['name'] = name;
+      if (email != null) body['email'] = email;
+      if (nid != null) body['nid'] = nid;
+
+      final response = await _client.patch(
+        Uri.parse('$baseUrl/me'),
+        headers: _headers,
+        body: json.encode(body),
+      );
+
+      print('Update profile response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode == 200) {
+        return data['profile'] ?? {};
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception(data['message'] ?? 'Could not update the profile');
+      }
+    } catch (e) {
+      print('Error updating profile: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Every other device of the user is logged out
+  Future<void> changePassword(String oldPassword, String newPassword) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/me/password'),
+        headers: _headers,
+        body: json.encode({
+          'old_password': oldPassword,
+          'new_password': newPassword,
+        }),
+      );
+
+      print('Change password response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not change the password');
+      }
+    } catch (e) {
+      print('Change password error: $e');
+      throw Exception('Change password error: $e');
+    }
+  }
+
+  // Texts a code to the new phone number, see verifyPhoneChange
+  Future<void> requestPhoneChange(String phoneNumber) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/me/phone'),
+        headers: _headers,
+        body: json.encode({'phone_number': phoneNumber}),
+      );
+
+      print('Phone change response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not send a verification code');
+      }
+    } catch (e) {
+      print('Phone change error: $e');
+      throw Exception('Phone change error: $e');
+    }
+  }
+
+  Future<void> verifyPhoneChange(String phoneNumber, String code) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/me/phone/verify'),
+        headers: _headers,
+        body: json.encode({'phone_number': phoneNumber, 'code': code}),
+      );
+
+      print('Verify phone change response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not change the phone number');
+      }
+    } catch (e) {
+      print('Verify phone change error: $e');
+      throw Exception('Verify phone change error: $e');
+    }
+  }
+
   // REGISTRATION
   Future<bool> register(String phoneNumber, String password, {required String name}) async {
     try {
@@ -109,6 +343,107 @@ class ApiService {
     }
   }
 
+  // Completes a registration with the code sent by SMS and logs in
+  Future<bool> verifyRegistration(String phoneNumber, String password, String code) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/register/verify'),
+        headers: {'Content-Type': 'application/json'},
+        body: json.encode({
+          'phone_number': phoneNumber,
+          'password': password,
+          'code': code,
+          'device_name': kIsWeb ? 'Web browser' : defaultTargetPlatform.name,
+        }),
+      );
+
+      print('Verify registration response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode == 200) {
+        final accessToken = data['access_token'];
+        if (accessToken is String) {
+          await setSessionToken(accessToken, refreshToken: data['refresh_token']);
+        }
+        return true;
+      }
+      throw Exception(data['message'] ?? 'Verification failed');
+    } catch (e) {
+      print('Verify registration error: $e');
+      throw Exception('Verification error: $e');
+    }
+  }
+
+  // Sends a new registration code by SMS
+  Future<bool> resendRegistrationCode(String phoneNumber) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/register/resend'),
+        headers: {'Content-Type': 'application/json'},
+        body: json.encode({'phone_number': phoneNumber}),
+      );
+
+      print('Resend code response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        return true;
+      }
+      final Map<String, dynamic> data = json.decode(response.body);
+      throw Exception(data['message'] ?? 'Could not send a new code');
+    } catch (e) {
+      print('Resend code error: $e');
+      throw Exception('Resend code error: $e');
+    }
+  }
+
+  // Texts a password reset code to the phone number if it is registered
+  Future<void> forgotPassword(String phoneNumber) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/password/forgot'),
+        headers: {'Content-Type': 'application/json'},
+        body: json.encode({'phone_number': phoneNumber}),
+      );
+
+      print('Forgot password response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not send a reset code');
+      }
+    } catch (e) {
+      print('Forgot password error: $e');
+      throw Exception('Forgot password error: $e');
+    }
+  }
+
+  // Sets a new password with the code sent by forgotPassword. Every session
+  // of the account, this one included, is logged out.
+  Future<void> resetPassword(String phoneNumber, String code, String newPassword) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/password/reset'),
+        headers: {'Content-Type': 'application/json'},
+        body: json.encode({
+          'phone_number': phoneNumber,
+          'code': code,
+          'new_password': newPassword,
+        }),
+      );
+
+      print('Reset password response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not reset the password');
+      }
+      await clearSessionToken();
+    } catch (e) {
+      print('Reset password error: $e');
+      throw Exception('Reset password error: $e');
+    }
+  }
+
   Future<List<Property>> getProperties() async {
     try {
       print('Fetching properties with headers: $_headers');
@@ -247,6 +582,53 @@ class ApiService {
     }
   }
 
+  // Changes the name and/or address; fields left null keep their value
+  Future<Property> updateProperty(int id, {String? name, String? address}) async {
+    try {
+      final body = <String, dynamic>{};
+      if (name != null) body['name'] = name;
+      if (address != null) body['address'] = address;
+
+      final response = await _client.patch(
+        Uri.parse('$baseUrl/property/$id'),
+        headers: _headers,
+        body: json.encode(body),
+      );
+
+      print('Update property response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 200) {
+        throw Exception(data['message'] ?? 'Could not update the property');
+      }
+      return Property.fromJson(data['property']);
+    } catch (e) {
+      print('Update property error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Archives the property; the server refuses while a unit has a tenant or
+  // a pending tenant request
+  Future<void> deleteProperty(int id) async {
+    try {
+      final response = await _client.delete(
+        Uri.parse('$baseUrl/property/$id'),
+        headers: _headers,
+      );
+
+      print('Delete property response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not delete the property');
+      }
+    } catch (e) {
+      print('Delete property error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
   Future<Map<String, dynamic>> getPropertyDetails(int id) async {
     try {
       print('Fetching property details from: $baseUrl/property/$id');
@@ -275,6 +657,8 @@ class ApiService {
             'property': property,
             'floors': floors,
             'is_manager': data['is_manager'] ?? false,
+            'role': data['role'],
+            'permissions': List<String>.from(data['permissions'] ?? []),
           };
         } else {
           throw Exception(data['message'] ?? 'Failed to load property details');
@@ -327,6 +711,37 @@ class ApiService {
     }
   }
 
+  // Creates floors named by pattern, e.g. "Floor {n}" or "{n}A/{n}B", for
+  // every number from..to. Nothing is created if a name is already taken.
+  Future<List<int>> bulkAddFloors(int propertyId, String pattern, int from, int to, int rent, {Map<String, int>? overrides}) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/floor/bulk'),
+        headers: _headers,
+        body: json.encode({
+          'pattern': pattern,
+          'from': from,
+          'to': to,
+          'rent': rent,
+          if (overrides != null) 'overrides': overrides,
+        }),
+      );
+
+      print('Bulk add floors response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 201) {
+        final conflicts = List<String>.from(data['conflicts'] ?? []);
+        final message = data['message'] ?? 'Could not add the floors';
+        throw Exception(conflicts.isEmpty ? message : '$message: ${conflicts.join(', ')}');
+      }
+      return List<int>.from(data['floor_ids'] ?? []);
+    } catch (e) {
+      print('Bulk add floors error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
   Future<bool> addTenantToFloor(int propertyId, int floorId, String tenantName, String phoneNumber) async {
     try {
       print('Adding tenant to floor: $floorId in property: $propertyId');
@@ -360,11 +775,12 @@ class ApiService {
     }
   }
 
-  Future<bool> removeTenantFromFloor(int propertyId, int floorId) async {
+  // Pass unitId on floors with several units
+  Future<bool> removeTenantFromFloor(int propertyId, int floorId, {int? unitId}) async {
     try {
       print('Removing tenant from floor: $floorId in property: $propertyId');
       final response = await _client.delete(
-        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/tenant'),
+        Uri.parse('${_floorUrl(propertyId, floorId, unitId)}/tenant'),
         headers: _headers,
       );
 
@@ -389,7 +805,372 @@ class ApiService {
     }
   }
 
-  Future<bool> updateFloor(int propertyId, int floorId, String name, int rent) async {
+  // Archives the floor; the server refuses while it has a tenant or a
+  // pending tenant request
+  Future<void> deleteFloor(int propertyId, int floorId) async {
+    try {
+      final response = await _client.delete(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId'),
+        headers: _headers,
+      );
+
+      print('Delete floor response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not delete the floor');
+      }
+    } catch (e) {
+      print('Delete floor error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<List<Floor>> getArchivedFloors(int propertyId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/floor?archived=true'),
+        headers: _headers,
+      );
+
+      print('Archived floors response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        final List<dynamic> floorsJson = data['floors'] ?? [];
+        return floorsJson.map((json) => Floor.fromJson(json)).toList();
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error fetching archived floors: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<void> restoreFloor(int propertyId, int floorId) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/restore'),
+        headers: _headers,
+      );
+
+      print('Restore floor response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not restore the floor');
+      }
+    } catch (e) {
+      print('Restore floor error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // UNITS
+  // The tenant routes of a floor act on its only unit; a floor with several
+  // units needs the unit in the URL
+  String _floorUrl(int propertyId, int floorId, int? unitId) {
+    final floorUrl = '$baseUrl/property/$propertyId/floor/$floorId';
+    return unitId == null ? floorUrl : '$floorUrl/unit/$unitId';
+  }
+
+  Future<int> addUnit(int propertyId, int floorId, String name, int rent) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/unit'),
+        headers: _headers,
+        body: json.encode({'name': name, 'rent': rent}),
+      );
+
+      print('Add unit response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 201) {
+        throw Exception(data['message'] ?? 'Could not add the unit');
+      }
+      return data['unit_id'];
+    } catch (e) {
+      print('Add unit error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<void> updateUnit(int propertyId, int floorId, int unitId, String name, int rent) async {
+    try {
+      final response = await _client.put(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/unit/$unitId'),
+        headers: _headers,
+        body: json.encode({'name': name, 'rent': rent}),
+      );
+
+      print('Update unit response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not update the unit');
+      }
+    } catch (e) {
+      print('Update unit error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Occupancy history of the floor, latest tenancy first
+  Future<List<Tenancy>> getTenancies(int propertyId, int floorId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/tenancies'),
+        headers: _headers,
+      );
+
+      print('Tenancies response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 200) {
+        throw Exception(data['message'] ?? 'Could not load the tenancies');
+      }
+      final List<dynamic> tenancies = data['tenancies'] ?? [];
+      return tenancies.map((json) => Tenancy.fromJson(json)).toList();
+    } catch (e) {
+      print('Error fetching tenancies: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // LEASES
+  // Leases of all units of the floor, latest start first
+  Future<List<Lease>> getLeases(int propertyId, int floorId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/leases'),
+        headers: _headers,
+      );
+
+      print('Leases response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 200) {
+        throw Exception(data['message'] ?? 'Could not load the leases');
+      }
+      final List<dynamic> leases = data['leases'] ?? [];
+      return leases.map((json) => Lease.fromJson(json)).toList();
+    } catch (e) {
+      print('Error fetching leases: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // The caller's own leases on a floor they rent
+  Future<List<Lease>> getMyLeases(int propertyId, int floorId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/tenant/leases'),
+        headers: _headers,
+      );
+
+      print('My leases response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 200) {
+        throw Exception(data['message'] ?? 'Could not load the leases');
+      }
+      final List<dynamic> leases = data['leases'] ?? [];
+      return leases.map((json) => Lease.fromJson(json)).toList();
+    } catch (e) {
+      print('Error fetching leases: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Only the dates and terms of lease are sent, the unit and tenant come
+  // from the tenancy
+  Future<Lease> createLease(int propertyId, int floorId, int tenancyId, Lease lease) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/tenancies/$tenancyId/leases'),
+        headers: _headers,
+        body: json.encode(lease.toJson()),
+      );
+
+      print('Create lease response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 201) {
+        throw Exception(data['message'] ?? 'Could not create the lease');
+      }
+      return Lease.fromJson(data['lease']);
+    } catch (e) {
+      print('Create lease error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<Lease> updateLease(int propertyId, int floorId, Lease lease) async {
+    try {
+      final response = await _client.put(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/leases/${lease.id}'),
+        headers: _headers,
+        body: json.encode(lease.toJson()),
+      );
+
+      print('Update lease response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 200) {
+        throw Exception(data['message'] ?? 'Could not update the lease');
+      }
+      return Lease.fromJson(data['lease']);
+    } catch (e) {
+      print('Update lease error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<void> deleteLease(int propertyId, int floorId, int leaseId) async {
+    try {
+      final response = await _client.delete(
+        Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/leases/$leaseId'),
+        headers: _headers,
+      );
+
+      print('Delete lease response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not delete the lease');
+      }
+    } catch (e) {
+      print('Delete lease error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // MEMBERS
+  // Returns the members of the property under 'members' and the unanswered
+  // invitations under 'invites'
+  Future<Map<String, dynamic>> getMembers(int propertyId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/members'),
+        headers: _headers,
+      );
+
+      print('Members response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        return {
+          'members': List<Map<String, dynamic>>.from(data['members'] ?? []),
+          'invites': List<Map<String, dynamic>>.from(data['invites'] ?? []),
+        };
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error fetching members: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Invites the user with the phone number to the property; they answer it
+  // from their notifications. role is one of owner, manager, caretaker,
+  // accountant and read_only.
+  Future<void> inviteMember(int propertyId, String phoneNumber, String role) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/members'),
+        headers: _headers,
+        body: json.encode({
+          'phone_number': phoneNumber,
+          'role': role,
+        }),
+      );
+
+      print('Invite member response status: ${response.statusCode}');
+
+      if (response.statusCode != 201) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not send the invitation');
+      }
+    } catch (e) {
+      print('Invite member error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Offers the caller's ownership of the property to the user with the phone
+  // number; it only moves once they accept it from their notifications
+  Future<Map<String, dynamic>> transferProperty(int propertyId, String phoneNumber) async {
+    try {
+      final response = await _client.post(
+        Uri.parse('$baseUrl/property/$propertyId/transfer'),
+        headers: _headers,
+        body: json.encode({'phone_number': phoneNumber}),
+      );
+
+      print('Transfer property response status: ${response.statusCode}');
+
+      final Map<String, dynamic> data = json.decode(response.body);
+      if (response.statusCode != 201) {
+        throw Exception(data['message'] ?? 'Could not transfer the property');
+      }
+      return data['transfer'] ?? {};
+    } catch (e) {
+      print('Transfer property error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<List<Map<String, dynamic>>> getTransfers(int propertyId) async {
+    try {
+      final response = await _client.get(
+        Uri.parse('$baseUrl/property/$propertyId/transfers'),
+        headers: _headers,
+      );
+
+      print('Transfers response status: ${response.statusCode}');
+
+      if (response.statusCode == 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        return List<Map<String, dynamic>>.from(data['transfers'] ?? []);
+      } else if (response.statusCode == 401) {
+        throw Exception('Session expired. Please login again.');
+      } else {
+        throw Exception('Server returned status code ${response.statusCode}');
+      }
+    } catch (e) {
+      print('Error fetching transfers: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  Future<void> removeMember(int propertyId, int userId) async {
+    try {
+      final response = await _client.delete(
+        Uri.parse('$baseUrl/property/$propertyId/members/$userId'),
+        headers: _headers,
+      );
+
+      print('Remove member response status: ${response.statusCode}');
+
+      if (response.statusCode != 200) {
+        final Map<String, dynamic> data = json.decode(response.body);
+        throw Exception(data['message'] ?? 'Could not remove the member');
+      }
+    } catch (e) {
+      print('Remove member error: $e');
+      throw Exception('Error: $e');
+    }
+  }
+
+  // Leave rent out for a floor with several units, their rent is set per
+  // unit
+  Future<bool> updateFloor(int propertyId, int floorId, String name, int? rent) async {
     try {
       print('Updating floor: $floorId in property: $propertyId');
       final response = await _client.put(
@@ -397,7 +1178,7 @@ class ApiService {
         headers: _headers,
         body: json.encode({
           'name': name,
-          'rent': rent,
+          if (rent != null) 'rent': rent,
         }),
       );
 
@@ -455,11 +1236,12 @@ class ApiService {
     }
   }
 
-  Future<bool> sendTenantRequest(int propertyId, int floorId, String phoneNumber) async {
+  // Pass unitId on floors with several units
+  Future<bool> sendTenantRequest(int propertyId, int floorId, String phoneNumber, {int? unitId}) async {
     try {
       print('Sending tenant request for property: $propertyId, floor: $floorId');
       final response = await _client.post(
-       Uri.parse('$baseUrl/property/$propertyId/floor/$floorId/request'),
+       Uri.parse('${_floorUrl(propertyId, floorId, unitId)}/request'),
         headers: _headers,
         body: json.encode({
           'phone_number': phoneNumber,
@@ -731,4 +1513,31 @@ class ApiService {
   void dispose() {
     _client.close();
   }
-} 
\ No newline at end of file
+} 
+// _RefreshingClient refreshes the session and retries once when a request
+// made with an access token is rejected with 401
+class _RefreshingClient extends http.BaseClient {
+  _RefreshingClient(this._inner, this._api);
+
+  final http.Client _inner;
+  final ApiService _api;
+
+  @override
+  Future<http.StreamedResponse> send(http.BaseRequest request) async {
+    final body = request is http.Request ? request.bodyBytes : null;
+    final response = await _inner.send(request);
+    if (response.statusCode != 401 ||
+        body == null ||
+        !request.headers.containsKey('Authorization') ||
+        !await _api._refreshSession()) {
+      return response;
+    }
+    await response.stream.drain();
+
+    final retry = http.Request(request.method, request.url)
+      ..headers.addAll(request.headers)
+      ..headers['Authorization'] = 'Bearer ${_api._sessionToken}'
+      ..bodyBytes = body;
+    return _inner.send(retry);
+  }
+}
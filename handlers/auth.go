@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/policy"
+	"go-rent/store"
+	"go-rent/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Routes declare who may call them by wrapping their handler:
+//
+//	public                  the handler itself
+//	Authenticated(h)        any logged in user
+//	PropertyAction(a, h)    a member of the property in {id} whose role allows a
+//	TenantOfFloor(h)        a tenant, current or former, of a unit of floor
+//	                        {floor_id} of property {id}
+//
+// The wrapped handler can rely on auth.FromContext returning the caller.
+
+// authError is the body of every response rejected by the middleware. It
+// has the same success/message shape as the handlers' own responses.
+type authError struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+func denyRequest(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(authError{false, message})
+}
+
+// accessClaims returns the claims of the access token, nil if the request
+// has no valid one. Native clients send the token in an Authorization: Bearer
+// header, browsers in the sessiontoken cookie. A request with a bearer header
+// is never authenticated by its cookie, because CSRF lets such requests
+// through without a CSRF token.
+func accessClaims(r *http.Request) *utils.Claims {
+	var token string
+	if header := r.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil
+		}
+		token = strings.TrimPrefix(header, "Bearer ")
+	} else {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			return nil
+		}
+		token = cookie.Value
+	}
+
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		logging.FromContext(r.Context()).Debug("Rejected access token", "error", err)
+		return nil
+	}
+	// Tokens issued before sessions existed can't be revoked
+	if claims.UserID == 0 || claims.SessionID == 0 {
+		return nil
+	}
+	return claims
+}
+
+// Authenticated only lets requests with a valid session through and stores
+// the user in the request context
+func Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := accessClaims(r)
+		if claims == nil {
+			denyRequest(w, http.StatusUnauthorized, "User not authenticated")
+			return
+		}
+		userID := claims.UserID
+
+		// A token stays valid until it expires, so the session is checked
+		// on every request to make logout take effect at once
+		session, err := stores.Sessions.GetActive(r.Context(), claims.SessionID)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && session.UserID != userID) {
+			denyRequest(w, http.StatusUnauthorized, "Session expired")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error loading session", "session_id", claims.SessionID, "error", err)
+			denyRequest(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		if err := stores.Sessions.Touch(r.Context(), session.ID, clientIP(r)); err != nil {
+			logging.FromContext(r.Context()).Error("Error updating session", "session_id", session.ID, "error", err)
+		}
+
+		// The token outlives a deleted account, so the user is loaded
+		// on every request
+		user, err := stores.Users.Get(r.Context(), userID)
+		if errors.Is(err, store.ErrNotFound) {
+			denyRequest(w, http.StatusUnauthorized, "User not authenticated")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error loading user", "user", userID, "error", err)
+			denyRequest(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+
+		logging.SetUserID(r.Context(), user.ID)
+		ctx := auth.WithPrincipal(r.Context(), &auth.Principal{
+			UserID:      user.ID,
+			Name:        user.Name,
+			PhoneNumber: user.PhoneNumber,
+			SessionID:   session.ID,
+		})
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// PropertyAction only lets members of the property in the {id} route
+// variable through whose role allows the action, see policy.Allows
+func PropertyAction(action policy.Action, next http.HandlerFunc) http.HandlerFunc {
+	return Authenticated(func(w http.ResponseWriter, r *http.Request) {
+		propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			denyRequest(w, http.StatusBadRequest, "Invalid property ID")
+			return
+		}
+
+		role, err := stores.Properties.Role(r.Context(), propertyID, auth.UserID(r.Context()))
+		if errors.Is(err, store.ErrNotFound) {
+			denyRequest(w, http.StatusForbidden, "Access denied to property")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error checking property role", "error", err)
+			denyRequest(w, http.StatusInternalServerError, "Error checking manager status")
+			return
+		}
+		if !policy.Allows(role, action) {
+			denyRequest(w, http.StatusForbidden, "Your role on this property does not allow this")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// TenantOfFloor only lets tenants of a unit of the floor in the {floor_id}
+// route variable, which must belong to property {id}, through. Tenants who
+// have moved out still pass, so they keep reading what was theirs.
+func TenantOfFloor(next http.HandlerFunc) http.HandlerFunc {
+	return Authenticated(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			denyRequest(w, http.StatusBadRequest, "Invalid property ID")
+			return
+		}
+		floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+		if err != nil {
+			denyRequest(w, http.StatusBadRequest, "Invalid floor ID")
+			return
+		}
+
+		if _, err := stores.Floors.Get(r.Context(), propertyID, floorID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				denyRequest(w, http.StatusNotFound, "Floor not found")
+				return
+			}
+			logging.FromContext(r.Context()).Error("Error querying floor", "error", err)
+			denyRequest(w, http.StatusInternalServerError, "Error fetching floor")
+			return
+		}
+		// Every tenant who moved in has a tenancy, ended ones included
+		tenancies, err := stores.Tenancies.ListByFloor(r.Context(), propertyID, floorID)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error querying tenancies", "error", err)
+			denyRequest(w, http.StatusInternalServerError, "Error fetching floor")
+			return
+		}
+		for _, t := range tenancies {
+			if t.TenantID == auth.UserID(r.Context()) {
+				next(w, r)
+				return
+			}
+		}
+		denyRequest(w, http.StatusForbidden, "Access denied to floor")
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"go-rent/models"
+	"go-rent/store"
+	"go-rent/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// callWithSession serves a GET to h, middleware included, with a bearer
+// token of a new session of userID
+func callWithSession(t *testing.T, s *store.Store, h http.HandlerFunc, routeVars map[string]string, userID int64) int {
+	t.Helper()
+	utils.InitJWT([]byte("test key"), time.Hour, time.Hour)
+	session := models.Session{UserID: userID}
+	if err := s.Sessions.Create(context.Background(), &session, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	token, err := utils.GenerateToken(userID, session.ID)
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r = mux.SetURLVars(r, routeVars)
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w.Code
+}
+
+func TestTenantOfFloor(t *testing.T) {
+	s := newTestStore(t)
+	owner := seedUser(t, s, "+880 1711-000001")
+	tenant := seedUser(t, s, "+880 1811-000001")
+	stranger := seedUser(t, s, "+880 1911-000001")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "1A", 8000)
+	otherFloorID, _ := seedFloor(t, s, propertyID, "2A", 8000)
+	route := vars("id", propertyID, "floor_id", floorID)
+	h := TenantOfFloor(ListTenantLeasesHandler)
+
+	if code := callWithSession(t, s, h, route, tenant); code != http.StatusForbidden {
+		t.Errorf("before moving in: got status %d, want 403", code)
+	}
+	moveIn(t, owner, tenant, propertyID, floorID, unitID)
+	if code := callWithSession(t, s, h, route, tenant); code != http.StatusOK {
+		t.Errorf("tenant: got status %d, want 200", code)
+	}
+	if code := callWithSession(t, s, h, vars("id", propertyID, "floor_id", otherFloorID), tenant); code != http.StatusForbidden {
+		t.Errorf("tenant on another floor: got status %d, want 403", code)
+	}
+	for what, userID := range map[string]int64{"owner": owner, "stranger": stranger} {
+		if code := callWithSession(t, s, h, route, userID); code != http.StatusForbidden {
+			t.Errorf("%s: got status %d, want 403", what, code)
+		}
+	}
+	if code := callWithSession(t, s, h, vars("id", propertyID, "floor_id", 1), tenant); code != http.StatusNotFound {
+		t.Errorf("unknown floor: got status %d, want 404", code)
+	}
+
+	status, resp := call(t, RemoveTenantHandler, "DELETE", route, owner, nil)
+	expect(t, "removing the tenant", status, resp, http.StatusOK)
+	if code := callWithSession(t, s, h, route, tenant); code != http.StatusOK {
+		t.Errorf("former tenant: got status %d, want 200", code)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"go-rent/logging"
+	"go-rent/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// setCSRFCookie issues a new CSRF token. It is called whenever session
+// tokens are issued, so a CSRF token never outlives its session.
+func setCSRFCookie(w http.ResponseWriter) error {
+	csrfToken, err := utils.GenerateCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    csrfToken,
+		Expires:  time.Now().Add(utils.RefreshTTL()),
+		Path:     "/",
+		HttpOnly: false, // Must be accessible via JavaScript
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteLaxMode, // Changed from Strict to Lax for better compatibility
+	})
+	return nil
+}
+
+// CSRF is a double-submit check for state-changing requests: a POST, PUT,
+// PATCH or DELETE that carries the session or refresh cookie must repeat
+// the value of the csrf_token cookie in the X-CSRF-Token header. Another
+// site can make the browser send the cookies but can't read them to set
+// the header.
+//
+// Requests without those cookies have nothing to forge and pass, as do
+// requests with an Authorization: Bearer header, which browsers never add
+// on their own.
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !hasCookie(r, sessionCookieName) && !hasCookie(r, refreshCookieName) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var expected string
+		if cookie, err := r.Cookie(csrfCookieName); err == nil {
+			expected = cookie.Value
+		}
+		if !utils.ValidateCSRFToken(r.Header.Get(csrfHeaderName), expected) {
+			logging.FromContext(r.Context()).Warn("Rejected request without a valid CSRF token", "method", r.Method, "path", r.URL.Path)
+			denyRequest(w, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+	return err == nil
+}
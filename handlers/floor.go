@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxBulkFloors caps how many floors one bulk request may create
+	maxBulkFloors = 200
+	// maxFloorNumber caps the numbers a bulk request may put in names
+	maxFloorNumber = 9999
+)
+
+// BulkFloorsRequest describes floors to create from a naming pattern.
+// Pattern holds {n}, which is replaced by every number from From to To;
+// parts separated by "/" each give a floor per number, so "{n}A/{n}B"
+// makes 1A, 1B, 2A, 2B and so on. Overrides sets the rent of single floors
+// by their generated name.
+type BulkFloorsRequest struct {
+	Pattern   string         `json:"pattern"`
+	From      int            `json:"from"`
+	To        int            `json:"to"`
+	Rent      int            `json:"rent"`
+	Overrides map[string]int `json:"overrides,omitempty"`
+}
+
+type BulkFloorsResponse struct {
+	Success  bool    `json:"success"`
+	Message  string  `json:"message"`
+	FloorIDs []int64 `json:"floor_ids,omitempty"`
+	// Conflicts lists the names that are already taken in the property
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// errFloorNamesTaken makes the bulk transaction roll back when a generated
+// name is already in use
+var errFloorNamesTaken = errors.New("floor names already in use")
+
+// expandFloorPattern returns the floor names for the numbers from..to in
+// the order they are created
+func expandFloorPattern(pattern string, from, to int) ([]string, error) {
+	if from < 0 || to < from || to > maxFloorNumber {
+		return nil, fmt.Errorf("the range needs 0 <= from <= to <= %d", maxFloorNumber)
+	}
+	var parts []string
+	for _, part := range strings.Split(pattern, "/") {
+		part = strings.TrimSpace(part)
+		if !strings.Contains(part, "{n}") {
+			return nil, errors.New("every part of the pattern needs {n}")
+		}
+		parts = append(parts, part)
+	}
+	// Bounded by maxBulkFloors/len(parts) rather than multiplied out, so
+	// that no product can overflow
+	if to-from >= maxBulkFloors/len(parts) {
+		return nil, fmt.Errorf("at most %d floors can be created at once", maxBulkFloors)
+	}
+
+	var names []string
+	for n := from; n <= to; n++ {
+		for _, part := range parts {
+			name := strings.ReplaceAll(part, "{n}", strconv.Itoa(n))
+			if len(name) > 100 {
+				return nil, fmt.Errorf("floor name %q is longer than 100 characters", name)
+			}
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// createFloor inserts the floor together with its first unit, which has the
+// name of the floor and the rent
+func createFloor(ctx context.Context, tx *store.Store, f *models.Floor, rent int) error {
+	if err := tx.Floors.Create(ctx, f); err != nil {
+		return fmt.Errorf("creating floor: %v", err)
+	}
+	err := tx.Units.Create(ctx, &models.Unit{
+		FloorID:    f.ID,
+		PropertyID: f.PropertyID,
+		Name:       f.Name,
+		Rent:       rent,
+		CreatedBy:  f.CreatedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("creating unit: %v", err)
+	}
+	return nil
+}
+
+// BulkAddFloorsHandler creates the floors described by a BulkFloorsRequest
+// in one transaction. If any name is taken by a floor of the property no
+// floor is created.
+func BulkAddFloorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Invalid property ID", nil, nil})
+		return
+	}
+
+	var req BulkFloorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Invalid request body", nil, nil})
+		return
+	}
+	names, err := expandFloorPattern(req.Pattern, req.From, req.To)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Invalid floor pattern: " + err.Error(), nil, nil})
+		return
+	}
+	if req.Rent < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Rent can't be negative", nil, nil})
+		return
+	}
+
+	generated := make(map[string]bool, len(names))
+	var repeated []string
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if generated[key] {
+			repeated = append(repeated, name)
+		}
+		generated[key] = true
+	}
+	if len(repeated) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "The pattern generates the same name more than once", nil, repeated})
+		return
+	}
+	for name, rent := range req.Overrides {
+		if !generated[strings.ToLower(name)] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(BulkFloorsResponse{false, fmt.Sprintf("Override %q doesn't match a generated floor", name), nil, nil})
+			return
+		}
+		if rent < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Rent can't be negative", nil, nil})
+			return
+		}
+	}
+	overrides := make(map[string]int, len(req.Overrides))
+	for name, rent := range req.Overrides {
+		overrides[strings.ToLower(name)] = rent
+	}
+
+	ctx := r.Context()
+	userID := auth.UserID(ctx)
+
+	var floorIDs []int64
+	var conflicts []string
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		existing, err := tx.Floors.ListNames(ctx, propertyID)
+		if err != nil {
+			return fmt.Errorf("listing floor names: %v", err)
+		}
+		for _, name := range existing {
+			if generated[strings.ToLower(name)] {
+				conflicts = append(conflicts, name)
+			}
+		}
+		if len(conflicts) > 0 {
+			return errFloorNamesTaken
+		}
+
+		for _, name := range names {
+			rent, ok := overrides[strings.ToLower(name)]
+			if !ok {
+				rent = req.Rent
+			}
+			floor := models.Floor{
+				PropertyID: propertyID,
+				Name:       name,
+				CreatedBy:  userID,
+			}
+			if err := createFloor(ctx, tx, &floor, rent); err != nil {
+				return fmt.Errorf("floor %q: %v", name, err)
+			}
+			floorIDs = append(floorIDs, floor.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, errFloorNamesTaken) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Some floor names are already in use, no floor was created", nil, conflicts})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error creating floors", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(BulkFloorsResponse{false, "Error adding floors", nil, nil})
+		return
+	}
+	logging.FromContext(ctx).Info("Floors added", "property_id", propertyID, "count", len(floorIDs))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BulkFloorsResponse{true, fmt.Sprintf("%d floors added", len(floorIDs)), floorIDs, nil})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestExpandFloorPatternLimits(t *testing.T) {
+	names, err := expandFloorPattern("{n}A/{n}B", 1, 100)
+	if err != nil || len(names) != 200 || names[0] != "1A" || names[199] != "100B" {
+		t.Errorf("200 floors: got %d names, %v", len(names), err)
+	}
+
+	for _, c := range []struct {
+		pattern  string
+		from, to int
+	}{
+		{"{n}A/{n}B", 1, 101},
+		{"{n}", 0, 200},
+		// Ranges whose floor count overflows an int
+		{"{n}", 0, math.MaxInt},
+		{"{n}A/{n}B/{n}C/{n}D", 0, 1<<62 - 1},
+		{"{n}", math.MaxInt - 1, math.MaxInt},
+		{"{n}", -1, 10},
+	} {
+		if names, err := expandFloorPattern(c.pattern, c.from, c.to); err == nil {
+			t.Errorf("%q from %d to %d: got %d names, want an error", c.pattern, c.from, c.to, len(names))
+		}
+	}
+}
+
+func TestBulkAddFloorsRollsBack(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	propertyID := seedProperty(t, s, owner)
+	seedFloor(t, s, propertyID, "3b", 8000)
+	route := vars("id", propertyID)
+
+	status, resp := call(t, BulkAddFloorsHandler, "POST", route, owner,
+		BulkFloorsRequest{Pattern: "{n}A/{n}B", From: 1, To: 5, Rent: 8000})
+	expect(t, "bulk add over a taken name", status, resp, http.StatusConflict)
+	if conflicts, _ := resp["conflicts"].([]interface{}); len(conflicts) != 1 || conflicts[0] != "3b" {
+		t.Errorf("conflicts: got %v, want [3b]", resp["conflicts"])
+	}
+	names, err := s.Floors.ListNames(ctx, propertyID)
+	if err != nil || len(names) != 1 {
+		t.Errorf("floors after a refused bulk add: %v, %v", names, err)
+	}
+
+	status, resp = call(t, BulkAddFloorsHandler, "POST", route, owner,
+		BulkFloorsRequest{Pattern: "{n}A/{n}B", From: 1, To: 2, Rent: 8000, Overrides: map[string]int{"2b": 9000}})
+	expect(t, "bulk add", status, resp, http.StatusCreated)
+	if ids, _ := resp["floor_ids"].([]interface{}); len(ids) != 4 {
+		t.Errorf("floor_ids: got %v, want 4", resp["floor_ids"])
+	}
+}
+
+func TestRestoreFloorNameTaken(t *testing.T) {
+	s := newTestStore(t)
+	owner := seedUser(t, s, "+880 1711-000002")
+	propertyID := seedProperty(t, s, owner)
+	floorID, _ := seedFloor(t, s, propertyID, "1A", 8000)
+	route := vars("id", propertyID, "floor_id", floorID)
+
+	status, resp := call(t, DeleteFloorHandler, "DELETE", route, owner, nil)
+	expect(t, "archiving", status, resp, http.StatusOK)
+	// Bulk creation only checks the active floors, so it can reuse the name
+	status, resp = call(t, BulkAddFloorsHandler, "POST", vars("id", propertyID), owner,
+		BulkFloorsRequest{Pattern: "{n}a", From: 1, To: 1, Rent: 8000})
+	expect(t, "bulk add over an archived name", status, resp, http.StatusCreated)
+	reusedID := int64(resp["floor_ids"].([]interface{})[0].(float64))
+
+	status, resp = call(t, RestoreFloorHandler, "POST", route, owner, nil)
+	expect(t, "restoring over an active name", status, resp, http.StatusConflict)
+	if names, err := s.Floors.ListNames(context.Background(), propertyID); err != nil || len(names) != 1 {
+		t.Errorf("active floors after a refused restore: %v, %v", names, err)
+	}
+
+	status, resp = call(t, DeleteFloorHandler, "DELETE", vars("id", propertyID, "floor_id", reusedID), owner, nil)
+	expect(t, "archiving the floor that reused the name", status, resp, http.StatusOK)
+	status, resp = call(t, RestoreFloorHandler, "POST", route, owner, nil)
+	expect(t, "restoring", status, resp, http.StatusOK)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// The handler tests run against store.NewMemory. They call the handlers
+// directly with the route variables and the caller set on the request, the
+// way PropertyAction and Authenticated leave them, so no token or session
+// is needed.
+
+// newTestStore gives the handlers an empty memory store
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s := store.NewMemory()
+	SetStore(s)
+	return s
+}
+
+// seedUser adds a verified user with the phone number
+func seedUser(t *testing.T, s *store.Store, phone string) int64 {
+	t.Helper()
+	u := models.User{Name: "User " + phone, PhoneNumber: phone, Password: "hash", Verified: true}
+	if err := s.Users.Create(context.Background(), &u); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	return u.ID
+}
+
+// seedProperty adds a property owned by ownerID
+func seedProperty(t *testing.T, s *store.Store, ownerID int64) int64 {
+	t.Helper()
+	ctx := context.Background()
+	p := models.Property{Name: "Rose Villa", Address: "Dhanmondi", CreatedBy: ownerID}
+	if err := s.Properties.Create(ctx, &p); err != nil {
+		t.Fatalf("creating property: %v", err)
+	}
+	if err := s.Properties.AddMember(ctx, p.ID, ownerID, models.RoleOwner, ownerID); err != nil {
+		t.Fatalf("adding owner: %v", err)
+	}
+	return p.ID
+}
+
+// seedFloor adds a floor with its first unit, like AddFloorHandler
+func seedFloor(t *testing.T, s *store.Store, propertyID int64, name string, rent int) (floorID, unitID int64) {
+	t.Helper()
+	ctx := context.Background()
+	f := models.Floor{PropertyID: propertyID, Name: name}
+	err := s.WithTx(ctx, func(tx *store.Store) error {
+		return createFloor(ctx, tx, &f, rent)
+	})
+	if err != nil {
+		t.Fatalf("creating floor: %v", err)
+	}
+	units, err := s.Units.ListByFloor(ctx, propertyID, f.ID)
+	if err != nil || len(units) != 1 {
+		t.Fatalf("listing units of the new floor: %v, %d units", err, len(units))
+	}
+	return f.ID, units[0].ID
+}
+
+// vars builds route variables from name, value pairs
+func vars(pairs ...interface{}) map[string]string {
+	v := make(map[string]string)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		v[pairs[i].(string)] = fmt.Sprint(pairs[i+1])
+	}
+	return v
+}
+
+// call serves one request to h as userID. The body is sent as JSON and the
+// response decoded into a map, which is nil if it isn't JSON.
+func call(t *testing.T, h http.HandlerFunc, method string, routeVars map[string]string, userID int64, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			t.Fatalf("encoding body: %v", err)
+		}
+	}
+	r := httptest.NewRequest(method, "/", bytes.NewReader(payload))
+	r = mux.SetURLVars(r, routeVars)
+	if userID != 0 {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{UserID: userID}))
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		resp = nil
+	}
+	return w.Code, resp
+}
+
+// expect fails the test if the status isn't the wanted one
+func expect(t *testing.T, what string, status int, resp map[string]interface{}, want int) {
+	t.Helper()
+	if status != want {
+		t.Fatalf("%s: got status %d (%v), want %d", what, status, resp, want)
+	}
+}
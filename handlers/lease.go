@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// A lease records the terms of a tenancy. Members who manage tenants
+// create, change and delete the leases of a floor; the tenant reads their
+// own through /tenant/leases, also after moving out. A lease lies within
+// its tenancy, and two leases of the same unit never share a day, so a
+// renewal starts after the previous lease ended. Overlaps are checked per
+// unit, not per floor: units took the place of floors as what is let out,
+// so the units of one floor have leases that run side by side.
+
+// maxClausesLength caps the free-text clauses of a lease
+const maxClausesLength = 10000
+
+// Lease is the JSON representation of a lease
+type Lease struct {
+	ID         int64  `json:"id"`
+	TenancyID  int64  `json:"tenancy_id"`
+	UnitID     int64  `json:"unit_id"`
+	TenantID   int64  `json:"tenant_id"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	Rent       int    `json:"rent"`
+	Deposit    int    `json:"deposit"`
+	NoticeDays int    `json:"notice_days"`
+	BillingDay int    `json:"billing_day"`
+	Clauses    string `json:"clauses"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// LeaseRequest holds the dates, as YYYY-MM-DD, and terms of a lease.
+// Deposit is the advance paid when moving in and NoticeDays how long
+// before leaving either side has to give notice.
+type LeaseRequest struct {
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	Rent       int    `json:"rent"`
+	Deposit    int    `json:"deposit"`
+	NoticeDays int    `json:"notice_days"`
+	BillingDay int    `json:"billing_day"`
+	Clauses    string `json:"clauses"`
+}
+
+type LeaseResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Lease   *Lease `json:"lease,omitempty"`
+}
+
+type LeasesResponse struct {
+	Success bool    `json:"success"`
+	Message string  `json:"message"`
+	Leases  []Lease `json:"leases"`
+}
+
+// errLeaseOverlap makes a lease transaction roll back when the dates
+// overlap another lease of the unit
+var errLeaseOverlap = errors.New("lease overlaps another lease")
+
+func toLease(l *models.Lease) *Lease {
+	return &Lease{
+		ID:         l.ID,
+		TenancyID:  l.TenancyID,
+		UnitID:     l.UnitID,
+		TenantID:   l.TenantID,
+		StartDate:  l.StartDate,
+		EndDate:    l.EndDate,
+		Rent:       l.Rent,
+		Deposit:    l.Deposit,
+		NoticeDays: l.NoticeDays,
+		BillingDay: l.BillingDay,
+		Clauses:    l.Clauses,
+		CreatedAt:  l.CreatedAt,
+		UpdatedAt:  l.UpdatedAt,
+	}
+}
+
+// validLease checks the dates and terms of a lease, returning the message
+// to send back if they are not valid
+func validLease(req *LeaseRequest) string {
+	req.Clauses = strings.TrimSpace(req.Clauses)
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return "Start date must be in YYYY-MM-DD format"
+	}
+	end, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return "End date must be in YYYY-MM-DD format"
+	}
+	switch {
+	case !end.After(start):
+		return "End date must be after the start date"
+	case req.Rent <= 0:
+		return "Monthly rent must be more than 0"
+	case req.Deposit < 0:
+		return "Deposit can't be negative"
+	case req.NoticeDays < 0:
+		return "Notice period can't be negative"
+	case req.BillingDay < 1 || req.BillingDay > 28:
+		return "Billing day must be between 1 and 28"
+	case len(req.Clauses) > maxClausesLength:
+		return fmt.Sprintf("Clauses can't be longer than %d characters", maxClausesLength)
+	}
+	return ""
+}
+
+// errLeaseOutsideTenancy makes a lease transaction roll back when the dates
+// don't lie within the tenancy
+var errLeaseOutsideTenancy = errors.New("lease outside its tenancy")
+
+// withinTenancy reports whether start..end lies within the tenancy. Dates
+// are YYYY-MM-DD, so they compare as strings.
+func withinTenancy(t *models.Tenancy, start, end string) bool {
+	return start >= t.StartDate && (t.EndDate == "" || end <= t.EndDate)
+}
+
+// tenancyMessage tells the dates a lease of the tenancy has to lie within
+func tenancyMessage(t *models.Tenancy) string {
+	if t.EndDate == "" {
+		return fmt.Sprintf("The lease can't start before the tenancy, on %s", t.StartDate)
+	}
+	return fmt.Sprintf("The lease must lie within the tenancy, from %s to %s", t.StartDate, t.EndDate)
+}
+
+// overlapMessage tells which lease the dates collide with
+func overlapMessage(l *models.Lease) string {
+	return fmt.Sprintf("The dates overlap the lease from %s to %s", l.StartDate, l.EndDate)
+}
+
+// leaseRoute parses {id} and {floor_id}, writing the error response if
+// one of them is invalid
+func leaseRoute(w http.ResponseWriter, r *http.Request) (propertyID, floorID int64, ok bool) {
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid property ID", nil})
+		return 0, 0, false
+	}
+	floorID, err = strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid floor ID", nil})
+		return 0, 0, false
+	}
+	return propertyID, floorID, true
+}
+
+// CreateLeaseHandler adds a lease to the tenancy in {tenancy_id}
+func CreateLeaseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+	tenancyID, err := strconv.ParseInt(mux.Vars(r)["tenancy_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid tenancy ID", nil})
+		return
+	}
+
+	var req LeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid request body", nil})
+		return
+	}
+	if msg := validLease(&req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, msg, nil})
+		return
+	}
+
+	ctx := r.Context()
+	var lease models.Lease
+	var tenancy *models.Tenancy
+	var overlap *models.Lease
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		var err error
+		tenancy, err = tx.Tenancies.Get(ctx, propertyID, floorID, tenancyID)
+		if err != nil {
+			return err
+		}
+		if !withinTenancy(tenancy, req.StartDate, req.EndDate) {
+			return errLeaseOutsideTenancy
+		}
+		overlap, err = tx.Leases.Overlapping(ctx, tenancy.UnitID, 0, req.StartDate, req.EndDate)
+		if err == nil {
+			return errLeaseOverlap
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("checking overlapping leases: %v", err)
+		}
+
+		lease = models.Lease{
+			TenancyID:  tenancy.ID,
+			UnitID:     tenancy.UnitID,
+			FloorID:    tenancy.FloorID,
+			PropertyID: tenancy.PropertyID,
+			TenantID:   tenancy.TenantID,
+			StartDate:  req.StartDate,
+			EndDate:    req.EndDate,
+			Rent:       req.Rent,
+			Deposit:    req.Deposit,
+			NoticeDays: req.NoticeDays,
+			BillingDay: req.BillingDay,
+			Clauses:    req.Clauses,
+			CreatedBy:  auth.UserID(ctx),
+		}
+		return tx.Leases.Create(ctx, &lease)
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Tenancy not found", nil})
+		return
+	case errors.Is(err, errLeaseOutsideTenancy):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, tenancyMessage(tenancy), nil})
+		return
+	case errors.Is(err, errLeaseOverlap):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(LeaseResponse{false, overlapMessage(overlap), nil})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Error creating lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Error creating lease", nil})
+		return
+	}
+	logging.FromContext(ctx).Info("Lease created", "lease_id", lease.ID, "tenancy_id", tenancyID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(LeaseResponse{true, "Lease created successfully", toLease(&lease)})
+}
+
+// ListLeasesHandler returns the leases of the units of the floor, latest
+// start first
+func ListLeasesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := stores.Leases.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing leases", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeasesResponse{false, "Error fetching leases", nil})
+		return
+	}
+	leases := []Lease{}
+	for i := range stored {
+		leases = append(leases, *toLease(&stored[i]))
+	}
+	json.NewEncoder(w).Encode(LeasesResponse{true, "Leases retrieved successfully", leases})
+}
+
+// ListTenantLeasesHandler returns the caller's leases on the floor. Leases
+// are looked up by their tenant, so a tenant who moved out still reads the
+// leases they signed.
+func ListTenantLeasesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := stores.Leases.ListByTenant(ctx, propertyID, floorID, auth.UserID(ctx))
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing leases", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeasesResponse{false, "Error fetching leases", nil})
+		return
+	}
+	leases := []Lease{}
+	for i := range stored {
+		leases = append(leases, *toLease(&stored[i]))
+	}
+	json.NewEncoder(w).Encode(LeasesResponse{true, "Leases retrieved successfully", leases})
+}
+
+// GetLeaseHandler returns the lease in {lease_id}
+func GetLeaseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+	leaseID, err := strconv.ParseInt(mux.Vars(r)["lease_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid lease ID", nil})
+		return
+	}
+
+	ctx := r.Context()
+	lease, err := stores.Leases.Get(ctx, propertyID, floorID, leaseID)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Lease not found", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error querying lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Error fetching lease", nil})
+		return
+	}
+	json.NewEncoder(w).Encode(LeaseResponse{true, "Lease retrieved successfully", toLease(lease)})
+}
+
+// UpdateLeaseHandler replaces the dates and terms of the lease in
+// {lease_id}
+func UpdateLeaseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+	leaseID, err := strconv.ParseInt(mux.Vars(r)["lease_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid lease ID", nil})
+		return
+	}
+
+	var req LeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid request body", nil})
+		return
+	}
+	if msg := validLease(&req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, msg, nil})
+		return
+	}
+
+	ctx := r.Context()
+	var lease *models.Lease
+	var tenancy *models.Tenancy
+	var overlap *models.Lease
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		var err error
+		lease, err = tx.Leases.Get(ctx, propertyID, floorID, leaseID)
+		if err != nil {
+			return err
+		}
+		tenancy, err = tx.Tenancies.Get(ctx, propertyID, floorID, lease.TenancyID)
+		if err != nil {
+			return fmt.Errorf("loading tenancy: %v", err)
+		}
+		if !withinTenancy(tenancy, req.StartDate, req.EndDate) {
+			return errLeaseOutsideTenancy
+		}
+		overlap, err = tx.Leases.Overlapping(ctx, lease.UnitID, lease.ID, req.StartDate, req.EndDate)
+		if err == nil {
+			return errLeaseOverlap
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("checking overlapping leases: %v", err)
+		}
+
+		lease.StartDate = req.StartDate
+		lease.EndDate = req.EndDate
+		lease.Rent = req.Rent
+		lease.Deposit = req.Deposit
+		lease.NoticeDays = req.NoticeDays
+		lease.BillingDay = req.BillingDay
+		lease.Clauses = req.Clauses
+		lease.UpdatedBy = auth.UserID(ctx)
+		return tx.Leases.Update(ctx, lease)
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Lease not found", nil})
+		return
+	case errors.Is(err, errLeaseOutsideTenancy):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, tenancyMessage(tenancy), nil})
+		return
+	case errors.Is(err, errLeaseOverlap):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(LeaseResponse{false, overlapMessage(overlap), nil})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Error updating lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Error updating lease", nil})
+		return
+	}
+	logging.FromContext(ctx).Info("Lease updated", "lease_id", leaseID)
+
+	json.NewEncoder(w).Encode(LeaseResponse{true, "Lease updated successfully", toLease(lease)})
+}
+
+// DeleteLeaseHandler deletes the lease in {lease_id}. The row is kept with
+// deleted_at set.
+func DeleteLeaseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, floorID, ok := leaseRoute(w, r)
+	if !ok {
+		return
+	}
+	leaseID, err := strconv.ParseInt(mux.Vars(r)["lease_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Invalid lease ID", nil})
+		return
+	}
+
+	ctx := r.Context()
+	err = stores.Leases.Delete(ctx, propertyID, floorID, leaseID, auth.UserID(ctx))
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Lease not found", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error deleting lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeaseResponse{false, "Error deleting lease", nil})
+		return
+	}
+	logging.FromContext(ctx).Info("Lease deleted", "lease_id", leaseID)
+
+	json.NewEncoder(w).Encode(LeaseResponse{true, "Lease deleted successfully", nil})
+}
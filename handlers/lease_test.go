@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"go-rent/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLeaseDates(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	tenant := seedUser(t, s, "+880 1811-000001")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "1A", 8000)
+	moveIn(t, owner, tenant, propertyID, floorID, unitID)
+	tenancies, err := s.Tenancies.ListByFloor(ctx, propertyID, floorID)
+	if err != nil || len(tenancies) != 1 {
+		t.Fatalf("listing tenancies: %v, %d found", err, len(tenancies))
+	}
+	start, _ := time.Parse("2006-01-02", tenancies[0].StartDate)
+	day := func(days int) string { return start.AddDate(0, 0, days).Format("2006-01-02") }
+	lease := func(from, to int) LeaseRequest {
+		return LeaseRequest{StartDate: day(from), EndDate: day(to), Rent: 8000, BillingDay: 5}
+	}
+	create := vars("id", propertyID, "floor_id", floorID, "tenancy_id", tenancies[0].ID)
+
+	status, resp := call(t, CreateLeaseHandler, "POST", create, owner, lease(-1, 364))
+	expect(t, "lease starting before the tenancy", status, resp, http.StatusBadRequest)
+	status, resp = call(t, CreateLeaseHandler, "POST", create, owner, lease(0, 364))
+	expect(t, "first lease", status, resp, http.StatusCreated)
+	firstID := int64(resp["lease"].(map[string]interface{})["id"].(float64))
+	status, resp = call(t, CreateLeaseHandler, "POST", create, owner, lease(364, 729))
+	expect(t, "renewal sharing the last day", status, resp, http.StatusConflict)
+	status, resp = call(t, CreateLeaseHandler, "POST", create, owner, lease(365, 729))
+	expect(t, "renewal", status, resp, http.StatusCreated)
+
+	first := vars("id", propertyID, "floor_id", floorID, "lease_id", firstID)
+	status, resp = call(t, UpdateLeaseHandler, "PUT", first, owner, lease(0, 400))
+	expect(t, "stretching the first lease over the renewal", status, resp, http.StatusConflict)
+	status, resp = call(t, UpdateLeaseHandler, "PUT", first, owner, lease(0, 300))
+	expect(t, "shortening the first lease", status, resp, http.StatusOK)
+
+	// The tenant keeps reading their leases after moving out, and the
+	// ended tenancy holds no new lease beyond its end
+	status, resp = call(t, RemoveTenantHandler, "DELETE", vars("id", propertyID, "floor_id", floorID), owner, nil)
+	expect(t, "removing the tenant", status, resp, http.StatusOK)
+	status, resp = call(t, ListTenantLeasesHandler, "GET", vars("id", propertyID, "floor_id", floorID), tenant, nil)
+	expect(t, "listing own leases", status, resp, http.StatusOK)
+	if leases := resp["leases"].([]interface{}); len(leases) != 2 {
+		t.Errorf("former tenant reads %d leases, want 2", len(leases))
+	}
+	status, resp = call(t, ListTenantLeasesHandler, "GET", vars("id", propertyID, "floor_id", floorID), owner, nil)
+	expect(t, "listing leases of someone else", status, resp, http.StatusOK)
+	if leases := resp["leases"].([]interface{}); len(leases) != 0 {
+		t.Errorf("owner reads %d leases as a tenant, want 0", len(leases))
+	}
+	status, resp = call(t, UpdateLeaseHandler, "PUT", first, owner, lease(0, 3000))
+	expect(t, "lease ending after the tenancy", status, resp, http.StatusBadRequest)
+}
+
+func TestLeasesOfUnitsOfOneFloorMayOverlap(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000002")
+	first := seedUser(t, s, "+880 1811-000002")
+	second := seedUser(t, s, "+880 1911-000002")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "2A", 8000)
+	other := models.Unit{FloorID: floorID, PropertyID: propertyID, Name: "2A-2", Rent: 6000}
+	if err := s.Units.Create(ctx, &other); err != nil {
+		t.Fatalf("adding unit: %v", err)
+	}
+	moveIn(t, owner, first, propertyID, floorID, unitID)
+	moveIn(t, owner, second, propertyID, floorID, other.ID)
+
+	tenancies, err := s.Tenancies.ListByFloor(ctx, propertyID, floorID)
+	if err != nil || len(tenancies) != 2 {
+		t.Fatalf("listing tenancies: %v, %d found", err, len(tenancies))
+	}
+	// Both tenancies start today, so leases over the same year overlap
+	start, _ := time.Parse("2006-01-02", tenancies[0].StartDate)
+	lease := LeaseRequest{StartDate: start.Format("2006-01-02"), EndDate: start.AddDate(1, 0, -1).Format("2006-01-02"), Rent: 8000, BillingDay: 5}
+	for _, tenancy := range tenancies {
+		status, resp := call(t, CreateLeaseHandler, "POST", vars("id", propertyID, "floor_id", floorID, "tenancy_id", tenancy.ID), owner, lease)
+		expect(t, "lease of unit "+tenancy.UnitName, status, resp, http.StatusCreated)
+	}
+	if leases, _ := s.Leases.ListByFloor(ctx, propertyID, floorID); len(leases) != 2 {
+		t.Errorf("floor has %d leases, want 2", len(leases))
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net"
+	"regexp"
+	"time"
+)
+
+// Failed logins are counted per phone number and per client IP address.
+// Once a key has used up its free failures, the next login has to wait, and
+// the wait doubles with every further failure. A key that reaches its
+// maximum is locked out for loginLockout; the owner of a phone number gets
+// an SMS.
+
+var (
+	loginMaxFailures = 10
+	loginLockout     = 30 * time.Minute
+)
+
+const (
+	// loginFreeFailures is how many failed logins a phone number gets
+	// before it has to wait
+	loginFreeFailures = 3
+	// ipFreeFailures is the same for an IP address, higher because many
+	// users can share one address behind NAT
+	ipFreeFailures   = 20
+	loginBackoffBase = time.Second
+	loginBackoffMax  = 15 * time.Minute
+	// loginFailureReset is how long after the last failure a key starts
+	// over
+	loginFailureReset = 24 * time.Hour
+)
+
+// errLoginBlocked rolls back the attempts counted by beginLogin when one of
+// its keys has to wait
+var errLoginBlocked = errors.New("login has to wait")
+
+// SetLoginLimits sets after how many failed logins a phone number is locked
+// out and for how long
+func SetLoginLimits(maxFailures int, lockout time.Duration) {
+	loginMaxFailures = maxFailures
+	loginLockout = lockout
+}
+
+// storedPhoneRegex matches phone numbers as they are stored, +880XXXXXXXXXX
+var storedPhoneRegex = regexp.MustCompile(`^\+880\d{10}$`)
+
+func phoneLoginKey(phoneNumber string) string { return "phone:" + phoneNumber }
+
+func ipLoginKey(ip string) string { return "ip:" + ip }
+
+// ipMaxFailures is after how many failed logins an IP address is locked
+// out. It waits as often as a phone number before that, on top of its
+// larger free allowance.
+func ipMaxFailures() int {
+	return loginMaxFailures + ipFreeFailures - loginFreeFailures
+}
+
+// loginBackoff returns how long to wait after the given number of failures
+func loginBackoff(failures, free int) time.Duration {
+	n := failures - free
+	if n < 0 {
+		return 0
+	}
+	if n > 20 {
+		return loginBackoffMax
+	}
+	d := loginBackoffBase << uint(n)
+	if d > loginBackoffMax {
+		d = loginBackoffMax
+	}
+	return d
+}
+
+// beginLogin counts a login for the phone number from the IP address
+// before its password is checked, and sets the wait or lockout that the new
+// counts call for. Both keys are checked and counted in one unit of work
+// that keeps them locked, so parallel logins are counted one after another
+// instead of all passing the check before the first failure is recorded.
+//
+// If a key has to wait nothing is counted and beginLogin returns how long,
+// and whether that is a lockout. Otherwise it returns how many attempts the
+// phone number has made, for loginFailed.
+func beginLogin(ctx context.Context, phoneNumber, ip string) (attempts int, wait time.Duration, locked bool, err error) {
+	now := time.Now()
+	keys := []struct {
+		key               string
+		free, maxFailures int
+	}{
+		{phoneLoginKey(phoneNumber), loginFreeFailures, loginMaxFailures},
+		{ipLoginKey(ip), ipFreeFailures, ipMaxFailures()},
+	}
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		counted := make([]*models.LoginAttempt, len(keys))
+		for i, k := range keys {
+			a, err := tx.LoginAttempts.Attempt(ctx, k.key, now, loginFailureReset)
+			if errors.Is(err, store.ErrConflict) {
+				if d := a.BlockedUntil.Sub(now); d > wait {
+					wait = d
+				}
+				locked = locked || a.Locked
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("counting login: %v", err)
+			}
+			counted[i] = a
+		}
+		if wait > 0 {
+			return errLoginBlocked
+		}
+
+		for i, k := range keys {
+			a := counted[i]
+			if a.Failures >= k.maxFailures {
+				err := tx.LoginAttempts.Lock(ctx, a.Key, now.Add(loginLockout))
+				if err != nil && !errors.Is(err, store.ErrConflict) {
+					return fmt.Errorf("locking login: %v", err)
+				}
+			} else if d := loginBackoff(a.Failures, k.free); d > 0 {
+				if err := tx.LoginAttempts.Delay(ctx, a.Key, now.Add(d)); err != nil {
+					return fmt.Errorf("delaying login: %v", err)
+				}
+			}
+		}
+		attempts = counted[0].Failures
+		return nil
+	})
+	if errors.Is(err, errLoginBlocked) {
+		return 0, wait, locked, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return attempts, 0, false, nil
+}
+
+// loginFailed tells the user when their failed login was the one that
+// locked their phone number
+func loginFailed(ctx context.Context, user *models.User, attempts int) {
+	if attempts != loginMaxFailures {
+		return
+	}
+	message := fmt.Sprintf("Your go-rent account was locked for %d minutes after %d failed logins. If this wasn't you, reset your password.",
+		int(loginLockout.Minutes()), attempts)
+	if err := smsSender.Send(ctx, user.PhoneNumber, message); err != nil {
+		logging.FromContext(ctx).Error("Error sending lockout notice", "error", err)
+	}
+}
+
+// loginSucceeded clears the failed logins of the phone number and takes
+// back the attempt counted for the IP address
+func loginSucceeded(ctx context.Context, phoneNumber, ip string) {
+	clearLoginFailures(ctx, phoneNumber)
+	if err := stores.LoginAttempts.Forgive(ctx, ipLoginKey(ip)); err != nil {
+		logging.FromContext(ctx).Error("Error forgiving login attempt", "error", err)
+	}
+}
+
+// clearLoginFailures forgets the failed logins of the phone number after it
+// proved to be in the right hands
+func clearLoginFailures(ctx context.Context, phoneNumber string) {
+	err := stores.LoginAttempts.Clear(ctx, phoneLoginKey(phoneNumber))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(ctx).Error("Error clearing failed logins", "error", err)
+	}
+}
+
+// UnlockLogin lifts the lockout and backoff of a phone number or a client
+// IP address, store.ErrNotFound if it has no failed logins
+func UnlockLogin(ctx context.Context, phoneOrIP string) error {
+	if ip := net.ParseIP(phoneOrIP); ip != nil {
+		return stores.LoginAttempts.Clear(ctx, ipLoginKey(ip.String()))
+	}
+	phoneNumber, ok := lookupPhoneNumber(phoneOrIP)
+	if !ok {
+		return fmt.Errorf("%q is neither a phone number nor an IP address", phoneOrIP)
+	}
+	return stores.LoginAttempts.Clear(ctx, phoneLoginKey(phoneNumber))
+}
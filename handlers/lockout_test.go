@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// login posts the credentials to LoginHandler from the IP address
+func login(t *testing.T, phone, password, ip string) int {
+	t.Helper()
+	body, err := json.Marshal(LoginRequest{PhoneNumber: phone, Password: password})
+	if err != nil {
+		t.Fatalf("encoding body: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	r.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	LoginHandler(w, r)
+	return w.Code
+}
+
+func TestLoginFailuresCountedAtomically(t *testing.T) {
+	s := newTestStore(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := seedUser(t, s, "+8801711000001")
+	if err := s.Users.SetPassword(context.Background(), userID, string(hash)); err != nil {
+		t.Fatalf("setting password: %v", err)
+	}
+
+	// Only the free failures may try a password, however many guesses
+	// arrive at once
+	codes := make(chan int, 30)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- login(t, "+880 1711-000001", "wrong", "192.0.2.1")
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	tried := 0
+	for code := range codes {
+		switch code {
+		case http.StatusUnauthorized:
+			tried++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("parallel login: got status %d", code)
+		}
+	}
+	if tried != loginFreeFailures {
+		t.Errorf("%d parallel logins checked a password, want %d", tried, loginFreeFailures)
+	}
+}
+
+func TestLoginLocksOutIPAddress(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	key := ipLoginKey("192.0.2.2")
+	for i := 1; i < ipMaxFailures(); i++ {
+		if _, err := s.LoginAttempts.Attempt(ctx, key, now, loginFailureReset); err != nil {
+			t.Fatalf("counting attempt %d: %v", i, err)
+		}
+	}
+
+	// Each guess is for another number, so only the address is counted
+	if code := login(t, "+880 1711-000002", "wrong", "192.0.2.2"); code != http.StatusUnauthorized {
+		t.Fatalf("last allowed login: got status %d, want 401", code)
+	}
+	a, err := s.LoginAttempts.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("loading attempts: %v", err)
+	}
+	if !a.Locked || !a.BlockedUntil.After(now.Add(loginLockout-time.Minute)) {
+		t.Errorf("address after %d failures: %+v, want locked for %v", a.Failures, a, loginLockout)
+	}
+	if code := login(t, "+880 1711-000003", "wrong", "192.0.2.2"); code != http.StatusTooManyRequests {
+		t.Errorf("login from a locked address: got status %d, want 429", code)
+	}
+	if code := login(t, "+880 1711-000003", "wrong", "192.0.2.3"); code != http.StatusUnauthorized {
+		t.Errorf("login from another address: got status %d, want 401", code)
+	}
+}
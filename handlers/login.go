@@ -1,16 +1,15 @@
 package handlers
 
 import (
-	"bytes"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"go-rent/config"
-	"go-rent/utils"
+	"go-rent/logging"
+	"go-rent/store"
 	"golang.org/x/crypto/bcrypt"
-	"io"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -18,6 +17,8 @@ import (
 type LoginRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	Password    string `json:"password"`
+	// DeviceName labels the session in GET /me/sessions, e.g. "Pixel 7"
+	DeviceName string `json:"device_name"`
 }
 
 type LoginResponse struct {
@@ -25,33 +26,31 @@ type LoginResponse struct {
 	Message string `json:"message"`
 	UserID  int64  `json:"user_id,omitempty"`
 	Name    string `json:"name,omitempty"`
+	*Tokens
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Login Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
-	// Read and log request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		fmt.Printf("Error reading request body: %v\n", err)
-	}
-	fmt.Printf("Request Body: %s\n", string(body))
-
-	// Restore body for decoding
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+// Tokens is returned to clients that send the access token in an
+// Authorization: Bearer header instead of keeping the session cookie
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the lifetime of AccessToken in seconds
+	ExpiresIn int64 `json:"expires_in"`
+	// RefreshToken gets a new AccessToken from POST /token/refresh
+	RefreshToken string `json:"refresh_token"`
+}
 
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Method not allowed", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Method not allowed", 0, "", nil})
 		return
 	}
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid request body", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid request body", 0, "", nil})
 		return
 	}
 
@@ -59,7 +58,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	phoneRegex := regexp.MustCompile(`^\+880 \d{4}-\d{6}$`)
 	if !phoneRegex.MatchString(req.PhoneNumber) {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0, "", nil})
 		return
 	}
 
@@ -69,96 +68,84 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	if req.Password == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Password is required", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Password is required", 0, "", nil})
 		return
 	}
 
-	db, err := config.GetDBConnection()
+	// Count the login, or refuse it while the phone number or the client is
+	// backing off after failed logins, see lockout.go
+	ctx := r.Context()
+	ip := clientIP(r)
+	attempts, wait, locked, err := beginLogin(ctx, phoneNumber, ip)
 	if err != nil {
+		logging.FromContext(r.Context()).Error("Database error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Database connection error", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Database error", 0, "", nil})
+		return
+	}
+	if wait > 0 {
+		seconds := int(wait.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		retry := time.Duration(seconds) * time.Second
+		message := fmt.Sprintf("Too many failed logins. Try again in %v", retry)
+		if locked {
+			message = fmt.Sprintf("Account locked after too many failed logins. Try again in %v", retry)
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(LoginResponse{false, message, 0, "", nil})
 		return
 	}
-	defer db.Close()
 
 	// Check if user exists and get their details
-	var (
-		userID   int64
-		name     string
-		password string
-	)
-	err = db.QueryRow("SELECT id, name, password FROM user WHERE phone_number = ?", phoneNumber).Scan(&userID, &name, &password)
+	user, err := stores.Users.GetByPhone(ctx, phoneNumber)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number or password", 0, ""})
+			json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number or password", 0, "", nil})
 			return
 		}
-		fmt.Printf("Database error: %v\n", err)
+		logging.FromContext(r.Context()).Error("Database error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Database error", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Database error", 0, "", nil})
 		return
 	}
 
 	// Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(password), []byte(req.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		loginFailed(ctx, user, attempts)
 		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number or password", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number or password", 0, "", nil})
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(userID)
-	if err != nil {
-		fmt.Printf("Error generating token: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Error generating authentication token", 0, ""})
+	loginSucceeded(ctx, phoneNumber, ip)
+
+	// The registration was never completed with the code sent by SMS
+	if !user.Verified {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Phone number not verified", 0, "", nil})
 		return
 	}
 
-	// Generate CSRF token
-	csrfToken, err := utils.GenerateCSRFToken()
+	// Start a session and set its cookies
+	tokens, err := startSession(w, r, user.ID, req.DeviceName)
 	if err != nil {
-		fmt.Printf("Error generating CSRF token: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error starting session", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(LoginResponse{false, "Error generating CSRF token", 0, ""})
+		json.NewEncoder(w).Encode(LoginResponse{false, "Error generating authentication token", 0, "", nil})
 		return
 	}
 
-	fmt.Printf("Generated CSRF token: %s\n", csrfToken) // Debug log
-
-	// Set session cookie with JWT token
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sessiontoken",
-		Value:    token,
-		Expires:  time.Now().Add(24 * time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	// Set CSRF token cookie
-	csrfCookie := &http.Cookie{
-		Name:     "csrf_token",
-		Value:    csrfToken,
-		Expires:  time.Now().Add(24 * time.Hour),
-		Path:     "/",
-		Domain:   "localhost",
-		HttpOnly: false, // Must be accessible via JavaScript
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode, // Changed from Strict to Lax for better compatibility
-	}
-	http.SetCookie(w, csrfCookie)
-
-	fmt.Printf("Set CSRF cookie: %s\n", csrfCookie.String()) // Debug log
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(LoginResponse{
 		Success: true,
 		Message: "Login successful",
-		UserID:  userID,
-		Name:    name,
+		UserID:  user.ID,
+		Name:    user.Name,
+		Tokens:  tokens,
 	})
 } 
\ No newline at end of file
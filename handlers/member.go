@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/policy"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Co-managers join a property by invitation. The invitation is a pending
+// notification that carries the offered role; the invitee answers it through
+// HandleTenantRequestAction like a tenant request.
+
+// Member is the JSON representation of a user with a role on a property
+type Member struct {
+	UserID      int64  `json:"user_id"`
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phone_number"`
+	Role        string `json:"role"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// Invite is an invitation to join a property that was not answered yet
+type Invite struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phone_number"`
+	Role        string `json:"role"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type MembersResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message"`
+	Members []Member `json:"members"`
+	Invites []Invite `json:"invites"`
+}
+
+type InviteMemberRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Role        string `json:"role"`
+}
+
+type MemberResponse struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	InviteID int64  `json:"invite_id,omitempty"`
+}
+
+// roleName is how a role reads in messages, "read only" for read_only
+func roleName(role string) string {
+	return strings.ReplaceAll(role, "_", " ")
+}
+
+// InviteMemberHandler invites the user with the phone number to become a
+// member of the property with the given role
+func InviteMemberHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Invalid property ID", 0})
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Invalid request body", 0})
+		return
+	}
+	phoneNumber, ok := lookupPhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0})
+		return
+	}
+	if !policy.ValidRole(req.Role) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Role must be one of owner, manager, caretaker, accountant, read_only", 0})
+		return
+	}
+
+	ctx := r.Context()
+	userID := auth.UserID(ctx)
+
+	invitee, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if err == nil && !invitee.Verified {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(MemberResponse{false, "User not found with this phone number", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error finding user", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error finding user", 0})
+		return
+	}
+
+	_, err = stores.Properties.Role(ctx, propertyID, invitee.ID)
+	if err == nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(MemberResponse{false, "User is already a member of this property", 0})
+		return
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(ctx).Error("Error checking property role", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error checking membership", 0})
+		return
+	}
+	pending, err := stores.Notifications.HasPendingInvite(ctx, propertyID, invitee.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error checking pending invitations", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error checking pending invitations", 0})
+		return
+	}
+	if pending {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(MemberResponse{false, "This user already has a pending invitation", 0})
+		return
+	}
+
+	property, err := stores.Properties.GetForUser(ctx, propertyID, userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error getting property", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error getting property details", 0})
+		return
+	}
+
+	invite := &models.Notification{
+		Message:    fmt.Sprintf("Invitation to manage %s as %s", property.Name, roleName(req.Role)),
+		Sender:     &userID,
+		Receiver:   invitee.ID,
+		PropertyID: propertyID,
+		Role:       req.Role,
+		Status:     models.NotificationPending,
+		CreatedBy:  userID,
+	}
+	if err := stores.Notifications.Create(ctx, invite); err != nil {
+		logging.FromContext(ctx).Error("Error creating invitation", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error creating invitation", 0})
+		return
+	}
+	logging.FromContext(ctx).Info("Member invited", "property_id", propertyID, "invitee_id", invitee.ID, "role", req.Role)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(MemberResponse{true, "Invitation sent", invite.ID})
+}
+
+// ListMembersHandler returns the members of the property and the
+// invitations that were not answered yet
+func ListMembersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MembersResponse{false, "Invalid property ID", nil, nil})
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := stores.Properties.ListMembers(ctx, propertyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing members", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MembersResponse{false, "Error fetching members", nil, nil})
+		return
+	}
+	members := []Member{}
+	for _, m := range stored {
+		members = append(members, Member{m.UserID, m.Name, m.PhoneNumber, m.Role, m.CreatedAt})
+	}
+
+	pending, err := stores.Notifications.ListPendingInvites(ctx, propertyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing invitations", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MembersResponse{false, "Error fetching invitations", nil, nil})
+		return
+	}
+	invites := []Invite{}
+	for _, n := range pending {
+		invitee, err := stores.Users.Get(ctx, n.Receiver)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error loading invitee", "error", err, "user_id", n.Receiver)
+			continue
+		}
+		invites = append(invites, Invite{n.ID, invitee.ID, invitee.Name, invitee.PhoneNumber, n.Role, n.CreatedAt})
+	}
+
+	json.NewEncoder(w).Encode(MembersResponse{true, "Members retrieved successfully", members, invites})
+}
+
+// RemoveMemberHandler takes the role on the property away from the user in
+// {user_id}. The last owner of a property can't be removed.
+func RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Invalid property ID", 0})
+		return
+	}
+	memberID, err := strconv.ParseInt(vars["user_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Invalid user ID", 0})
+		return
+	}
+
+	ctx := r.Context()
+	err = stores.Properties.RemoveMember(ctx, propertyID, memberID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(MemberResponse{false, "User is not a member of this property", 0})
+		return
+	case errors.Is(err, store.ErrConflict):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(MemberResponse{false, "The last owner of a property can't be removed", 0})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Error removing member", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(MemberResponse{false, "Error removing member", 0})
+		return
+	}
+	logging.FromContext(ctx).Info("Member removed", "property_id", propertyID, "member_id", memberID)
+
+	json.NewEncoder(w).Encode(MemberResponse{true, "Member removed", 0})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"go-rent/models"
+	"net/http"
+	"testing"
+)
+
+func TestRemoveLastOwner(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	coOwner := seedUser(t, s, "+880 1711-000002")
+	manager := seedUser(t, s, "+880 1711-000003")
+	propertyID := seedProperty(t, s, owner)
+	if err := s.Properties.AddMember(ctx, propertyID, coOwner, models.RoleOwner, owner); err != nil {
+		t.Fatalf("adding co-owner: %v", err)
+	}
+	if err := s.Properties.AddMember(ctx, propertyID, manager, models.RoleManager, owner); err != nil {
+		t.Fatalf("adding manager: %v", err)
+	}
+
+	status, resp := call(t, RemoveMemberHandler, "DELETE", vars("id", propertyID, "user_id", coOwner), owner, nil)
+	expect(t, "removing a co-owner", status, resp, http.StatusOK)
+
+	// A manager left on the property doesn't make up for its owner
+	status, resp = call(t, RemoveMemberHandler, "DELETE", vars("id", propertyID, "user_id", owner), owner, nil)
+	expect(t, "removing the last owner", status, resp, http.StatusConflict)
+	if role, err := s.Properties.Role(ctx, propertyID, owner); err != nil || role != models.RoleOwner {
+		t.Errorf("last owner after a refused removal: role %q, %v", role, err)
+	}
+
+	status, resp = call(t, RemoveMemberHandler, "DELETE", vars("id", propertyID, "user_id", coOwner), owner, nil)
+	expect(t, "removing a former member", status, resp, http.StatusNotFound)
+}
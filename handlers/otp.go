@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rent/models"
+	"go-rent/sms"
+	"go-rent/store"
+	"go-rent/utils"
+	"net/http"
+	"time"
+)
+
+// One-time codes prove that the caller controls a phone number. A code is
+// sent for a purpose (see models.OTP*), and only the newest unused code for
+// that phone number and purpose is accepted.
+
+var (
+	smsSender      sms.Sender = sms.LogSender{}
+	otpTTL                    = 5 * time.Minute
+	otpMaxAttempts            = 5
+)
+
+const (
+	// otpResendInterval is the minimum time between two codes sent to the
+	// same phone number for the same purpose
+	otpResendInterval = time.Minute
+	// otpHourlyLimit caps the codes sent to a phone number per purpose and
+	// hour, since every one of them costs an SMS
+	otpHourlyLimit = 5
+)
+
+var (
+	errOTPTooSoon        = errors.New("verification code requested too often")
+	errOTPInvalid        = errors.New("invalid or expired verification code")
+	errOTPNoAttemptsLeft = errors.New("too many wrong verification codes")
+)
+
+// SetSMSSender sets how verification codes are delivered
+func SetSMSSender(s sms.Sender) {
+	smsSender = s
+}
+
+// SetOTPLimits sets how long a verification code stays valid and how many
+// wrong guesses it allows
+func SetOTPLimits(ttl time.Duration, maxAttempts int) {
+	otpTTL = ttl
+	otpMaxAttempts = maxAttempts
+}
+
+// sendOTP stores a new code for the phone number and texts it, errOTPTooSoon
+// if the rate limit is reached. Run inside a transaction, a failed SMS rolls
+// back the writes that came with it.
+func sendOTP(ctx context.Context, s *store.Store, phoneNumber, purpose string, userID int64) error {
+	now := time.Now()
+	recent, err := s.OTPs.CountSince(ctx, phoneNumber, purpose, now.Add(-otpResendInterval))
+	if err != nil {
+		return fmt.Errorf("counting verification codes: %v", err)
+	}
+	hourly, err := s.OTPs.CountSince(ctx, phoneNumber, purpose, now.Add(-time.Hour))
+	if err != nil {
+		return fmt.Errorf("counting verification codes: %v", err)
+	}
+	if recent > 0 || hourly >= otpHourlyLimit {
+		return errOTPTooSoon
+	}
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	otp := &models.OTP{
+		Purpose:     purpose,
+		PhoneNumber: phoneNumber,
+		UserID:      userID,
+		CodeHash:    utils.HashOTP(code),
+	}
+	if err := s.OTPs.Create(ctx, otp, now.Add(otpTTL)); err != nil {
+		return fmt.Errorf("saving verification code: %v", err)
+	}
+
+	message := fmt.Sprintf("Your go-rent verification code is %s. It expires in %d minutes.", code, int(otpTTL.Minutes()))
+	if err := smsSender.Send(ctx, phoneNumber, message); err != nil {
+		return fmt.Errorf("sending verification code: %v", err)
+	}
+	return nil
+}
+
+// checkOTP uses up the code sent to the phone number for purpose if code
+// matches it. Every guess counts against the attempt limit.
+func checkOTP(ctx context.Context, phoneNumber, purpose, code string) (*models.OTP, error) {
+	otp, err := stores.OTPs.GetActive(ctx, phoneNumber, purpose)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errOTPInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading verification code: %v", err)
+	}
+
+	err = stores.OTPs.Attempt(ctx, otp.ID, otpMaxAttempts)
+	if errors.Is(err, store.ErrConflict) {
+		return nil, errOTPNoAttemptsLeft
+	}
+	if err != nil {
+		return nil, fmt.Errorf("counting verification attempt: %v", err)
+	}
+	if !utils.ValidateOTP(code, otp.CodeHash) {
+		return nil, errOTPInvalid
+	}
+
+	err = stores.OTPs.Consume(ctx, otp.ID)
+	if errors.Is(err, store.ErrConflict) {
+		return nil, errOTPInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("using verification code: %v", err)
+	}
+	return otp, nil
+}
+
+// otpError returns the status and message for an error of sendOTP or
+// checkOTP
+func otpError(err error) (int, string) {
+	switch {
+	case errors.Is(err, errOTPTooSoon):
+		return http.StatusTooManyRequests, "Too many verification codes requested, please wait before trying again"
+	case errors.Is(err, errOTPInvalid):
+		return http.StatusBadRequest, "Invalid or expired verification code"
+	case errors.Is(err, errOTPNoAttemptsLeft):
+		return http.StatusTooManyRequests, "Too many wrong codes, please request a new one"
+	default:
+		return http.StatusInternalServerError, "Error processing verification code"
+	}
+}
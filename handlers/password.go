@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+)
+
+type ForgotPasswordRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type ResetPasswordRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// ForgotPasswordHandler texts a password reset code to a registered phone
+// number. The response is the same whether or not the number is
+// registered, so it can't be used to find out.
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Invalid request body"})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX"})
+		return
+	}
+
+	ctx := r.Context()
+	user, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if err == nil && user.Verified {
+		err = stores.WithTx(ctx, func(tx *store.Store) error {
+			return sendOTP(ctx, tx, phoneNumber, models.OTPPasswordReset, user.ID)
+		})
+	} else if errors.Is(err, store.ErrNotFound) || err == nil {
+		logging.FromContext(r.Context()).Info("Password reset requested for unknown number", "phone_number", phoneNumber)
+		err = nil
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error sending password reset code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(PasswordResponse{false, message})
+		return
+	}
+
+	json.NewEncoder(w).Encode(PasswordResponse{true, "If this number is registered, a code has been sent to it"})
+}
+
+// ResetPasswordHandler sets a new password with the code sent by
+// ForgotPasswordHandler and logs the account out everywhere
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Invalid request body"})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX"})
+		return
+	}
+	if req.Code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Code is required"})
+		return
+	}
+	if req.NewPassword == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "New password is required"})
+		return
+	}
+
+	ctx := r.Context()
+	otp, err := checkOTP(ctx, phoneNumber, models.OTPPasswordReset, req.Code)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error verifying password reset code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(PasswordResponse{false, message})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Error hashing password"})
+		return
+	}
+
+	// Whoever knew the old password may still be logged in, so every
+	// session ends with it
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Users.SetPassword(ctx, otp.UserID, string(hash)); err != nil {
+			return fmt.Errorf("updating password: %v", err)
+		}
+		if err := tx.Sessions.RevokeAll(ctx, otp.UserID); err != nil {
+			return fmt.Errorf("revoking sessions: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error resetting password", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Error resetting password"})
+		return
+	}
+
+	// The code proves the number is in the right hands, lift any lockout
+	clearLoginFailures(ctx, phoneNumber)
+
+	clearSessionCookies(w)
+	json.NewEncoder(w).Encode(PasswordResponse{true, "Password reset, please log in with the new password"})
+}
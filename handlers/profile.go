@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"strings"
+)
+
+// Profile is the JSON representation of the logged in user
+type Profile struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phone_number"`
+	Email       string `json:"email,omitempty"`
+	NID         string `json:"nid,omitempty"`
+	Manager     *bool  `json:"manager,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type ProfileResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message"`
+	Profile *Profile `json:"profile,omitempty"`
+}
+
+// UpdateProfileRequest changes the fields that are present. An empty email
+// or NID removes it.
+type UpdateProfileRequest struct {
+	Name    *string `json:"name"`
+	Email   *string `json:"email"`
+	NID     *string `json:"nid"`
+	Manager *bool   `json:"manager"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePhoneRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type VerifyPhoneChangeRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+}
+
+func toProfile(u *models.User) *Profile {
+	return &Profile{
+		ID:          u.ID,
+		Name:        u.Name,
+		PhoneNumber: u.PhoneNumber,
+		Email:       u.Email,
+		NID:         u.NID,
+		Manager:     u.Manager,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
+
+// GetProfileHandler returns the profile of the logged in user
+func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := stores.Users.Get(r.Context(), auth.UserID(r.Context()))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading profile", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Error fetching profile", nil})
+		return
+	}
+	json.NewEncoder(w).Encode(ProfileResponse{true, "Profile retrieved successfully", toProfile(user)})
+}
+
+// UpdateProfileHandler changes name, email, NID and manager flag of the
+// logged in user
+func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Invalid request body", nil})
+		return
+	}
+
+	ctx := r.Context()
+	userID := auth.UserID(ctx)
+	user, err := stores.Users.Get(ctx, userID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading profile", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Error fetching profile", nil})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ProfileResponse{false, "Name is required", nil})
+			return
+		}
+		user.Name = name
+	}
+	if req.Email != nil {
+		if *req.Email != "" && !emailRegex.MatchString(*req.Email) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ProfileResponse{false, invalidEmailMessage, nil})
+			return
+		}
+		user.Email = *req.Email
+	}
+	if req.NID != nil {
+		if *req.NID != "" && !nidRegex.MatchString(*req.NID) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ProfileResponse{false, invalidNIDMessage, nil})
+			return
+		}
+		user.NID = *req.NID
+	}
+	if req.Manager != nil {
+		user.Manager = req.Manager
+	}
+
+	user.UpdatedBy = userID
+	if err := stores.Users.UpdateProfile(ctx, user); err != nil {
+		logging.FromContext(r.Context()).Error("Error updating profile", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Error updating profile", nil})
+		return
+	}
+	json.NewEncoder(w).Encode(ProfileResponse{true, "Profile updated successfully", toProfile(user)})
+}
+
+// ChangePasswordHandler sets a new password once the current one is
+// confirmed. Every other session of the user is logged out.
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Invalid request body"})
+		return
+	}
+	if req.OldPassword == "" || req.NewPassword == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Old and new password are required"})
+		return
+	}
+
+	ctx := r.Context()
+	principal, _ := auth.FromContext(ctx)
+	user, err := stores.Users.GetByPhone(ctx, principal.PhoneNumber)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading user", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Database error"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)) != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Current password is incorrect"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Error hashing password"})
+		return
+	}
+
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Users.SetPassword(ctx, user.ID, string(hash)); err != nil {
+			return fmt.Errorf("updating password: %v", err)
+		}
+		if err := tx.Sessions.RevokeOthers(ctx, user.ID, principal.SessionID); err != nil {
+			return fmt.Errorf("revoking sessions: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error changing password", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PasswordResponse{false, "Error changing password"})
+		return
+	}
+	json.NewEncoder(w).Encode(PasswordResponse{true, "Password changed, other devices have been logged out"})
+}
+
+// RequestPhoneChangeHandler texts a code to the new phone number. The
+// number only changes once VerifyPhoneChangeHandler gets that code.
+func RequestPhoneChangeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ChangePhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Invalid request body", nil})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", nil})
+		return
+	}
+
+	ctx := r.Context()
+	principal, _ := auth.FromContext(ctx)
+	if phoneNumber == principal.PhoneNumber {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "This is already your phone number", nil})
+		return
+	}
+	existing, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(r.Context()).Error("Database error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Database error", nil})
+		return
+	}
+	if existing != nil && existing.Verified {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Phone number already registered", nil})
+		return
+	}
+
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		return sendOTP(ctx, tx, phoneNumber, models.OTPPhoneChange, principal.UserID)
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error sending phone change code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ProfileResponse{false, message, nil})
+		return
+	}
+	json.NewEncoder(w).Encode(ProfileResponse{true, "Verification code sent", nil})
+}
+
+// VerifyPhoneChangeHandler moves the account to the new phone number with
+// the code sent to it, and tells the old number about the change
+func VerifyPhoneChangeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req VerifyPhoneChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Invalid request body", nil})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", nil})
+		return
+	}
+	if req.Code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Code is required", nil})
+		return
+	}
+
+	ctx := r.Context()
+	principal, _ := auth.FromContext(ctx)
+	otp, err := checkOTP(ctx, phoneNumber, models.OTPPhoneChange, req.Code)
+	if err == nil && otp.UserID != principal.UserID {
+		// Someone else asked to move to this number
+		err = errOTPInvalid
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error verifying phone change code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ProfileResponse{false, message, nil})
+		return
+	}
+
+	// A registration of the number that was never verified proves nothing
+	// and gives way
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Users.DeleteUnverified(ctx, phoneNumber); err != nil {
+			return fmt.Errorf("removing unverified registration: %v", err)
+		}
+		return tx.Users.SetPhone(ctx, principal.UserID, phoneNumber)
+	})
+	if errors.Is(err, store.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Phone number already registered", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error changing phone number", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ProfileResponse{false, "Error changing phone number", nil})
+		return
+	}
+
+	message := fmt.Sprintf("The phone number of your go-rent account was changed to %s.", phoneNumber)
+	if err := smsSender.Send(ctx, principal.PhoneNumber, message); err != nil {
+		logging.FromContext(r.Context()).Error("Error notifying old phone number", "error", err)
+	}
+
+	user, err := stores.Users.Get(ctx, principal.UserID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading profile", "error", err)
+		json.NewEncoder(w).Encode(ProfileResponse{true, "Phone number changed", nil})
+		return
+	}
+	json.NewEncoder(w).Encode(ProfileResponse{true, "Phone number changed", toProfile(user)})
+}
@@ -1,17 +1,20 @@
 package handlers
 
 import (
-	
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"go-rent/config"
-	"go-rent/utils"
-	
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/policy"
+	"go-rent/store"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
 	"github.com/gorilla/mux"
 )
 
@@ -31,6 +34,22 @@ type Property struct {
 	Name      string `json:"name"`
 	Address   string `json:"address"`
 	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	// Role is the caller's role on the property in /properties
+	Role string `json:"role,omitempty"`
+}
+
+// UpdatePropertyRequest changes the fields that are present. PUT needs
+// every field, PATCH any of them.
+type UpdatePropertyRequest struct {
+	Name    *string `json:"name"`
+	Address *string `json:"address"`
+}
+
+type UpdatePropertyResponse struct {
+	Success  bool      `json:"success"`
+	Message  string    `json:"message"`
+	Property *Property `json:"property,omitempty"`
 }
 
 type UserPropertiesResponse struct {
@@ -45,6 +64,10 @@ type SinglePropertyResponse struct {
 	Property Property `json:"property,omitempty"`
 	Floors   []Floor  `json:"floors,omitempty"`
 	IsManager bool    `json:"is_manager,omitempty"`
+	// Role and Permissions are the caller's role on the property and what
+	// it allows, empty for tenants
+	Role        string          `json:"role,omitempty"`
+	Permissions []policy.Action `json:"permissions,omitempty"`
 }
 
 type Floor struct {
@@ -55,11 +78,16 @@ type Floor struct {
 	Tenant    *int64 `json:"tenant,omitempty"`
 	Status    string `json:"status,omitempty"`
 	NotificationID *int64 `json:"notification_id,omitempty"`
+	ArchivedAt     string `json:"archived_at,omitempty"`
+	// Units of the floor. Rent, Tenant, Status and NotificationID repeat
+	// those of the unit when the floor has only one.
+	Units []Unit `json:"units"`
 }
 
 type FloorRequest struct {
 	Name              string `json:"name"`
-	Rent             int    `json:"rent"`
+	// Rent is left unchanged by an update that doesn't send it
+	Rent             *int   `json:"rent"`
 	Tenant           *int64 `json:"tenant,omitempty"`
 	DueRent          int    `json:"due_rent,omitempty"`
 	DueElectricityBill int  `json:"due_electricity_bill,omitempty"`
@@ -120,7 +148,12 @@ type Notification struct {
 		ID   int64  `json:"id"`
 		Name string `json:"name"`
 	} `json:"floor"`
-	ShowActions bool `json:"show_actions"`
+	Unit struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"unit"`
+	Role        string `json:"role,omitempty"`
+	ShowActions bool   `json:"show_actions"`
 }
 
 type NotificationsResponse struct {
@@ -130,10 +163,6 @@ type NotificationsResponse struct {
 }
 
 func AddPropertyHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Add Property Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -143,13 +172,7 @@ func AddPropertyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(PropertyResponse{false, "User not authenticated", 0})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
 	var req PropertyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -164,97 +187,41 @@ func AddPropertyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PropertyResponse{false, "Database connection error", 0})
-		return
-	}
-
-	// Generate random ID for property
-	randomID, err := utils.GenerateRandomID()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PropertyResponse{false, "Error generating property ID", 0})
-		return
-	}
+	ctx := r.Context()
 
 	// Insert property into database
-	_, err = db.Exec(
-		`INSERT INTO property (id, name, address, created_at, created_by, updated_at, updated_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		randomID,
-		req.Name,
-		req.Address,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-	)
+	property := models.Property{
+		Name:      req.Name,
+		Address:   req.Address,
+		CreatedBy: userID,
+	}
+	// The property and its takes_care_of row are written together so a
+	// failure can't leave a property that nobody manages
+	err := stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Properties.Create(ctx, &property); err != nil {
+			return fmt.Errorf("adding property: %v", err)
+		}
+		if err := tx.Properties.AddMember(ctx, property.ID, userID, models.RoleOwner, userID); err != nil {
+			return fmt.Errorf("saving property care details: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
+		logging.FromContext(r.Context()).Error("Error adding property", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(PropertyResponse{false, "Error adding property", 0})
 		return
 	}
 
-	// Insert into takes_care_of table
-	takesCareID, err := utils.GenerateRandomID()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PropertyResponse{false, "Error generating care ID", 0})
-		return
-	}
-
-	_, err = db.Exec(
-		`INSERT INTO takes_care_of (id, uid, pid, created_at, created_by, updated_at, updated_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		takesCareID,
-		userID,
-		randomID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-	)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PropertyResponse{false, "Error saving property care details", 0})
-		return
-	}
-
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(PropertyResponse{
 		Success: true,
 		Message: "Property added successfully",
-		PropertyID: randomID,
+		PropertyID: property.ID,
 	})
 }
 
-// getUserIDFromSession retrieves the user ID from the session token
-func getUserIDFromSession(r *http.Request) int64 {
-	// Get token from cookie
-	cookie, err := r.Cookie("sessiontoken")
-	if err != nil {
-		fmt.Printf("Error getting session token: %v\n", err)
-		return 0 // Return 0 to indicate no user ID found
-	}
-
-	// Validate token and get user ID
-	userID, err := utils.ValidateToken(cookie.Value)
-	if err != nil {
-		fmt.Printf("Error validating token: %v\n", err)
-		return 0 // Return 0 to indicate no user ID found
-	}
-
-	return userID
-}
-
 func GetUserPropertiesHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get User Properties Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -264,63 +231,25 @@ func GetUserPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "User not authenticated", nil})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	fmt.Printf("Fetching properties for user ID: %d\n", userID)
+	logging.FromContext(r.Context()).Debug("Fetching properties")
 
-	db, err := config.GetDBConnection()
+	managed, err := stores.Properties.ListManaged(r.Context(), userID)
 	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Database connection error", nil})
-		return
-	}
-
-	// Query to get all properties for the user
-	query := `
-		SELECT p.id, p.name, p.address, p.created_at 
-		FROM property p
-		INNER JOIN takes_care_of t ON p.id = t.pid
-		WHERE t.uid = ?
-		ORDER BY p.created_at DESC`
-	
-	fmt.Printf("Executing query: %s with userID: %d\n", query, userID)
-	
-	rows, err := db.Query(query, userID)
-	if err != nil {
-		fmt.Printf("Error querying properties: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying properties", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Error fetching properties", nil})
 		return
 	}
-	defer rows.Close()
 
 	var properties []Property
-	for rows.Next() {
-		var prop Property
-		if err := rows.Scan(&prop.ID, &prop.Name, &prop.Address, &prop.CreatedAt); err != nil {
-			fmt.Printf("Error scanning property row: %v\n", err)
-			continue
-		}
-		properties = append(properties, prop)
-		fmt.Printf("Found property: ID=%d, Name=%s\n", prop.ID, prop.Name)
+	for _, p := range managed {
+		properties = append(properties, toProperty(p))
+		logging.FromContext(r.Context()).Debug("Found property", "property_id", p.ID, "name", p.Name)
 	}
 
-	if err = rows.Err(); err != nil {
-		fmt.Printf("Error iterating property rows: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Error processing properties", nil})
-		return
-	}
-
-	fmt.Printf("Found %d properties\n", len(properties))
+	logging.FromContext(r.Context()).Debug("Found properties", "count", len(properties))
 
 	response := UserPropertiesResponse{
 		Success: true,
@@ -328,270 +257,205 @@ func GetUserPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 		Properties: properties,
 	}
 
-	// Log the response
-	responseJSON, _ := json.MarshalIndent(response, "", "  ")
-	fmt.Printf("Sending response: %s\n", string(responseJSON))
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
 func GetPropertyByIDHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get Property By ID Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Method not allowed", Property{}, nil, false})
+		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Method not allowed", Property{}, nil, false, "", nil})
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "User not authenticated", Property{}, nil, false})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	// Extract property ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Invalid property ID format", Property{}, nil, false})
-		return
-	}
-
-	propertyID, err := strconv.ParseInt(pathParts[2], 10, 64)
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Invalid property ID", Property{}, nil, false})
+		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Invalid property ID", Property{}, nil, false, "", nil})
 		return
 	}
 
-	fmt.Printf("Fetching property ID: %d for user ID: %d\n", propertyID, userID)
+	logging.FromContext(r.Context()).Debug("Fetching property", "property_id", propertyID)
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Database connection error", Property{}, nil, false})
-		return
-	}
-
-	// Query to get the specific property and verify user has access
-	query := `
-		SELECT p.id, p.name, p.address, p.created_at 
-		FROM property p
-		WHERE p.id = ? AND (
-			EXISTS (
-				SELECT 1 FROM takes_care_of t 
-				WHERE t.pid = p.id AND t.uid = ?
-			) OR EXISTS (
-				SELECT 1 FROM floor f 
-				WHERE f.pid = p.id AND f.tenant = ?
-			)
-		)`
-	
-	fmt.Printf("Executing query: %s with propertyID: %d, userID: %d\n", query, propertyID, userID, userID)
-	
-	var prop Property
-	err = db.QueryRow(query, propertyID, userID, userID).Scan(&prop.ID, &prop.Name, &prop.Address, &prop.CreatedAt)
+	ctx := r.Context()
+
+	// Get the specific property and verify user has access
+	stored, err := stores.Properties.GetForUser(ctx, propertyID, userID)
 	if err != nil {
-		fmt.Printf("Error querying property: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying property", "error", err)
 		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Property not found or access denied", Property{}, nil, false})
+		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Property not found or access denied", Property{}, nil, false, "", nil})
 		return
 	}
+	prop := toProperty(*stored)
 
 	// Get all floors for this property
-	floorsQuery := `
-		SELECT f.id, f.name, f.rent, f.created_at, f.tenant,
-		       EXISTS(
-		           SELECT 1 FROM notification n 
-		           WHERE n.fid = f.id AND n.status = 'pending'
-		       ) as has_pending_request,
-		       (
-		           SELECT n.id 
-		           FROM notification n 
-		           WHERE n.fid = f.id AND n.status = 'pending'
-		           LIMIT 1
-		       ) as notification_id
-		FROM floor f
-		WHERE f.pid = ?
-		ORDER BY f.created_at DESC`
-	
-	rows, err := db.Query(floorsQuery, propertyID)
+	storedFloors, err := stores.Floors.ListByProperty(ctx, propertyID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error querying floors", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Error fetching floors", prop, nil, false, "", nil})
+		return
+	}
+	units, err := stores.Units.ListByProperty(ctx, propertyID)
 	if err != nil {
-		fmt.Printf("Error querying floors: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying units", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Error fetching floors", prop, nil, false})
+		json.NewEncoder(w).Encode(SinglePropertyResponse{false, "Error fetching floors", prop, nil, false, "", nil})
 		return
 	}
-	defer rows.Close()
 
 	var floors []Floor
-	for rows.Next() {
-		var floor Floor
-		var tenant sql.NullInt64
-		var hasPendingRequest bool
-		var notificationID sql.NullInt64
-		if err := rows.Scan(&floor.ID, &floor.Name, &floor.Rent, &floor.CreatedAt, &tenant, &hasPendingRequest, &notificationID); err != nil {
-			fmt.Printf("Error scanning floor row: %v\n", err)
-			continue
-		}
-		if tenant.Valid {
-			floor.Tenant = &tenant.Int64
-		}
-		if hasPendingRequest {
-			floor.Status = "pending"
-			if notificationID.Valid {
-				floor.NotificationID = &notificationID.Int64
-			}
-		}
-		floors = append(floors, floor)
+	for _, f := range storedFloors {
+		floors = append(floors, toFloor(f, units))
 	}
 
-	fmt.Printf("Found property: ID=%d, Name=%s with %d floors\n", prop.ID, prop.Name, len(floors))
+	logging.FromContext(r.Context()).Debug("Found property", "property_id", prop.ID, "name", prop.Name, "floors", len(floors))
 
-	// Check if user is a manager of this property
-	var isManager bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE uid = ? AND pid = ?
-		)`, userID, propertyID).Scan(&isManager)
-	
-	if err != nil {
-		fmt.Printf("Error checking manager status: %v\n", err)
-		isManager = false
+	// Check the user's role on this property, tenants have none
+	role, err := stores.Properties.Role(ctx, propertyID, userID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(r.Context()).Error("Error checking property role", "error", err)
 	}
 
-	fmt.Printf("User %d is manager of property %d: %v\n", userID, propertyID, isManager)
+	logging.FromContext(r.Context()).Debug("Checked role", "property_id", propertyID, "role", role)
 
 	response := SinglePropertyResponse{
 		Success: true,
 		Message: "Property retrieved successfully",
 		Property: prop,
 		Floors: floors,
-		IsManager: isManager,
+		IsManager: role != "",
+		Role: role,
+		Permissions: policy.Permissions(role),
 	}
 
-	// Log the response
-	responseJSON, _ := json.MarshalIndent(response, "", "  ")
-	fmt.Printf("Sending response: %s\n", string(responseJSON))
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// HandlePropertyRoutes handles all property-related routes
-func HandlePropertyRoutes(w http.ResponseWriter, r *http.Request) {
-	pathParts := strings.Split(r.URL.Path, "/")
-	
-	// Remove empty strings from path parts
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
+// UpdatePropertyHandler handles PUT and PATCH requests to change the name
+// and address of a property, the only fields of it that can be edited
+func UpdatePropertyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Invalid property ID", nil})
+		return
 	}
 
-	// Handle different routes
-	switch {
-	case len(cleanParts) == 1 && cleanParts[0] == "property":
-		// /property
-		GetUserPropertiesHandler(w, r)
-	case len(cleanParts) == 2 && cleanParts[0] == "property":
-		if cleanParts[1] == "tenant" {
-			// /property/tenant
-			GetUserTenantPropertiesHandler(w, r)
-		} else {
-			// /property/{id}
-			GetPropertyByIDHandler(w, r)
-		}
-	case len(cleanParts) == 3 && cleanParts[0] == "property" && cleanParts[2] == "floor":
-		// /property/{id}/floor
-		switch r.Method {
-		case http.MethodGet:
-			GetFloorsHandler(w, r)
-		case http.MethodPost:
-			AddFloorHandler(w, r)
-		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(FloorResponse{false, "Method not allowed", 0})
-		}
-	case len(cleanParts) == 4 && cleanParts[0] == "property" && cleanParts[2] == "floor":
-		// /property/{id}/floor/{floor_id}
-		switch r.Method {
-		case http.MethodGet:
-			GetFloorByIDHandler(w, r)
-		case http.MethodPut:
-			UpdateFloorHandler(w, r)
-		case http.MethodPost:
-			SendTenantRequestHandler(w, r)
-		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(FloorResponse{false, "Method not allowed", 0})
-		}
-	case len(cleanParts) == 5 && cleanParts[0] == "property" && cleanParts[2] == "floor" && cleanParts[4] == "payment":
-		// /property/{id}/floor/{floor_id}/payment
-		switch r.Method {
-		case http.MethodPost:
-			CreatePaymentHandler(w, r)
-		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(PaymentResponse{false, "Method not allowed", 0})
-		}
-	default:
-		http.NotFound(w, r)
+	var req UpdatePropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Invalid request body", nil})
+		return
+	}
+	if r.Method == http.MethodPut && (req.Name == nil || req.Address == nil) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "PUT needs name and address, use PATCH to change only some", nil})
+		return
+	}
+	if req.Name != nil && *req.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Property name is required", nil})
+		return
 	}
-}
 
-func AddFloorHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Add Floor Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
+	ctx := r.Context()
+	userID := auth.UserID(ctx)
 
-	// Set response header to JSON
-	w.Header().Set("Content-Type", "application/json")
+	property, err := stores.Properties.GetForUser(ctx, propertyID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Property not found", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error getting property", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Error getting property details", nil})
+		return
+	}
+	if req.Name != nil {
+		property.Name = *req.Name
+	}
+	if req.Address != nil {
+		property.Address = *req.Address
+	}
+	property.UpdatedBy = userID
 
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Method not allowed", 0})
+	err = stores.Properties.Update(ctx, property)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Property not found", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error updating property", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UpdatePropertyResponse{false, "Error updating property", nil})
 		return
 	}
+	logging.FromContext(ctx).Info("Property updated", "property_id", propertyID)
+
+	updated := toProperty(*property)
+	json.NewEncoder(w).Encode(UpdatePropertyResponse{true, "Property updated successfully", &updated})
+}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(FloorResponse{false, "User not authenticated", 0})
+// DeletePropertyHandler handles DELETE requests to delete a property. The
+// property is only marked as deleted, and not while it has tenants.
+func DeletePropertyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(PropertyResponse{false, "Invalid property ID", 0})
 		return
 	}
 
-	// Extract property ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
+	ctx := r.Context()
+	err = stores.Properties.Delete(ctx, propertyID, auth.UserID(ctx))
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(PropertyResponse{false, "Property not found", 0})
+		return
+	case errors.Is(err, store.ErrConflict):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(PropertyResponse{false, "Remove the tenants and cancel pending tenant requests before deleting the property", 0})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Error deleting property", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(PropertyResponse{false, "Error deleting property", 0})
+		return
 	}
+	logging.FromContext(ctx).Info("Property deleted", "property_id", propertyID)
 
-	if len(cleanParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid URL format", 0})
+	json.NewEncoder(w).Encode(PropertyResponse{true, "Property deleted successfully", propertyID})
+}
+
+func AddFloorHandler(w http.ResponseWriter, r *http.Request) {
+	// Set response header to JSON
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Method not allowed", 0})
 		return
 	}
 
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	userID := auth.UserID(r.Context())
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
@@ -611,167 +475,79 @@ func AddFloorHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Database connection error", 0})
-		return
-	}
+	ctx := r.Context()
 
-	// Verify user has access to the property
-	var exists bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE pid = ? AND uid = ?
-		)`, propertyID, userID).Scan(&exists)
-	
-	if err != nil || !exists {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Access denied to property", 0})
-		return
+	// Insert floor into database, with a unit that takes the rent
+	floor := models.Floor{
+		PropertyID: propertyID,
+		Name:       req.Name,
+		CreatedBy:  userID,
 	}
-
-	// Generate random ID for floor
-	floorID, err := utils.GenerateRandomID()
-	if err != nil {
-		fmt.Printf("Error generating random ID: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Error generating floor ID", 0})
-		return
-	}
-
-	// Insert floor into database
-	_, err = db.Exec(`
-		INSERT INTO floor (id, name, rent, created_at, created_by, updated_at, updated_by, pid)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		floorID,
-		req.Name,
-		req.Rent,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		propertyID,
-	)
+	rent := 0
+	if req.Rent != nil {
+		rent = *req.Rent
+	}
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		return createFloor(ctx, tx, &floor, rent)
+	})
 	if err != nil {
-		fmt.Printf("Error inserting floor: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error inserting floor", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Error adding floor", 0})
 		return
 	}
 
-	fmt.Printf("Successfully added floor ID: %d to property ID: %d\n", floorID, propertyID)
+	logging.FromContext(r.Context()).Info("Floor added", "floor_id", floor.ID, "property_id", propertyID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(FloorResponse{
 		Success: true,
 		Message: "Floor added successfully",
-		FloorID: floorID,
+		FloorID: floor.ID,
 	})
 }
 
 // GetFloorsHandler handles GET requests for floors of a property
 func GetFloorsHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get Floors Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(FloorResponse{false, "User not authenticated", 0})
-		return
-	}
-
-	// Extract property ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid URL format", 0})
-		return
-	}
-
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
 		return
 	}
 
-	db, err := config.GetDBConnection()
+	ctx := r.Context()
+
+	// Get all floors for this property, or the archived ones with ?archived=true
+	var storedFloors []models.Floor
+	if r.URL.Query().Get("archived") == "true" {
+		storedFloors, err = stores.Floors.ListArchived(ctx, propertyID)
+	} else {
+		storedFloors, err = stores.Floors.ListByProperty(ctx, propertyID)
+	}
 	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying floors", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Database connection error", 0})
-		return
-	}
-
-	// Verify user has access to the property
-	var exists bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE pid = ? AND uid = ?
-		)`, propertyID, userID).Scan(&exists)
-	
-	if err != nil || !exists {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Access denied to property", 0})
+		json.NewEncoder(w).Encode(FloorResponse{false, "Error fetching floors", 0})
 		return
 	}
-
-	// Get all floors for this property
-	rows, err := db.Query(`
-		SELECT f.id, f.name, f.rent, f.created_at, f.tenant,
-		       EXISTS(
-		           SELECT 1 FROM notification n 
-		           WHERE n.fid = f.id AND n.status = 'pending'
-		       ) as has_pending_request
-		FROM floor f
-		WHERE f.pid = ?
-		ORDER BY f.created_at DESC`, propertyID)
-	
+	units, err := stores.Units.ListByProperty(ctx, propertyID)
 	if err != nil {
-		fmt.Printf("Error querying floors: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying units", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Error fetching floors", 0})
 		return
 	}
-	defer rows.Close()
 
 	var floors []Floor
-	for rows.Next() {
-		var floor Floor
-		var tenant sql.NullInt64
-		var hasPendingRequest bool
-		if err := rows.Scan(&floor.ID, &floor.Name, &floor.Rent, &floor.CreatedAt, &tenant, &hasPendingRequest); err != nil {
-			fmt.Printf("Error scanning floor row: %v\n", err)
-			continue
-		}
-		if tenant.Valid {
-			floor.Tenant = &tenant.Int64
-		}
-		if hasPendingRequest {
-			floor.Status = "pending"
-		}
-		floors = append(floors, floor)
+	for _, f := range storedFloors {
+		floors = append(floors, toFloor(f, units))
 	}
 
-	fmt.Printf("Found %d floors for property ID: %d\n", len(floors), propertyID)
+	logging.FromContext(r.Context()).Debug("Found floors", "count", len(floors), "property_id", propertyID)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -783,94 +559,44 @@ func GetFloorsHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetFloorByIDHandler handles GET requests for a specific floor
 func GetFloorByIDHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get Floor By ID Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(FloorResponse{false, "User not authenticated", 0})
-		return
-	}
-
-	// Extract property ID and floor ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 4 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid URL format", 0})
-		return
-	}
-
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
 		return
 	}
 
-	floorID, err := strconv.ParseInt(cleanParts[3], 10, 64)
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid floor ID", 0})
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Database connection error", 0})
-		return
-	}
-
-	// Verify user has access to the property
-	var exists bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE pid = ? AND uid = ?
-		)`, propertyID, userID).Scan(&exists)
-	
-	if err != nil || !exists {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Access denied to property", 0})
-		return
-	}
+	ctx := r.Context()
 
 	// Get floor details
-	var floor Floor
-	var tenant sql.NullInt64
-	err = db.QueryRow(`
-		SELECT id, name, rent, created_at, tenant
-		FROM floor
-		WHERE id = ? AND pid = ?`, floorID, propertyID).Scan(
-		&floor.ID, &floor.Name, &floor.Rent, &floor.CreatedAt, &tenant)
-	
+	stored, err := stores.Floors.Get(ctx, propertyID, floorID)
 	if err != nil {
-		fmt.Printf("Error querying floor: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying floor", "error", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Floor not found", 0})
 		return
 	}
-
-	if tenant.Valid {
-		floor.Tenant = &tenant.Int64
+	units, err := stores.Units.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error querying units", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Error fetching floor", 0})
+		return
 	}
+	floor := toFloor(*stored, units)
 
-	fmt.Printf("Found floor: ID=%d, Name=%s\n", floor.ID, floor.Name)
+	logging.FromContext(r.Context()).Debug("Found floor", "floor_id", floor.ID, "name", floor.Name)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -882,45 +608,20 @@ func GetFloorByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateFloorHandler handles PUT requests to update a floor
 func UpdateFloorHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Update Floor Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(FloorResponse{false, "User not authenticated", 0})
-		return
-	}
-
-	// Extract property ID and floor ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 4 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid URL format", 0})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
 		return
 	}
 
-	floorID, err := strconv.ParseInt(cleanParts[3], 10, 64)
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid floor ID", 0})
@@ -940,84 +641,110 @@ func UpdateFloorHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Database connection error", 0})
-		return
-	}
+	ctx := r.Context()
 
-	// Verify user has access to the property
-	var exists bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE pid = ? AND uid = ?
-		)`, propertyID, userID).Scan(&exists)
-	
-	if err != nil || !exists {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Access denied to property", 0})
-		return
-	}
-
-	// Update floor
-	_, err = db.Exec(`
-		UPDATE floor 
-		SET name = ?, rent = ?, tenant = ?, updated_at = ?, updated_by = ?
-		WHERE id = ? AND pid = ?`,
-		req.Name, req.Rent, req.Tenant, time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"), userID, floorID, propertyID)
-	
-	if err != nil {
-		fmt.Printf("Error updating floor: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(FloorResponse{false, "Error updating floor", 0})
-		return
-	}
-
-	// If tenant is being added, create a payment record
-	if req.Tenant != nil {
-		// Generate random ID for payment
-		paymentID, err := utils.GenerateRandomID()
+	// Update floor and its unit if it has only one. If a tenant is being
+	// added, open their tenancy and create a payment record.
+	errTenantPresent := errors.New("unit already has a tenant")
+	errRentPerUnit := errors.New("rent of a floor with several units")
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		current, err := tx.Floors.Get(ctx, propertyID, floorID)
 		if err != nil {
-			fmt.Printf("Error generating payment ID: %v\n", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(FloorResponse{false, "Error generating payment ID", 0})
-			return
+			return err
+		}
+		err = tx.Floors.Update(ctx, &models.Floor{
+			ID:         floorID,
+			PropertyID: propertyID,
+			Name:       req.Name,
+			UpdatedBy:  userID,
+		})
+		if err != nil {
+			return fmt.Errorf("updating floor: %v", err)
 		}
 
-		// Insert payment record
-		_, err = db.Exec(`
-			INSERT INTO payment (
-				id, due_rent, due_electrictiy_bill, recieved_money, 
-				full_payment, created_at, created_by, updated_at, updated_by,
-				fid, uid
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			paymentID,
-			0, // due_rent
-			0, // due_electricity_bill
-			0, // received_money
-			true, // full_payment
-			time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-			userID,
-			time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-			userID,
-			floorID,
-			*req.Tenant,
-		)
+		// Rent and tenant of a floor with several units are set per unit
+		units, err := tx.Units.ListByFloor(ctx, propertyID, floorID)
+		if err != nil {
+			return fmt.Errorf("listing units: %v", err)
+		}
+		if len(units) != 1 {
+			if req.Tenant != nil {
+				return errSeveralUnits
+			}
+			if req.Rent != nil {
+				return errRentPerUnit
+			}
+			return nil
+		}
+		unit := units[0]
+		if unit.Name == current.Name {
+			unit.Name = req.Name
+		}
+		if req.Rent != nil {
+			unit.Rent = *req.Rent
+		}
+		unit.UpdatedBy = userID
+		if err := tx.Units.Update(ctx, &unit); err != nil {
+			return fmt.Errorf("updating unit: %v", err)
+		}
 
+		// Without a tenant in the request the current one stays. Tenants
+		// leave through RemoveTenantHandler, which ends their tenancy.
+		if req.Tenant == nil || (unit.Tenant != nil && *unit.Tenant == *req.Tenant) {
+			return nil
+		}
+		err = tx.Units.AssignTenant(ctx, unit.ID, *req.Tenant, userID)
+		if errors.Is(err, store.ErrConflict) {
+			return errTenantPresent
+		}
 		if err != nil {
-			fmt.Printf("Error creating payment record: %v\n", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(FloorResponse{false, fmt.Sprintf("Error creating payment record: %v", err), 0})
-			return
+			return fmt.Errorf("assigning tenant: %v", err)
+		}
+		if err := startTenancy(ctx, tx, &unit, *req.Tenant, userID); err != nil {
+			return err
 		}
 
-		fmt.Printf("Successfully created payment record for floor ID: %d and tenant ID: %d\n", floorID, *req.Tenant)
+		// Opening balance: nothing due, nothing received
+		err = tx.Payments.Create(ctx, &models.Payment{
+			FloorID:     floorID,
+			UnitID:      unit.ID,
+			TenantID:    *req.Tenant,
+			FullPayment: true,
+			CreatedBy:   userID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating payment record: %v", err)
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Floor not found", 0})
+		return
+	}
+	if errors.Is(err, errSeveralUnits) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(FloorResponse{false, "This floor has several units, send a tenant request for one of them instead", 0})
+		return
+	}
+	if errors.Is(err, errRentPerUnit) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(FloorResponse{false, "This floor has several units, set the rent of each unit instead", 0})
+		return
+	}
+	if errors.Is(err, errTenantPresent) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Remove the current tenant before adding another", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error updating floor", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Error updating floor", 0})
+		return
 	}
 
-	fmt.Printf("Successfully updated floor ID: %d\n", floorID)
+	logging.FromContext(r.Context()).Info("Floor updated", "floor_id", floorID)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(FloorResponse{
@@ -1027,75 +754,118 @@ func UpdateFloorHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetUserPhonesHandler handles GET requests for all users' phone numbers
-func GetUserPhonesHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get User Phones Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
-	// Set response header to JSON
+// DeleteFloorHandler handles DELETE requests to delete a floor. The floor
+// is archived so its payments and notifications stay, and not while it has
+// a tenant or a pending tenant request.
+func DeleteFloorHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(UserPhonesResponse{false, "Method not allowed", nil})
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
+		return
+	}
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid floor ID", 0})
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(UserPhonesResponse{false, "User not authenticated", nil})
+	ctx := r.Context()
+	err = stores.Floors.Archive(ctx, propertyID, floorID, auth.UserID(ctx))
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Floor not found", 0})
+		return
+	case errors.Is(err, store.ErrConflict):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Remove the tenant and cancel pending tenant requests before deleting the floor", 0})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Error archiving floor", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Error deleting floor", 0})
 		return
 	}
+	logging.FromContext(ctx).Info("Floor archived", "property_id", propertyID, "floor_id", floorID)
+
+	json.NewEncoder(w).Encode(FloorResponse{true, "Floor deleted successfully", floorID})
+}
+
+// RestoreFloorHandler handles POST requests to bring back an archived floor
+func RestoreFloorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	db, err := config.GetDBConnection()
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid property ID", 0})
+		return
+	}
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Invalid floor ID", 0})
+		return
+	}
+
+	ctx := r.Context()
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		return tx.Floors.Restore(ctx, propertyID, floorID, auth.UserID(ctx))
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Archived floor not found", 0})
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(FloorResponse{false, "Another floor of the property already has this name", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error restoring floor", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPhonesResponse{false, "Database connection error", nil})
+		json.NewEncoder(w).Encode(FloorResponse{false, "Error restoring floor", 0})
+		return
+	}
+	logging.FromContext(ctx).Info("Floor restored", "property_id", propertyID, "floor_id", floorID)
+
+	json.NewEncoder(w).Encode(FloorResponse{true, "Floor restored successfully", floorID})
+}
+
+// GetUserPhonesHandler handles GET requests for all users' phone numbers
+func GetUserPhonesHandler(w http.ResponseWriter, r *http.Request) {
+	// Set response header to JSON
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(UserPhonesResponse{false, "Method not allowed", nil})
 		return
 	}
 
-	// Query to get all users' phone numbers
-	query := `
-		SELECT id, phone_number 
-		FROM user 
-		WHERE phone_number IS NOT NULL AND phone_number != ''
-		ORDER BY id DESC`
-	
-	fmt.Printf("Executing query: %s\n", query)
-	
-	rows, err := db.Query(query)
+	// Get all users' phone numbers
+	stored, err := stores.Users.ListWithPhone(r.Context())
 	if err != nil {
-		fmt.Printf("Error querying users: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying users", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(UserPhonesResponse{false, "Error fetching users", nil})
 		return
 	}
-	defer rows.Close()
 
 	var users []UserPhone
-	for rows.Next() {
-		var user UserPhone
-		if err := rows.Scan(&user.ID, &user.Phone); err != nil {
-			fmt.Printf("Error scanning user row: %v\n", err)
-			continue
-		}
-		users = append(users, user)
-		fmt.Printf("Found user: ID=%d, Phone=%s\n", user.ID, user.Phone)
-	}
-
-	if err = rows.Err(); err != nil {
-		fmt.Printf("Error iterating user rows: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPhonesResponse{false, "Error processing users", nil})
-		return
+	for _, u := range stored {
+		users = append(users, UserPhone{ID: u.ID, Phone: u.PhoneNumber})
+		logging.FromContext(r.Context()).Debug("Found user", "user", u.ID, "phone_number", u.PhoneNumber)
 	}
 
-	fmt.Printf("Found %d users with phone numbers\n", len(users))
+	logging.FromContext(r.Context()).Debug("Found users with phone numbers", "count", len(users))
 
 	response := UserPhonesResponse{
 		Success: true,
@@ -1103,20 +873,12 @@ func GetUserPhonesHandler(w http.ResponseWriter, r *http.Request) {
 		Users:   users,
 	}
 
-	// Log the response
-	responseJSON, _ := json.MarshalIndent(response, "", "  ")
-	fmt.Printf("Sending response: %s\n", string(responseJSON))
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
 // GetUserIDByPhoneHandler handles GET requests to get user ID by phone number
 func GetUserIDByPhoneHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get User ID By Phone Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1126,81 +888,44 @@ func GetUserIDByPhoneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(UserIDResponse{false, "User not authenticated", 0})
-		return
-	}
-
-	// Extract phone number from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(UserIDResponse{false, "Invalid URL format", 0})
-		return
-	}
-
-	phoneNumber := cleanParts[2]
+	phoneNumber := mux.Vars(r)["phone"]
 	if phoneNumber == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(UserIDResponse{false, "Phone number is required", 0})
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserIDResponse{false, "Database connection error", 0})
-		return
+	// Get user ID by phone number. Unverified registrations are not users
+	// yet, anyone could have made them.
+	user, err := stores.Users.GetByPhone(r.Context(), phoneNumber)
+	if err == nil && !user.Verified {
+		err = store.ErrNotFound
 	}
-
-	// Query to get user ID by phone number
-	var foundUserID int64
-	err = db.QueryRow(`
-		SELECT id 
-		FROM user 
-		WHERE phone_number = ?`, phoneNumber).Scan(&foundUserID)
-	
 	if err != nil {
-		if err == sql.ErrNoRows {
-			fmt.Printf("No user found with phone number: %s\n", phoneNumber)
+		if errors.Is(err, store.ErrNotFound) {
+			logging.FromContext(r.Context()).Debug("No user found with phone number", "phone_number", phoneNumber)
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(UserIDResponse{false, "User not found", 0})
 			return
 		}
-		fmt.Printf("Error querying user: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying user", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(UserIDResponse{false, "Error fetching user", 0})
 		return
 	}
 
-	fmt.Printf("Found user ID: %d for phone number: %s\n", foundUserID, phoneNumber)
+	logging.FromContext(r.Context()).Debug("Found user by phone number", "user", user.ID, "phone_number", phoneNumber)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(UserIDResponse{
 		Success: true,
 		Message: "User ID retrieved successfully",
-		UserID:  foundUserID,
+		UserID:  user.ID,
 	})
 }
 
 // CreatePaymentHandler handles POST requests to create a payment record
 func CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Create Payment Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1210,107 +935,61 @@ func CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	fmt.Printf("User ID from session: %d\n", userID)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "User not authenticated", 0})
-		return
-	}
-
-	// Extract property ID and floor ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	fmt.Printf("URL parts: %v\n", cleanParts)
-
-	if len(cleanParts) != 5 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "Invalid URL format", 0})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	// Extract property ID and floor ID from URL, the unit ID is optional
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(PaymentResponse{false, "Invalid property ID", 0})
 		return
 	}
 
-	floorID, err := strconv.ParseInt(cleanParts[3], 10, 64)
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(PaymentResponse{false, "Invalid floor ID", 0})
 		return
 	}
 
-	fmt.Printf("Property ID: %d, Floor ID: %d\n", propertyID, floorID)
+	logging.FromContext(r.Context()).Debug("Creating payment", "property_id", propertyID, "floor_id", floorID)
 
 	var req PaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		fmt.Printf("Error decoding request body: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error decoding request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(PaymentResponse{false, "Invalid request body", 0})
 		return
 	}
 
-	fmt.Printf("Request body: %+v\n", req)
-
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "Database connection error", 0})
-		return
-	}
-
-	// Check if user is a manager of the property
-	var isManager bool
-	managerQuery := "SELECT EXISTS(SELECT 1 FROM takes_care_of WHERE uid = ? AND pid = ?)"
-	fmt.Printf("Executing manager check query: %s with userID: %d, propertyID: %d\n", managerQuery, userID, propertyID)
-	
-	err = db.QueryRow(managerQuery, userID, propertyID).Scan(&isManager)
-	if err != nil {
-		fmt.Printf("Error checking manager status: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "Database error", 0})
-		return
-	}
-
-	fmt.Printf("Is user %d manager of property %d: %v\n", userID, propertyID, isManager)
-
-	if !isManager {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "Only managers can create payments", 0})
-		return
-	}
+	ctx := r.Context()
 
-	// Get tenant ID from floor
-	var tenantID int64
-	err = db.QueryRow(`
-		SELECT tenant 
-		FROM floor 
-		WHERE id = ? AND pid = ?`, floorID, propertyID).Scan(&tenantID)
-	
+	// Get tenant ID from unit
+	unit, err := routeUnit(ctx, propertyID, floorID, vars["unit_id"])
 	if err != nil {
-		fmt.Printf("Error getting tenant ID: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error getting tenant ID", "error", err)
+		if errors.Is(err, store.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(PaymentResponse{false, "Floor or unit not found", 0})
+			return
+		}
+		if errors.Is(err, errSeveralUnits) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(PaymentResponse{false, "This floor has several units, record the payment for one of them", 0})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(PaymentResponse{false, "Error getting tenant information", 0})
 		return
 	}
 
-	if tenantID == 0 {
+	if unit.Tenant == nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "No tenant assigned to this floor", 0})
+		json.NewEncoder(w).Encode(PaymentResponse{false, "No tenant assigned to this unit", 0})
 		return
 	}
+	tenantID := *unit.Tenant
 
 	// Calculate total due amount
 	totalDue := req.DueRent + req.DueElectricityBill
@@ -1321,61 +1000,39 @@ func CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 	// Set full_payment based on after_receiving_money
 	fullPayment := afterReceivingMoney == 0
 
-	fmt.Printf("Total due: %d, Received: %d, After receiving: %d, Full payment: %v\n", 
-		totalDue, req.ReceivedMoney, afterReceivingMoney, fullPayment)
-
-	// Generate random ID for payment
-	paymentID, err := utils.GenerateRandomID()
-	if err != nil {
-		fmt.Printf("Error generating payment ID: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PaymentResponse{false, "Error generating payment ID", 0})
-		return
-	}
+	logging.FromContext(r.Context()).Debug("Payment amounts", "total_due", totalDue, "received", req.ReceivedMoney,
+		"after_receiving", afterReceivingMoney, "full_payment", fullPayment)
 
 	// Insert payment record
-	_, err = db.Exec(`
-		INSERT INTO payment (
-			id, due_rent, due_electrictiy_bill, recieved_money, 
-			full_payment, created_at, created_by, updated_at, updated_by,
-			fid, uid
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		paymentID,
-		req.DueRent,
-		req.DueElectricityBill,
-		req.ReceivedMoney,
-		fullPayment,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		floorID,
-		tenantID,
-	)
-
-	if err != nil {
-		fmt.Printf("Error creating payment record: %v\n", err)
+	payment := models.Payment{
+		FloorID:            floorID,
+		UnitID:             unit.ID,
+		TenantID:           tenantID,
+		DueRent:            req.DueRent,
+		DueElectricityBill: req.DueElectricityBill,
+		ReceivedMoney:      req.ReceivedMoney,
+		FullPayment:        fullPayment,
+		CreatedBy:          userID,
+	}
+	if err := stores.Payments.Create(ctx, &payment); err != nil {
+		logging.FromContext(r.Context()).Error("Error creating payment record", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(PaymentResponse{false, fmt.Sprintf("Error creating payment record: %v", err), 0})
 		return
 	}
 
-	fmt.Printf("Successfully created payment record ID: %d for floor ID: %d and tenant ID: %d\n", paymentID, floorID, tenantID)
+	logging.FromContext(r.Context()).Info("Payment created", "payment_id", payment.ID, "floor_id", floorID, "unit_id", unit.ID, "tenant_id", tenantID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(PaymentResponse{
 		Success:   true,
 		Message:   "Payment record created successfully",
-		PaymentID: paymentID,
+		PaymentID: payment.ID,
 	})
 }
 
 // SendTenantRequestHandler handles POST requests to send a tenant request
 func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Send Tenant Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1385,13 +1042,7 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "User not authenticated"})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
 	// Extract property ID and floor ID from URL using Gorilla Mux's Vars
 	vars := mux.Vars(r)
@@ -1423,35 +1074,37 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.GetDBConnection()
+	ctx := r.Context()
+
+	// Get property and floor details
+	property, err := stores.Properties.GetForUser(ctx, propertyID, userID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Database connection error"})
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error getting property details"})
 		return
 	}
-
-	// Check if user is a manager of the property
-	var isManager bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE uid = ? AND pid = ?
-		)`, userID, propertyID).Scan(&isManager)
-	
-	if err != nil || !isManager {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Only managers can send tenant requests"})
+	floor, err := stores.Floors.Get(ctx, propertyID, floorID)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Floor not found"})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error getting property details"})
+		return
+	}
+	unit, err := routeUnit(ctx, propertyID, floorID, vars["unit_id"])
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Unit not found"})
+		return
+	}
+	if errors.Is(err, errSeveralUnits) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "This floor has several units, send the request for one of them"})
 		return
 	}
-
-	// Get property and floor details
-	var propertyName, floorName string
-	err = db.QueryRow(`
-		SELECT p.name, f.name
-		FROM property p
-		JOIN floor f ON p.id = f.pid
-		WHERE p.id = ? AND f.id = ?`, propertyID, floorID).Scan(&propertyName, &floorName)
-	
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error getting property details"})
@@ -1459,13 +1112,12 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get tenant ID from phone number
-	var tenantID int64
-	err = db.QueryRow(`
-		SELECT id FROM user 
-		WHERE phone_number = ?`, req.PhoneNumber).Scan(&tenantID)
-	
+	tenant, err := stores.Users.GetByPhone(ctx, req.PhoneNumber)
+	if err == nil && !tenant.Verified {
+		err = store.ErrNotFound
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(TenantRequestResponse{false, "User not found with this phone number"})
 			return
@@ -1475,14 +1127,8 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if there's already a pending notification for this floor
-	var pendingExists bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM notification 
-			WHERE fid = ? AND status = 'pending'
-		)`, floorID).Scan(&pendingExists)
-	
+	// Check if there's already a pending notification for this unit
+	pendingExists, err := stores.Notifications.HasPendingForUnit(ctx, unit.ID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error checking pending notifications"})
@@ -1491,40 +1137,27 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 
 	if pendingExists {
 		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "A pending request already exists for this floor"})
+		json.NewEncoder(w).Encode(TenantRequestResponse{false, "A pending request already exists for this unit"})
 		return
 	}
 
-	// Generate notification ID
-	notificationID, err := utils.GenerateRandomID()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error generating notification ID"})
-		return
+	// Create notification message, naming the unit if it isn't named like the floor
+	message := fmt.Sprintf("Tenant request for %s - %s", property.Name, floor.Name)
+	if unit.Name != floor.Name {
+		message += " - " + unit.Name
 	}
 
-	// Create notification message
-	message := fmt.Sprintf("Tenant request for %s - %s", propertyName, floorName)
-
 	// Insert notification
-	_, err = db.Exec(`
-		INSERT INTO notification (
-			id, message, sender, receiver, pid, fid,
-			status, created_at, created_by, updated_at, updated_by
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		notificationID,
-		message,
-		userID,
-		tenantID,
-		propertyID,
-		floorID,
-		"pending",
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-		time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02 15:04:05"),
-		userID,
-	)
-
+	err = stores.Notifications.Create(ctx, &models.Notification{
+		Message:    message,
+		Sender:     &userID,
+		Receiver:   tenant.ID,
+		PropertyID: propertyID,
+		FloorID:    &floorID,
+		UnitID:     &unit.ID,
+		Status:     models.NotificationPending,
+		CreatedBy:  userID,
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error creating notification"})
@@ -1540,10 +1173,6 @@ func SendTenantRequestHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetUserNotificationsHandler handles GET requests to get all notifications for a user
 func GetUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get User Notifications Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1553,70 +1182,41 @@ func GetUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(NotificationsResponse{false, "User not authenticated", nil})
-		return
-	}
-
-	db, err := config.GetDBConnection()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(NotificationsResponse{false, "Database connection error", nil})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
 	// Get all notifications for the user
-	query := `
-		SELECT 
-			n.id, n.message, n.status, n.created_at,
-			p.id as property_id, p.name as property_name,
-			f.id as floor_id, f.name as floor_name,
-			CASE 
-				WHEN n.message LIKE 'Tenant request%' AND n.status = 'pending' THEN true
-				ELSE false
-			END as show_actions
-		FROM notification n
-		JOIN property p ON n.pid = p.id
-		JOIN floor f ON n.fid = f.id
-		WHERE n.receiver = ?
-		ORDER BY n.created_at DESC
-	`
-	
-	rows, err := db.Query(query, userID)
+	stored, err := stores.Notifications.ListForReceiver(r.Context(), userID)
 	if err != nil {
-		fmt.Printf("Error querying notifications: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying notifications", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(NotificationsResponse{false, "Error fetching notifications", nil})
 		return
 	}
-	defer rows.Close()
 
 	var notifications []Notification
-	for rows.Next() {
+	for _, sn := range stored {
 		var n Notification
-		if err := rows.Scan(
-			&n.ID, &n.Message, &n.Status, &n.CreatedAt,
-			&n.Property.ID, &n.Property.Name,
-			&n.Floor.ID, &n.Floor.Name,
-			&n.ShowActions,
-		); err != nil {
-			fmt.Printf("Error scanning notification row: %v\n", err)
-			continue
+		n.ID = sn.ID
+		n.Message = sn.Message
+		n.Status = sn.Status
+		n.CreatedAt = sn.CreatedAt
+		n.Property.ID = sn.PropertyID
+		n.Property.Name = sn.PropertyName
+		if sn.FloorID != nil {
+			n.Floor.ID = *sn.FloorID
+			n.Floor.Name = sn.FloorName
+		}
+		if sn.UnitID != nil {
+			n.Unit.ID = *sn.UnitID
+			n.Unit.Name = sn.UnitName
 		}
+		n.Role = sn.Role
+		n.ShowActions = (strings.HasPrefix(sn.Message, "Tenant request") || strings.HasPrefix(sn.Message, "Ownership transfer") || sn.Role != "") &&
+			sn.Status == models.NotificationPending
 		notifications = append(notifications, n)
 	}
 
-	if err = rows.Err(); err != nil {
-		fmt.Printf("Error iterating notification rows: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(NotificationsResponse{false, "Error processing notifications", nil})
-		return
-	}
-
-	fmt.Printf("Found %d notifications for user %d\n", len(notifications), userID)
+	logging.FromContext(r.Context()).Debug("Found notifications", "count", len(notifications))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(NotificationsResponse{
@@ -1628,10 +1228,6 @@ func GetUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 
 // DeleteNotificationHandler handles DELETE requests to remove a notification
 func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Delete Notification Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1641,70 +1237,31 @@ func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "User not authenticated"})
-		return
-	}
-
-	// Extract notification ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Invalid URL format"})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	notificationID, err := strconv.ParseInt(cleanParts[2], 10, 64)
+	notificationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Invalid notification ID"})
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Database connection error"})
-		return
-	}
-
-	// Check if user is the sender of the notification and if it's pending
-	var isSender bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM notification 
-			WHERE id = ? AND sender = ? AND status = 'pending'
-		)`, notificationID, userID).Scan(&isSender)
-	
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error checking notification"})
-		return
-	}
-
-	if !isSender {
+	// Delete the notification if user is its sender and it's still pending.
+	// Withdrawing the notification of an ownership transfer cancels it.
+	ctx := r.Context()
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Notifications.DeletePending(ctx, notificationID, userID); err != nil {
+			return err
+		}
+		return cancelTransfer(ctx, tx, notificationID, userID)
+	})
+	if errors.Is(err, store.ErrNotFound) {
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "You can only delete your own pending notifications"})
 		return
 	}
-
-	// Delete the notification
-	_, err = db.Exec(`
-		DELETE FROM notification 
-		WHERE id = ? AND sender = ? AND status = 'pending'`,
-		notificationID, userID)
-	
 	if err != nil {
+		logging.FromContext(ctx).Error("Error deleting notification", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(TenantRequestResponse{false, "Error deleting notification"})
 		return
@@ -1728,82 +1285,66 @@ func SendMonthlyNotifications() {
 		return
 	}
 
-	fmt.Println("=== Sending Monthly Notifications ===")
-
-	// Get database connection
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		return
-	}
+	ctx := context.Background()
+	logging.FromContext(ctx).Info("Sending monthly notifications")
 
-	// Get all floors with tenants
-	query := `
-		SELECT f.id, f.pid, f.tenant, p.name as property_name
-		FROM floor f
-		JOIN property p ON f.pid = p.id
-		WHERE f.tenant IS NOT NULL
-	`
-	rows, err := db.Query(query)
+	// Get all units with tenants
+	units, err := stores.Units.ListOccupied(ctx)
 	if err != nil {
-		fmt.Printf("Error querying floors: %v\n", err)
+		logging.FromContext(ctx).Error("Error querying units", "error", err)
 		return
 	}
-	defer rows.Close()
 
-	// Process each floor
-	for rows.Next() {
-		var floorID, propertyID, tenantID int
-		var propertyName string
-		if err := rows.Scan(&floorID, &propertyID, &tenantID, &propertyName); err != nil {
-			fmt.Printf("Error scanning floor: %v\n", err)
+	// Process each unit
+	for _, unit := range units {
+		property, err := stores.Properties.GetForUser(ctx, unit.PropertyID, *unit.Tenant)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error querying property", "error", err)
 			continue
 		}
 
-		// Get latest payment for this floor
+		// Get latest payment for this unit
 		var dueRent, dueElectricity, receivedMoney float64
-		paymentQuery := `
-			SELECT due_rent, due_electrictiy_bill, recieved_money
-			FROM payment
-			WHERE fid = ?
-			ORDER BY created_at DESC
-			LIMIT 1
-		`
-		err := db.QueryRow(paymentQuery, floorID).Scan(&dueRent, &dueElectricity, &receivedMoney)
-		if err != nil && err != sql.ErrNoRows {
-			fmt.Printf("Error querying payment: %v\n", err)
+		payment, err := stores.Payments.LatestForUnit(ctx, unit.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			logging.FromContext(ctx).Error("Error querying payment", "error", err)
 			continue
 		}
+		if payment != nil {
+			dueRent = float64(payment.DueRent)
+			dueElectricity = float64(payment.DueElectricityBill)
+			receivedMoney = float64(payment.ReceivedMoney)
+		}
 
 		// Calculate due payment
 		duePayment := dueRent + dueElectricity - receivedMoney
 
 		// Create notification
-		notificationQuery := `
-			INSERT INTO notification (pid, receiver, message, created_at)
-			VALUES (?, ?, ?, NOW())
-		`
 		message := fmt.Sprintf("Monthly rent reminder for %s:\nDue Rent: ৳%.2f\nDue Electricity: ৳%.2f\nReceived Money: ৳%.2f\nDue Payment: ৳%.2f",
-			propertyName, dueRent, dueElectricity, receivedMoney, duePayment)
-
-		_, err = db.Exec(notificationQuery, propertyID, tenantID, message)
+			property.Name, dueRent, dueElectricity, receivedMoney, duePayment)
+
+		floorID, unitID := unit.FloorID, unit.ID
+		err = stores.Notifications.Create(ctx, &models.Notification{
+			Message:    message,
+			Receiver:   *unit.Tenant,
+			PropertyID: unit.PropertyID,
+			FloorID:    &floorID,
+			UnitID:     &unitID,
+		})
 		if err != nil {
-			fmt.Printf("Error creating notification: %v\n", err)
+			logging.FromContext(ctx).Error("Error creating notification", "error", err)
 			continue
 		}
 
-		fmt.Printf("Created notification for tenant %d in property %s\n", tenantID, propertyName)
+		logging.FromContext(ctx).Debug("Created monthly notification", "tenant_id", *unit.Tenant, "property_id", property.ID)
 	}
 
-	fmt.Println("Monthly notifications sent successfully.")
+	logging.FromContext(ctx).Info("Monthly notifications sent")
 }
 
-// HandleTenantRequestAction handles POST requests to accept/reject tenant requests
+// HandleTenantRequestAction handles POST requests to accept/reject tenant
+// requests, invitations to join a property and ownership transfers
 func HandleTenantRequestAction(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Tenant Request Action ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1813,13 +1354,7 @@ func HandleTenantRequestAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "User not authenticated"})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
 	// Parse request body
 	var request struct {
@@ -1832,55 +1367,13 @@ func HandleTenantRequestAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get database connection
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		http.Error(w, "Database connection failed", http.StatusInternalServerError)
-		return
-	}
-	if db == nil {
-		http.Error(w, "Database connection is nil", http.StatusInternalServerError)
-		return
-	}
-
-	// Start transaction
-	tx, err := db.Begin()
-	if err != nil {
-		fmt.Printf("Transaction start error: %v\n", err)
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
+	ctx := r.Context()
 
 	// Get notification details
-	var notification struct {
-		ID      int64
-		Message string
-		Status  string
-		FloorID int64
-		PID     int64
-		Sender  int64
-		Receiver int64
-	}
-
-	err = tx.QueryRow(`
-		SELECT n.id, n.message, n.status, n.fid, n.pid, n.sender, n.receiver
-		FROM notification n
-		WHERE n.id = ? AND n.receiver = ?
-	`, request.NotificationID, userID).Scan(
-		&notification.ID,
-		&notification.Message,
-		&notification.Status,
-		&notification.FloorID,
-		&notification.PID,
-		&notification.Sender,
-		&notification.Receiver,
-	)
-
+	notification, err := stores.Notifications.GetForReceiver(ctx, request.NotificationID, userID)
 	if err != nil {
-		fmt.Printf("Error getting notification: %v\n", err)
-		if err == sql.ErrNoRows {
+		logging.FromContext(r.Context()).Error("Error getting notification", "error", err)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "Notification not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Failed to get notification", http.StatusInternalServerError)
@@ -1888,85 +1381,112 @@ func HandleTenantRequestAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if notification.Status != "pending" {
+	invite := notification.Role != ""
+	var transfer *models.Transfer
+	if notification.FloorID == nil && !invite {
+		transfer, err = stores.Transfers.GetByNotification(ctx, notification.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			logging.FromContext(r.Context()).Error("Error getting transfer", "error", err)
+			http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if notification.Status != models.NotificationPending || (notification.FloorID == nil && !invite && transfer == nil) {
 		http.Error(w, "Notification is not pending", http.StatusBadRequest)
 		return
 	}
 
 	// Update notification status
-	newStatus := "rejected"
+	newStatus := models.NotificationRejected
 	if request.Accept {
-		newStatus = "accepted"
+		newStatus = models.NotificationAccepted
 	}
 
-	_, err = tx.Exec(`
-		UPDATE notification 
-		SET status = ?, updated_at = NOW(), updated_by = ?
-		WHERE id = ?
-	`, newStatus, userID, request.NotificationID)
-
-	if err != nil {
-		fmt.Printf("Error updating notification: %v\n", err)
-		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
-		return
-	}
-
-	// If accepted, update floor table
-	if request.Accept {
-		// Check if floor is already occupied
-		var isOccupied bool
-		err = tx.QueryRow(`
-			SELECT COUNT(*) > 0
-			FROM floor 
-			WHERE id = ? AND tenant IS NOT NULL
-		`, notification.FloorID).Scan(&isOccupied)
-
+	errUnitOccupied := errors.New("unit is already occupied")
+	errAlreadyMember := errors.New("already a member of the property")
+	errNotPending := errors.New("notification is not pending")
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		err := tx.Notifications.Answer(ctx, notification.ID, newStatus, userID)
+		if errors.Is(err, store.ErrConflict) {
+			return errNotPending
+		}
 		if err != nil {
-			fmt.Printf("Error checking floor status: %v\n", err)
-			http.Error(w, "Failed to check floor status", http.StatusInternalServerError)
-			return
+			return fmt.Errorf("updating notification: %v", err)
 		}
 
-		if isOccupied {
-			http.Error(w, "Floor is already occupied", http.StatusConflict)
-			return
+		if transfer != nil {
+			return answerTransfer(ctx, tx, transfer, request.Accept, userID)
 		}
 
-		// Update floor with tenant (receiver of the notification, not sender)
-		_, err = tx.Exec(`
-			UPDATE floor 
-			SET tenant = ?, updated_at = NOW(), updated_by = ?
-			WHERE id = ?
-		`, notification.Receiver, userID, notification.FloorID)
+		// An accepted invitation makes the receiver a member with the offered role
+		if request.Accept && invite {
+			err = tx.Properties.AddMember(ctx, notification.PropertyID, notification.Receiver, notification.Role, notification.CreatedBy)
+			if errors.Is(err, store.ErrConflict) {
+				return errAlreadyMember
+			}
+			if err != nil {
+				return fmt.Errorf("adding member: %v", err)
+			}
+			return nil
+		}
 
-		if err != nil {
-			fmt.Printf("Error updating floor: %v\n", err)
-			http.Error(w, "Failed to update floor", http.StatusInternalServerError)
-			return
+		// If accepted, update unit with tenant (receiver of the notification, not sender)
+		if request.Accept {
+			if notification.UnitID == nil {
+				return fmt.Errorf("tenant request %d has no unit", notification.ID)
+			}
+			err = tx.Units.AssignTenant(ctx, *notification.UnitID, notification.Receiver, userID)
+			if errors.Is(err, store.ErrConflict) {
+				return errUnitOccupied
+			}
+			if err != nil {
+				return fmt.Errorf("updating unit: %v", err)
+			}
+			unit, err := tx.Units.Get(ctx, notification.PropertyID, *notification.FloorID, *notification.UnitID)
+			if err != nil {
+				return fmt.Errorf("loading unit: %v", err)
+			}
+			return startTenancy(ctx, tx, unit, notification.Receiver, userID)
 		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNotPending):
+		http.Error(w, "Notification is not pending", http.StatusBadRequest)
+		return
+	case errors.Is(err, errUnitOccupied):
+		http.Error(w, "Unit is already occupied", http.StatusConflict)
+		return
+	case errors.Is(err, errAlreadyMember):
+		http.Error(w, "You are already a member of this property", http.StatusConflict)
+		return
+	case errors.Is(err, errTransferStale):
+		http.Error(w, "The sender no longer owns this property", http.StatusConflict)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Error answering tenant request", "error", err)
+		http.Error(w, "Failed to answer tenant request", http.StatusInternalServerError)
+		return
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		fmt.Printf("Error committing transaction: %v\n", err)
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
-		return
+	message := "Tenant request " + newStatus
+	if invite {
+		message = "Invitation " + newStatus
+	} else if transfer != nil {
+		message = "Ownership transfer " + newStatus
 	}
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Tenant request " + newStatus,
+		"message": message,
 	})
 }
 
 // GetUserTenantPropertiesHandler handles GET requests to get all properties where the user is a tenant
 func GetUserTenantPropertiesHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Get User Tenant Properties Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -1976,63 +1496,26 @@ func GetUserTenantPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "User not authenticated", nil})
-		return
-	}
-
-	fmt.Printf("Fetching properties where user ID: %d is a tenant\n", userID)
+	userID := auth.UserID(r.Context())
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Database connection error", nil})
-		return
-	}
+	logging.FromContext(r.Context()).Debug("Fetching tenant properties")
 
-	// Query to get all properties where the user is a tenant
-	query := `
-		SELECT DISTINCT p.id, p.name, p.address, p.created_at 
-		FROM property p
-		INNER JOIN floor f ON p.id = f.pid
-		WHERE f.tenant = ?
-		ORDER BY p.created_at DESC`
-	
-	fmt.Printf("Executing query: %s with userID: %d\n", query, userID)
-	
-	rows, err := db.Query(query, userID)
+	// Get all properties where the user is a tenant
+	tenanted, err := stores.Properties.ListTenanted(r.Context(), userID)
 	if err != nil {
-		fmt.Printf("Error querying properties: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error querying properties", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Error fetching properties", nil})
 		return
 	}
-	defer rows.Close()
 
 	var properties []Property
-	for rows.Next() {
-		var prop Property
-		if err := rows.Scan(&prop.ID, &prop.Name, &prop.Address, &prop.CreatedAt); err != nil {
-			fmt.Printf("Error scanning property row: %v\n", err)
-			continue
-		}
-		properties = append(properties, prop)
-		fmt.Printf("Found property: ID=%d, Name=%s\n", prop.ID, prop.Name)
+	for _, p := range tenanted {
+		properties = append(properties, toProperty(p))
+		logging.FromContext(r.Context()).Debug("Found property", "property_id", p.ID, "name", p.Name)
 	}
 
-	if err = rows.Err(); err != nil {
-		fmt.Printf("Error iterating property rows: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(UserPropertiesResponse{false, "Error processing properties", nil})
-		return
-	}
-
-	fmt.Printf("Found %d properties where user is a tenant\n", len(properties))
+	logging.FromContext(r.Context()).Debug("Found tenant properties", "count", len(properties))
 
 	response := UserPropertiesResponse{
 		Success: true,
@@ -2040,15 +1523,12 @@ func GetUserTenantPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 		Properties: properties,
 	}
 
-	// Log the response
-	responseJSON, _ := json.MarshalIndent(response, "", "  ")
-	fmt.Printf("Sending response: %s\n", string(responseJSON))
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// RemoveTenantHandler handles POST requests to remove a tenant from a floor
+// RemoveTenantHandler handles DELETE requests to remove the tenant from a
+// unit, or from the only unit of a floor
 func RemoveTenantHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -2056,8 +1536,7 @@ func RemoveTenantHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
+	userID := auth.UserID(r.Context())
 	vars := mux.Vars(r)
 	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
@@ -2070,91 +1549,43 @@ func RemoveTenantHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if userID == 0 {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(TenantRequestResponse{false, "User not authenticated"})
-		return
-	}
-
-	// Get database connection
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		http.Error(w, "Database connection failed", http.StatusInternalServerError)
-		return
-	}
-	if db == nil {
-		http.Error(w, "Database connection is nil", http.StatusInternalServerError)
-		return
-	}
-
-	// Start transaction
-	tx, err := db.Begin()
-	if err != nil {
-		fmt.Printf("Transaction start error: %v\n", err)
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
-
-	// Check if user is the manager of the property
-	var isManager bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE uid = ? AND pid = ?
-		)`, userID, propertyID).Scan(&isManager)
+	ctx := r.Context()
 
-	if err != nil {
-		fmt.Printf("Error checking property manager: %v\n", err)
-		http.Error(w, "Failed to check authorization", http.StatusInternalServerError)
+	unit, err := routeUnit(ctx, propertyID, floorID, vars["unit_id"])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Floor or unit not found", http.StatusNotFound)
 		return
 	}
-
-	if !isManager {
-		http.Error(w, "You are not authorized to manage this property", http.StatusForbidden)
+	if errors.Is(err, errSeveralUnits) {
+		http.Error(w, "This floor has several units, remove the tenant of one of them", http.StatusConflict)
 		return
 	}
-
-	// Check if there's a tenant in the floor
-	var hasTenant bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM floor 
-			WHERE id = ? AND pid = ? AND tenant IS NOT NULL
-		)`, floorID, propertyID).Scan(&hasTenant)
-
 	if err != nil {
-		fmt.Printf("Error checking tenant: %v\n", err)
-		http.Error(w, "Failed to check tenant", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("Error getting unit", "error", err)
+		http.Error(w, "Failed to remove tenant", http.StatusInternalServerError)
 		return
 	}
 
-	if !hasTenant {
-		http.Error(w, "No tenant found in this floor", http.StatusBadRequest)
+	// Update unit to remove tenant and end their tenancy
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if err := tx.Units.RemoveTenant(ctx, propertyID, floorID, unit.ID, userID); err != nil {
+			return err
+		}
+		if err := tx.Tenancies.End(ctx, unit.ID, today(), userID); err != nil {
+			return fmt.Errorf("ending tenancy: %v", err)
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "No tenant found in this unit", http.StatusBadRequest)
 		return
 	}
-
-	// Update floor to remove tenant
-	_, err = tx.Exec(`
-		UPDATE floor 
-		SET tenant = NULL, updated_at = NOW(), updated_by = ?
-		WHERE id = ? AND pid = ?
-	`, userID, floorID, propertyID)
-
 	if err != nil {
-		fmt.Printf("Error removing tenant: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error removing tenant", "error", err)
 		http.Error(w, "Failed to remove tenant", http.StatusInternalServerError)
 		return
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		fmt.Printf("Error committing transaction: %v\n", err)
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
-		return
-	}
-
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -2167,85 +1598,47 @@ type ManagerCheckResponse struct {
 	Success   bool   `json:"success"`
 	Message   string `json:"message"`
 	IsManager bool   `json:"is_manager"`
+	Role      string `json:"role,omitempty"`
 }
 
 // CheckUserManagerHandler handles GET requests to check if user is a manager of a property
 func CheckUserManagerHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Check User Manager Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-
 	// Set response header to JSON
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Method not allowed", false})
+		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Method not allowed", false, ""})
 		return
 	}
 
-	// Get user ID from session
-	userID := getUserIDFromSession(r)
-	if userID == 0 {
-		fmt.Println("No user ID found in session")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "User not authenticated", false})
-		return
-	}
-
-	// Extract property ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	var cleanParts []string
-	for _, part := range pathParts {
-		if part != "" {
-			cleanParts = append(cleanParts, part)
-		}
-	}
-
-	if len(cleanParts) != 3 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Invalid URL format", false})
-		return
-	}
+	userID := auth.UserID(r.Context())
 
-	propertyID, err := strconv.ParseInt(cleanParts[1], 10, 64)
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Invalid property ID", false})
+		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Invalid property ID", false, ""})
 		return
 	}
 
-	fmt.Printf("Checking if user ID: %d is manager of property ID: %d\n", userID, propertyID)
-
-	db, err := config.GetDBConnection()
-	if err != nil {
-		fmt.Printf("Database connection error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Database connection error", false})
-		return
-	}
+	logging.FromContext(r.Context()).Debug("Checking manager", "property_id", propertyID)
 
-	// Check if user is a manager of the property
-	var isManager bool
-	err = db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM takes_care_of 
-			WHERE uid = ? AND pid = ?
-		)`, userID, propertyID).Scan(&isManager)
-	
-	if err != nil {
-		fmt.Printf("Error checking manager status: %v\n", err)
+	// Any role on the property makes the user one of its managers
+	role, err := stores.Properties.Role(r.Context(), propertyID, userID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(r.Context()).Error("Error checking property role", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Error checking manager status", false})
+		json.NewEncoder(w).Encode(ManagerCheckResponse{false, "Error checking manager status", false, ""})
 		return
 	}
 
-	fmt.Printf("User %d is manager of property %d: %v\n", userID, propertyID, isManager)
+	logging.FromContext(r.Context()).Debug("Checked role", "property_id", propertyID, "role", role)
 
 	response := ManagerCheckResponse{
 		Success:   true,
 		Message:   "Manager check completed",
-		IsManager: isManager,
+		IsManager: role != "",
+		Role:      role,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"go-rent/models"
+	"net/http"
+	"testing"
+)
+
+// moveIn makes tenantID the tenant of the unit through a tenant request,
+// the way the app does it
+func moveIn(t *testing.T, ownerID, tenantID, propertyID, floorID, unitID int64) {
+	t.Helper()
+	ctx := context.Background()
+	tenant, err := stores.Users.Get(ctx, tenantID)
+	if err != nil {
+		t.Fatalf("loading tenant: %v", err)
+	}
+	status, resp := call(t, SendTenantRequestHandler, "POST", vars("id", propertyID, "floor_id", floorID, "unit_id", unitID), ownerID,
+		map[string]string{"phone_number": tenant.PhoneNumber})
+	expect(t, "sending tenant request", status, resp, http.StatusCreated)
+
+	notifications, err := stores.Notifications.ListForReceiver(ctx, tenantID)
+	if err != nil || len(notifications) == 0 {
+		t.Fatalf("listing notifications: %v, %d found", err, len(notifications))
+	}
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, tenantID,
+		map[string]interface{}{"notification_id": notifications[0].ID, "accept": true})
+	expect(t, "accepting tenant request", status, resp, http.StatusOK)
+}
+
+func TestCreatePayment(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	tenant := seedUser(t, s, "+880 1811-000001")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "1A", 8000)
+	route := vars("id", propertyID, "floor_id", floorID)
+
+	status, resp := call(t, CreatePaymentHandler, "POST", route, owner, map[string]int{"due_rent": 8000, "received_money": 8000})
+	expect(t, "payment without tenant", status, resp, http.StatusBadRequest)
+
+	moveIn(t, owner, tenant, propertyID, floorID, unitID)
+
+	status, resp = call(t, CreatePaymentHandler, "POST", route, owner,
+		map[string]int{"due_rent": 8000, "due_electricity_bill": 500, "received_money": 8500})
+	expect(t, "full payment", status, resp, http.StatusCreated)
+	payment, err := s.Payments.LatestForUnit(ctx, unitID)
+	if err != nil {
+		t.Fatalf("loading payment: %v", err)
+	}
+	if !payment.FullPayment || payment.TenantID != tenant || payment.CreatedBy != owner {
+		t.Errorf("full payment stored as %+v", payment)
+	}
+
+	status, resp = call(t, CreatePaymentHandler, "POST", route, owner, map[string]int{"due_rent": 8000, "received_money": 5000})
+	expect(t, "partial payment", status, resp, http.StatusCreated)
+	if payment, _ = s.Payments.LatestForUnit(ctx, unitID); payment.FullPayment {
+		t.Errorf("partial payment stored as full")
+	}
+}
+
+func TestCreatePaymentRoutes(t *testing.T) {
+	s := newTestStore(t)
+	owner := seedUser(t, s, "+880 1711-000002")
+	tenant := seedUser(t, s, "+880 1811-000002")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "2A", 6000)
+	moveIn(t, owner, tenant, propertyID, floorID, unitID)
+	body := map[string]int{"due_rent": 6000, "received_money": 6000}
+
+	status, resp := call(t, CreatePaymentHandler, "POST", vars("id", propertyID, "floor_id", 1), owner, body)
+	expect(t, "unknown floor", status, resp, http.StatusNotFound)
+	status, resp = call(t, CreatePaymentHandler, "POST", vars("id", propertyID, "floor_id", floorID, "unit_id", 1), owner, body)
+	expect(t, "unknown unit", status, resp, http.StatusNotFound)
+
+	second := models.Unit{FloorID: floorID, PropertyID: propertyID, Name: "2A-2", Rent: 4000}
+	if err := s.Units.Create(context.Background(), &second); err != nil {
+		t.Fatalf("adding unit: %v", err)
+	}
+	status, resp = call(t, CreatePaymentHandler, "POST", vars("id", propertyID, "floor_id", floorID), owner, body)
+	expect(t, "floor route with several units", status, resp, http.StatusConflict)
+	status, resp = call(t, CreatePaymentHandler, "POST", vars("id", propertyID, "floor_id", floorID, "unit_id", unitID), owner, body)
+	expect(t, "unit route", status, resp, http.StatusCreated)
+}
+
+func TestTenantRequest(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000003")
+	tenant := seedUser(t, s, "+880 1811-000003")
+	other := seedUser(t, s, "+880 1911-000003")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "3A", 7000)
+	route := vars("id", propertyID, "floor_id", floorID)
+
+	status, resp := call(t, SendTenantRequestHandler, "POST", route, owner, map[string]string{"phone_number": "+880 1511-999999"})
+	expect(t, "request to unknown phone", status, resp, http.StatusNotFound)
+	status, resp = call(t, SendTenantRequestHandler, "POST", route, owner, map[string]string{"phone_number": "+880 1811-000003"})
+	expect(t, "request", status, resp, http.StatusCreated)
+	status, resp = call(t, SendTenantRequestHandler, "POST", route, owner, map[string]string{"phone_number": "+880 1911-000003"})
+	expect(t, "second pending request", status, resp, http.StatusConflict)
+
+	notifications, _ := s.Notifications.ListForReceiver(ctx, tenant)
+	if len(notifications) != 1 || notifications[0].Status != models.NotificationPending {
+		t.Fatalf("tenant has notifications %+v", notifications)
+	}
+	answer := map[string]interface{}{"notification_id": notifications[0].ID, "accept": true}
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, other, answer)
+	expect(t, "answer by someone else", status, resp, http.StatusNotFound)
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, tenant, answer)
+	expect(t, "accept", status, resp, http.StatusOK)
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, tenant, answer)
+	expect(t, "accept twice", status, resp, http.StatusBadRequest)
+
+	unit, err := s.Units.Get(ctx, propertyID, floorID, unitID)
+	if err != nil || unit.Tenant == nil || *unit.Tenant != tenant {
+		t.Fatalf("unit after accepting is %+v, %v", unit, err)
+	}
+	tenancies, _ := s.Tenancies.ListByFloor(ctx, propertyID, floorID)
+	if len(tenancies) != 1 || tenancies[0].Status != models.TenancyActive || tenancies[0].Rent != 7000 {
+		t.Errorf("tenancies after accepting are %+v", tenancies)
+	}
+}
+
+func TestTenantRequestRejected(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000004")
+	tenant := seedUser(t, s, "+880 1811-000004")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "4A", 7000)
+
+	status, resp := call(t, SendTenantRequestHandler, "POST", vars("id", propertyID, "floor_id", floorID), owner,
+		map[string]string{"phone_number": "+880 1811-000004"})
+	expect(t, "request", status, resp, http.StatusCreated)
+	notifications, _ := s.Notifications.ListForReceiver(ctx, tenant)
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, tenant,
+		map[string]interface{}{"notification_id": notifications[0].ID, "accept": false})
+	expect(t, "reject", status, resp, http.StatusOK)
+
+	if unit, _ := s.Units.Get(ctx, propertyID, floorID, unitID); unit.Tenant != nil {
+		t.Errorf("rejected request assigned tenant %d", *unit.Tenant)
+	}
+	if pending, _ := s.Notifications.HasPendingForUnit(ctx, unitID); pending {
+		t.Errorf("rejected request is still pending")
+	}
+}
+
+func TestRemoveTenant(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000005")
+	tenant := seedUser(t, s, "+880 1811-000005")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "5A", 9000)
+	route := vars("id", propertyID, "floor_id", floorID)
+
+	status, resp := call(t, RemoveTenantHandler, "DELETE", route, owner, nil)
+	expect(t, "remove from empty unit", status, resp, http.StatusBadRequest)
+
+	moveIn(t, owner, tenant, propertyID, floorID, unitID)
+	status, resp = call(t, RemoveTenantHandler, "DELETE", route, owner, nil)
+	expect(t, "remove", status, resp, http.StatusOK)
+
+	if unit, _ := s.Units.Get(ctx, propertyID, floorID, unitID); unit.Tenant != nil {
+		t.Errorf("unit still has tenant %d", *unit.Tenant)
+	}
+	tenancies, _ := s.Tenancies.ListByFloor(ctx, propertyID, floorID)
+	if len(tenancies) != 1 || tenancies[0].Status != models.TenancyEnded || tenancies[0].EndDate == "" {
+		t.Errorf("tenancies after removing are %+v", tenancies)
+	}
+}
+
+func TestFloorHandlers(t *testing.T) {
+	s := newTestStore(t)
+	owner := seedUser(t, s, "+880 1711-000006")
+	propertyID := seedProperty(t, s, owner)
+
+	status, resp := call(t, AddFloorHandler, "POST", vars("id", propertyID), owner, map[string]interface{}{"name": "6A", "rent": 5000})
+	expect(t, "adding floor", status, resp, http.StatusCreated)
+	floorID := int64(resp["floor_id"].(float64))
+
+	status, resp = call(t, GetFloorsHandler, "GET", vars("id", propertyID), owner, nil)
+	expect(t, "listing floors", status, resp, http.StatusOK)
+	if floors := resp["floors"].([]interface{}); len(floors) != 1 {
+		t.Errorf("got %d floors, want 1", len(floors))
+	}
+	status, resp = call(t, GetFloorByIDHandler, "GET", vars("id", propertyID, "floor_id", floorID), owner, nil)
+	expect(t, "getting floor", status, resp, http.StatusOK)
+	if floor := resp["floor"].(map[string]interface{}); floor["rent"].(float64) != 5000 {
+		t.Errorf("floor is %v", floor)
+	}
+	status, resp = call(t, GetFloorByIDHandler, "GET", vars("id", propertyID, "floor_id", 1), owner, nil)
+	expect(t, "getting unknown floor", status, resp, http.StatusNotFound)
+
+	status, resp = call(t, GetPropertyByIDHandler, "GET", vars("id", propertyID), owner, nil)
+	expect(t, "getting property", status, resp, http.StatusOK)
+	if resp["role"] != "owner" || len(resp["floors"].([]interface{})) != 1 {
+		t.Errorf("property response is %v", resp)
+	}
+	status, resp = call(t, CheckUserManagerHandler, "GET", vars("id", propertyID), owner, nil)
+	expect(t, "checking manager", status, resp, http.StatusOK)
+	if resp["is_manager"] != true {
+		t.Errorf("owner is not a manager: %v", resp)
+	}
+}
+
+func TestDeleteNotification(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000007")
+	tenant := seedUser(t, s, "+880 1811-000007")
+	propertyID := seedProperty(t, s, owner)
+	floorID, _ := seedFloor(t, s, propertyID, "7A", 5000)
+
+	status, resp := call(t, SendTenantRequestHandler, "POST", vars("id", propertyID, "floor_id", floorID), owner,
+		map[string]string{"phone_number": "+880 1811-000007"})
+	expect(t, "request", status, resp, http.StatusCreated)
+	notifications, _ := s.Notifications.ListForReceiver(ctx, tenant)
+
+	status, resp = call(t, DeleteNotificationHandler, "DELETE", vars("id", notifications[0].ID), tenant, nil)
+	expect(t, "withdrawing by the receiver", status, resp, http.StatusForbidden)
+	status, resp = call(t, DeleteNotificationHandler, "DELETE", vars("id", notifications[0].ID), owner, nil)
+	expect(t, "withdrawing by the sender", status, resp, http.StatusOK)
+	if notifications, _ = s.Notifications.ListForReceiver(ctx, tenant); len(notifications) != 0 {
+		t.Errorf("withdrawn request is still listed: %+v", notifications)
+	}
+}
+
+func TestUpdateProperty(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	propertyID := seedProperty(t, s, owner)
+	route := vars("id", propertyID)
+
+	status, resp := call(t, UpdatePropertyHandler, "PUT", route, owner, map[string]string{"name": "Lake View"})
+	expect(t, "PUT without address", status, resp, http.StatusBadRequest)
+	status, resp = call(t, UpdatePropertyHandler, "PATCH", route, owner, map[string]string{"name": "Lake View"})
+	expect(t, "PATCH of the name", status, resp, http.StatusOK)
+	property, err := s.Properties.GetForUser(ctx, propertyID, owner)
+	if err != nil {
+		t.Fatalf("loading property: %v", err)
+	}
+	if property.Name != "Lake View" || property.Address != "Dhanmondi" {
+		t.Errorf("patched property stored as %+v", property)
+	}
+
+	if err := s.Properties.Delete(ctx, propertyID, owner); err != nil {
+		t.Fatalf("deleting property: %v", err)
+	}
+	status, resp = call(t, UpdatePropertyHandler, "PATCH", route, owner, map[string]string{"name": "Rose Villa"})
+	expect(t, "PATCH of a deleted property", status, resp, http.StatusNotFound)
+}
+
+func TestUpdateFloor(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+880 1711-000001")
+	tenant := seedUser(t, s, "+880 1811-000001")
+	other := seedUser(t, s, "+880 1811-000002")
+	propertyID := seedProperty(t, s, owner)
+	floorID, unitID := seedFloor(t, s, propertyID, "1A", 8000)
+	route := vars("id", propertyID, "floor_id", floorID)
+
+	rentOf := func() int {
+		t.Helper()
+		unit, err := s.Units.Get(ctx, propertyID, floorID, unitID)
+		if err != nil {
+			t.Fatalf("loading unit: %v", err)
+		}
+		return unit.Rent
+	}
+
+	status, resp := call(t, UpdateFloorHandler, "PUT", route, owner, map[string]interface{}{"name": "1B"})
+	expect(t, "renaming", status, resp, http.StatusOK)
+	if rent := rentOf(); rent != 8000 {
+		t.Errorf("rent after a rename: got %d, want 8000", rent)
+	}
+	status, resp = call(t, UpdateFloorHandler, "PUT", route, owner, map[string]interface{}{"name": "1B", "rent": 9000, "tenant": tenant})
+	expect(t, "moving a tenant in", status, resp, http.StatusOK)
+	if rent := rentOf(); rent != 9000 {
+		t.Errorf("rent after an update: got %d, want 9000", rent)
+	}
+	if unit, _ := s.Units.Get(ctx, propertyID, floorID, unitID); unit.Tenant == nil || *unit.Tenant != tenant {
+		t.Errorf("unit after moving in: %+v", unit)
+	}
+	status, resp = call(t, UpdateFloorHandler, "PUT", route, owner, map[string]interface{}{"name": "1B", "tenant": other})
+	expect(t, "replacing the tenant", status, resp, http.StatusConflict)
+
+	unit := models.Unit{PropertyID: propertyID, FloorID: floorID, Name: "1B-2", Rent: 4000, CreatedBy: owner}
+	if err := s.Units.Create(ctx, &unit); err != nil {
+		t.Fatalf("adding unit: %v", err)
+	}
+	status, resp = call(t, UpdateFloorHandler, "PUT", route, owner, map[string]interface{}{"name": "1C", "rent": 5000})
+	expect(t, "rent of a floor with several units", status, resp, http.StatusBadRequest)
+	status, resp = call(t, UpdateFloorHandler, "PUT", route, owner, map[string]interface{}{"name": "1C"})
+	expect(t, "renaming a floor with several units", status, resp, http.StatusOK)
+	if rent := rentOf(); rent != 9000 {
+		t.Errorf("rent after renaming a floor with several units: got %d, want 9000", rent)
+	}
+}
@@ -1,19 +1,16 @@
 package handlers
 
 import (
-	"bytes"
-	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"go-rent/config"
-	"go-rent/utils"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
 	"golang.org/x/crypto/bcrypt"
-	"io"
-	"math/big"
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
 type RegisterRequest struct {
@@ -31,31 +28,52 @@ type RegisterResponse struct {
 	UserID  int64  `json:"user_id,omitempty"`
 }
 
-func generateRandomID() (int64, error) {
-	// Generate a random number between 1000000 and 9999999
-	max := big.NewInt(8999999)
-	n, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return 0, err
-	}
-	// Add 1000000 to ensure 7-digit number
-	return n.Int64() + 1000000, nil
+// VerifyRegistrationRequest completes a registration. The password must be
+// the one it was made with, so whoever registers a number they don't own
+// can't have the owner's code activate an account with their password.
+type VerifyRegistrationRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+	Password    string `json:"password"`
+	DeviceName  string `json:"device_name"`
 }
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("\n=== New Registration Request ===")
-	fmt.Printf("Method: %s\n", r.Method)
-	fmt.Printf("URL: %s\n", r.URL)
-	fmt.Printf("Headers: %v\n", r.Header)
+type ResendCodeRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		fmt.Printf("Error reading request body: %v\n", err)
+var (
+	phoneRegex = regexp.MustCompile(`^\+880 \d{4}-\d{6}$`)
+	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	// NIDs are numeric and 10-17 digits
+	nidRegex = regexp.MustCompile(`^\d{10,17}$`)
+)
+
+const (
+	invalidEmailMessage = "Invalid email format"
+	invalidNIDMessage   = "Invalid NID format. Should be 10-17 digits"
+)
+
+// parsePhoneNumber checks the +880 XXXX-XXXXXX format used by the clients
+// and returns the number as stored, +880XXXXXXXXXX
+func parsePhoneNumber(s string) (string, bool) {
+	if !phoneRegex.MatchString(s) {
+		return "", false
 	}
-	fmt.Printf("Request Body: %s\n", string(body))
+	phoneNumber := strings.ReplaceAll(s, " ", "")
+	return strings.ReplaceAll(phoneNumber, "-", ""), true
+}
 
-	r.Body = io.NopCloser(bytes.NewBuffer(body)) // restore body for decoding
+// lookupPhoneNumber accepts a phone number in the client format or as
+// stored, the way the user list of GetUserPhonesHandler returns it
+func lookupPhoneNumber(s string) (string, bool) {
+	if phoneNumber, ok := parsePhoneNumber(s); ok {
+		return phoneNumber, true
+	}
+	return s, storedPhoneRegex.MatchString(s)
+}
 
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		json.NewEncoder(w).Encode(RegisterResponse{false, "Method not allowed", 0})
@@ -69,18 +87,14 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate phone number
-	phoneRegex := regexp.MustCompile(`^\+880 \d{4}-\d{6}$`)
-	if !phoneRegex.MatchString(req.PhoneNumber) {
+	// Validate and normalize phone number
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(RegisterResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0})
 		return
 	}
 
-	// Normalize phone number
-	phoneNumber := strings.ReplaceAll(req.PhoneNumber, " ", "")
-	phoneNumber = strings.ReplaceAll(phoneNumber, "-", "")
-
 	if req.Password == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(RegisterResponse{false, "Password is required", 0})
@@ -93,23 +107,17 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.GetDBConnection()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(RegisterResponse{false, "Database connection error", 0})
-		return
-	}
-	defer db.Close()
+	ctx := r.Context()
 
-	// Check if phone number exists
-	var exists int
-	err = db.QueryRow("SELECT COUNT(*) FROM user WHERE phone_number = ?", phoneNumber).Scan(&exists)
-	if err != nil {
+	// Check if phone number exists. A registration that was never verified
+	// doesn't count; registering again replaces it.
+	existing, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(RegisterResponse{false, "Database error", 0})
 		return
 	}
-	if exists > 0 {
+	if existing != nil && existing.Verified {
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(RegisterResponse{false, "Phone number already registered", 0})
 		return
@@ -124,84 +132,205 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Handle nullable fields
-	var email interface{}
-	if req.Email == "" {
-		email = nil
-	} else {
+	if req.Email != "" {
 		// Validate email format
-		emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 		if !emailRegex.MatchString(req.Email) {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(RegisterResponse{false, "Invalid email format", 0})
+			json.NewEncoder(w).Encode(RegisterResponse{false, invalidEmailMessage, 0})
 			return
 		}
-		email = req.Email
 	}
 
-	var nid interface{}
-	if req.NID == "" {
-		nid = nil
-	} else {
-		// Validate NID format (assuming it should be numeric and 10-17 digits)
-		nidRegex := regexp.MustCompile(`^\d{10,17}$`)
+	if req.NID != "" {
+		// Validate NID format
 		if !nidRegex.MatchString(req.NID) {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(RegisterResponse{false, "Invalid NID format. Should be 10-17 digits", 0})
+			json.NewEncoder(w).Encode(RegisterResponse{false, invalidNIDMessage, 0})
 			return
 		}
-		nid = req.NID
 	}
 
-	var manager interface{}
-	if req.Manager == nil {
-		manager = nil
-	} else {
-		manager = *req.Manager
+	// Insert into DB, the store assigns the ID. The account stays inactive
+	// until the code sent to the phone number is verified; if the SMS
+	// can't be sent, nothing is saved.
+	user := models.User{
+		Name:        req.Name,
+		PhoneNumber: phoneNumber,
+		Email:       req.Email,
+		NID:         req.NID,
+		Password:    string(hash),
+		Manager:     req.Manager,
+	}
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		if existing != nil {
+			user.ID = existing.ID
+			if err := tx.Users.UpdateUnverified(ctx, &user); err != nil {
+				return fmt.Errorf("updating user: %w", err)
+			}
+		} else if err := tx.Users.Create(ctx, &user); err != nil {
+			return fmt.Errorf("inserting user: %w", err)
+		}
+		return sendOTP(ctx, tx, phoneNumber, models.OTPRegister, user.ID)
+	})
+	if errors.Is(err, store.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "Phone number already registered", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error registering user", "error", err)
+		status, message := otpError(err)
+		if status == http.StatusInternalServerError {
+			message = "Error registering user"
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(RegisterResponse{false, message, 0})
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("User registered", "registered_user_id", user.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(RegisterResponse{
+		Success: true,
+		Message: "Verification code sent",
+		UserID:  user.ID,
+	})
+}
+
+// VerifyRegistrationHandler activates an account with the code sent to its
+// phone number and logs it in
+func VerifyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req VerifyRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid request body", 0, "", nil})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0, "", nil})
+		return
+	}
+	if req.Code == "" || req.Password == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Code and password are required", 0, "", nil})
+		return
 	}
 
-	// Generate random ID
-	randomID, err := utils.GenerateRandomID()
+	ctx := r.Context()
+	user, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LoginResponse{false, "No registration found for this phone number", 0, "", nil})
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error generating random ID: %v\n", err)
+		logging.FromContext(r.Context()).Error("Database error", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(RegisterResponse{false, "Error generating user ID", 0})
-		return
-	}
-
-	// Insert into DB with random ID
-	result, err := db.Exec(
-		`INSERT INTO user (id, name, phone_number, email, NID, password, manager, created_at, created_by, updated_at, updated_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		randomID,
-		req.Name,
-		phoneNumber,
-		email,
-		nid,
-		string(hash),
-		manager,
-		time.Now().Format("2006-01-02"),
-		randomID,
-		time.Now().Format("2006-01-02"),
-		randomID,
-	)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Database error", 0, "", nil})
+		return
+	}
+	if user.Verified {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Phone number already verified", 0, "", nil})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Invalid phone number or password", 0, "", nil})
+		return
+	}
+
+	otp, err := checkOTP(ctx, phoneNumber, models.OTPRegister, req.Code)
+	if err == nil && otp.UserID != user.ID {
+		// The code was sent for a registration that has since been replaced
+		err = errOTPInvalid
+	}
 	if err != nil {
-		fmt.Printf("Error inserting user: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(RegisterResponse{false, fmt.Sprintf("Error inserting user: %v", err), 0})
+		logging.FromContext(r.Context()).Error("Error verifying code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(LoginResponse{false, message, 0, "", nil})
 		return
 	}
 
-	lastID, err := result.LastInsertId()
+	err = stores.Users.Verify(ctx, user.ID)
+	if errors.Is(err, store.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Phone number already verified", 0, "", nil})
+		return
+	}
 	if err != nil {
-		fmt.Println("Inserted but failed to get last ID:", err)
-	} else {
-		fmt.Printf("User inserted with ID: %d\n", lastID)
+		logging.FromContext(r.Context()).Error("Error verifying user", "registered_user_id", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LoginResponse{false, "Database error", 0, "", nil})
+		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(RegisterResponse{
+	tokens, err := startSession(w, r, user.ID, req.DeviceName)
+	if err != nil {
+		// The account is active, the client can still log in
+		logging.FromContext(r.Context()).Error("Error starting session", "error", err)
+	}
+	json.NewEncoder(w).Encode(LoginResponse{
 		Success: true,
-		Message: "Registration successful",
-		UserID:  randomID,
+		Message: "Phone number verified",
+		UserID:  user.ID,
+		Name:    user.Name,
+		Tokens:  tokens,
+	})
+}
+
+// ResendRegistrationCodeHandler sends a new code for a registration that
+// hasn't been verified yet
+func ResendRegistrationCodeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ResendCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "Invalid request body", 0})
+		return
+	}
+	phoneNumber, ok := parsePhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", 0})
+		return
+	}
+
+	ctx := r.Context()
+	user, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "No registration found for this phone number", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Database error", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "Database error", 0})
+		return
+	}
+	if user.Verified {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(RegisterResponse{false, "Phone number already verified", 0})
+		return
+	}
+
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		return sendOTP(ctx, tx, phoneNumber, models.OTPRegister, user.ID)
 	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error sending code", "error", err)
+		status, message := otpError(err)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(RegisterResponse{false, message, 0})
+		return
+	}
+	json.NewEncoder(w).Encode(RegisterResponse{true, "Verification code sent", user.ID})
 }
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"go-rent/utils"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// A login starts a session that lives in the session table. The client gets
+// a short-lived access token, which names the session, and a refresh token,
+// which POST /token/refresh trades for a new pair. Every refresh replaces
+// the refresh token; presenting the one it replaced means the token was
+// copied, and the whole session is revoked.
+
+const (
+	sessionCookieName = "sessiontoken"
+	refreshCookieName = "refreshtoken"
+	// refreshCookiePath keeps browsers from sending the refresh token
+	// anywhere but the refresh endpoint
+	refreshCookiePath = "/token"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*Tokens
+}
+
+type LogoutResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Session is a login as listed by GET /me/sessions
+type Session struct {
+	ID         int64  `json:"id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	// Current marks the session the request was made with
+	Current bool `json:"current"`
+}
+
+type SessionsResponse struct {
+	Success  bool      `json:"success"`
+	Message  string    `json:"message"`
+	Sessions []Session `json:"sessions"`
+}
+
+// clientIP returns the address the request came from. X-Forwarded-For is
+// ignored since it can be set by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// truncate cuts s to at most n runes
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// startSession creates a session for the user and hands its tokens to the
+// client, as cookies and as the returned Tokens
+func startSession(w http.ResponseWriter, r *http.Request, userID int64, deviceName string) (*Tokens, error) {
+	logging.SetUserID(r.Context(), userID)
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		UserID:      userID,
+		RefreshHash: refreshHash,
+		DeviceName:  truncate(strings.TrimSpace(deviceName), 100),
+		UserAgent:   truncate(r.UserAgent(), 255),
+		IPAddress:   clientIP(r),
+	}
+	if err := stores.Sessions.Create(r.Context(), session, time.Now().Add(utils.RefreshTTL())); err != nil {
+		return nil, fmt.Errorf("error creating session: %v", err)
+	}
+	return issueTokens(w, session, refreshToken)
+}
+
+// issueTokens signs an access token for the session and sets the session,
+// refresh and CSRF cookies
+func issueTokens(w http.ResponseWriter, session *models.Session, refreshToken string) (*Tokens, error) {
+	accessToken, err := utils.GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %v", err)
+	}
+
+	// Rotate the CSRF token along with the session
+	if err := setCSRFCookie(w); err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    accessToken,
+		Expires:  time.Now().Add(utils.TokenTTL()),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		Expires:  time.Now().Add(utils.RefreshTTL()),
+		Path:     refreshCookiePath,
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(utils.TokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// clearSessionCookies removes the cookies set by issueTokens
+func clearSessionCookies(w http.ResponseWriter) {
+	for _, c := range []struct{ name, path string }{
+		{sessionCookieName, "/"},
+		{refreshCookieName, refreshCookiePath},
+		{csrfCookieName, "/"},
+	} {
+		http.SetCookie(w, &http.Cookie{Name: c.name, Value: "", Path: c.path, MaxAge: -1})
+	}
+}
+
+// RefreshTokenHandler trades a refresh token, from the JSON body or the
+// refreshtoken cookie, for a new access and refresh token
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Invalid request body", nil})
+		return
+	}
+	if req.RefreshToken == "" {
+		if cookie, err := r.Cookie(refreshCookieName); err == nil {
+			req.RefreshToken = cookie.Value
+		}
+	}
+	if req.RefreshToken == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Refresh token is required", nil})
+		return
+	}
+
+	ctx := r.Context()
+	hash := utils.HashRefreshToken(req.RefreshToken)
+	session, err := stores.Sessions.GetActiveByRefreshHash(ctx, hash)
+	if errors.Is(err, store.ErrNotFound) {
+		clearSessionCookies(w)
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Session expired", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading session", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Database error", nil})
+		return
+	}
+
+	if session.RefreshHash != hash {
+		// The token was already exchanged once, so someone else holds a
+		// copy of it. Neither copy may be used again.
+		logging.FromContext(r.Context()).Warn("Refresh token reused, revoking the session", "session_id", session.ID)
+		if err := stores.Sessions.Revoke(ctx, session.ID, session.UserID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			logging.FromContext(r.Context()).Error("Error revoking session", "session_id", session.ID, "error", err)
+		}
+		clearSessionCookies(w)
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Session expired", nil})
+		return
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error generating refresh token", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Error generating authentication token", nil})
+		return
+	}
+	err = stores.Sessions.Rotate(ctx, session.ID, hash, refreshHash, time.Now().Add(utils.RefreshTTL()))
+	if errors.Is(err, store.ErrConflict) {
+		// A concurrent refresh with the same token won
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Session expired", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error rotating refresh token", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Database error", nil})
+		return
+	}
+
+	tokens, err := issueTokens(w, session, refreshToken)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error issuing tokens", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TokenResponse{false, "Error generating authentication token", nil})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TokenResponse{true, "Token refreshed", tokens})
+}
+
+// LogoutHandler ends the session of the access token the request was made with
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, _ := auth.FromContext(r.Context())
+	err := stores.Sessions.Revoke(r.Context(), principal.SessionID, principal.UserID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logging.FromContext(r.Context()).Error("Error revoking session", "session_id", principal.SessionID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LogoutResponse{false, "Database error"})
+		return
+	}
+
+	clearSessionCookies(w)
+	json.NewEncoder(w).Encode(LogoutResponse{true, "Logged out"})
+}
+
+// LogoutAllHandler ends every session of the user, logging out all devices
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := auth.UserID(r.Context())
+	if err := stores.Sessions.RevokeAll(r.Context(), userID); err != nil {
+		logging.FromContext(r.Context()).Error("Error revoking sessions", "user", userID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LogoutResponse{false, "Database error"})
+		return
+	}
+
+	clearSessionCookies(w)
+	json.NewEncoder(w).Encode(LogoutResponse{true, "Logged out of all devices"})
+}
+
+// ListSessionsHandler lists the active sessions of the user, so they can see
+// every device their account is logged in on
+func ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, _ := auth.FromContext(r.Context())
+	sessions, err := stores.Sessions.ListActive(r.Context(), principal.UserID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error listing sessions", "user", principal.UserID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SessionsResponse{false, "Database error", nil})
+		return
+	}
+
+	result := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, Session{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.ID == principal.SessionID,
+		})
+	}
+	json.NewEncoder(w).Encode(SessionsResponse{true, "Sessions retrieved successfully", result})
+}
+
+// RevokeSessionHandler ends one session of the user, logging that device out
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sessionID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LogoutResponse{false, "Invalid session ID"})
+		return
+	}
+
+	principal, _ := auth.FromContext(r.Context())
+	err = stores.Sessions.Revoke(r.Context(), sessionID, principal.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(LogoutResponse{false, "Session not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error revoking session", "session_id", sessionID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LogoutResponse{false, "Database error"})
+		return
+	}
+
+	if sessionID == principal.SessionID {
+		clearSessionCookies(w)
+	}
+	json.NewEncoder(w).Encode(LogoutResponse{true, "Session revoked"})
+}
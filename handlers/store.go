@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"go-rent/models"
+	"go-rent/store"
+)
+
+// stores is the repository layer used by every handler
+var stores *store.Store
+
+// SetStore sets the store used by the handlers. It must be called before
+// the router starts serving requests.
+func SetStore(s *store.Store) {
+	stores = s
+}
+
+// toProperty converts a stored property into its JSON representation
+func toProperty(p models.Property) Property {
+	return Property{
+		ID:        p.ID,
+		Name:      p.Name,
+		Address:   p.Address,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+		Role:      p.Role,
+	}
+}
+
+// toFloor converts a stored floor into its JSON representation, with the
+// units of the floor taken from units
+func toFloor(f models.Floor, units []models.Unit) Floor {
+	floor := Floor{
+		ID:         f.ID,
+		Name:       f.Name,
+		CreatedAt:  f.CreatedAt,
+		ArchivedAt: f.ArchivedAt,
+		Units:      []Unit{},
+	}
+	for _, u := range units {
+		if u.FloorID == f.ID {
+			floor.Units = append(floor.Units, toUnit(u))
+		}
+	}
+	if len(floor.Units) == 1 {
+		u := floor.Units[0]
+		floor.Rent = u.Rent
+		floor.Tenant = u.Tenant
+		floor.Status = u.Status
+		floor.NotificationID = u.NotificationID
+	}
+	return floor
+}
+
+// toUnit converts a stored unit into its JSON representation
+func toUnit(u models.Unit) Unit {
+	unit := Unit{
+		ID:        u.ID,
+		FloorID:   u.FloorID,
+		Name:      u.Name,
+		Rent:      u.Rent,
+		CreatedAt: u.CreatedAt,
+		Tenant:    u.Tenant,
+	}
+	if u.PendingNotificationID != nil {
+		unit.Status = "pending"
+		unit.NotificationID = u.PendingNotificationID
+	}
+	return unit
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// A tenancy records a tenant's stay in a unit. It is opened when the
+// tenant moves in, at the rent of the unit on that day, and ended by
+// RemoveTenantHandler, so the tenancies of a floor are its occupancy
+// history.
+
+// Tenancy is the JSON representation of a tenancy
+type Tenancy struct {
+	ID         int64  `json:"id"`
+	UnitID     int64  `json:"unit_id"`
+	UnitName   string `json:"unit_name"`
+	TenantID   int64  `json:"tenant_id"`
+	TenantName string `json:"tenant_name"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date,omitempty"`
+	Rent       int    `json:"rent"`
+	Status     string `json:"status"`
+}
+
+type TenanciesResponse struct {
+	Success   bool      `json:"success"`
+	Message   string    `json:"message"`
+	Tenancies []Tenancy `json:"tenancies"`
+}
+
+// today returns the current date in Bangladesh as YYYY-MM-DD
+func today() string {
+	return time.Now().In(time.FixedZone("BDT", 6*60*60)).Format("2006-01-02")
+}
+
+// startTenancy opens the tenancy of a tenant who just moved into the unit
+func startTenancy(ctx context.Context, tx *store.Store, unit *models.Unit, tenantID, userID int64) error {
+	err := tx.Tenancies.Create(ctx, &models.Tenancy{
+		UnitID:     unit.ID,
+		FloorID:    unit.FloorID,
+		PropertyID: unit.PropertyID,
+		TenantID:   tenantID,
+		StartDate:  today(),
+		Rent:       unit.Rent,
+		Status:     models.TenancyActive,
+		CreatedBy:  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("creating tenancy: %v", err)
+	}
+	return nil
+}
+
+// GetFloorTenanciesHandler returns the tenancies of every unit of the
+// floor, latest first
+func GetFloorTenanciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TenanciesResponse{false, "Invalid property ID", nil})
+		return
+	}
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TenanciesResponse{false, "Invalid floor ID", nil})
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := stores.Floors.Get(ctx, propertyID, floorID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(TenanciesResponse{false, "Floor not found", nil})
+			return
+		}
+		logging.FromContext(ctx).Error("Error querying floor", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TenanciesResponse{false, "Error fetching tenancies", nil})
+		return
+	}
+
+	stored, err := stores.Tenancies.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing tenancies", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TenanciesResponse{false, "Error fetching tenancies", nil})
+		return
+	}
+	tenancies := []Tenancy{}
+	for _, t := range stored {
+		tenancies = append(tenancies, Tenancy{t.ID, t.UnitID, t.UnitName, t.TenantID, t.TenantName, t.StartDate, t.EndDate, t.Rent, t.Status})
+	}
+
+	json.NewEncoder(w).Encode(TenanciesResponse{true, "Tenancies retrieved successfully", tenancies})
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// An owner hands a property to another user with TransferPropertyHandler.
+// The recipient gets a notification and answers it through
+// HandleTenantRequestAction. Accepting makes the recipient an owner and
+// takes the sender off the property; floors, payments and notifications
+// stay with the property. Every step is written to the audit log.
+
+// Transfer is the JSON representation of an ownership transfer
+type Transfer struct {
+	ID             int64  `json:"id"`
+	FromUserID     int64  `json:"from_user_id"`
+	FromName       string `json:"from_name"`
+	ToUserID       int64  `json:"to_user_id"`
+	ToName         string `json:"to_name"`
+	NotificationID int64  `json:"notification_id"`
+	Status         string `json:"status"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+type TransferPropertyRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type TransferResponse struct {
+	Success  bool      `json:"success"`
+	Message  string    `json:"message"`
+	Transfer *Transfer `json:"transfer,omitempty"`
+}
+
+type TransfersResponse struct {
+	Success   bool       `json:"success"`
+	Message   string     `json:"message"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+// errTransferStale is returned when the sender of a transfer no longer owns
+// the property by the time the recipient accepts
+var errTransferStale = errors.New("sender no longer owns the property")
+
+// toTransfer looks up the names of both users, leaving a name empty if the
+// lookup fails
+func toTransfer(ctx context.Context, t *models.Transfer) *Transfer {
+	out := &Transfer{
+		ID:             t.ID,
+		FromUserID:     t.FromUserID,
+		ToUserID:       t.ToUserID,
+		NotificationID: t.NotificationID,
+		Status:         t.Status,
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+	}
+	if u, err := stores.Users.Get(ctx, t.FromUserID); err == nil {
+		out.FromName = u.Name
+	}
+	if u, err := stores.Users.Get(ctx, t.ToUserID); err == nil {
+		out.ToName = u.Name
+	}
+	return out
+}
+
+// TransferPropertyHandler offers the caller's ownership of the property to
+// the user with the phone number
+func TransferPropertyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Invalid property ID", nil})
+		return
+	}
+
+	var req TransferPropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Invalid request body", nil})
+		return
+	}
+	phoneNumber, ok := lookupPhoneNumber(req.PhoneNumber)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Invalid phone number format. Use format: +880 XXXX-XXXXXX", nil})
+		return
+	}
+
+	ctx := r.Context()
+	userID := auth.UserID(ctx)
+
+	recipient, err := stores.Users.GetByPhone(ctx, phoneNumber)
+	if err == nil && !recipient.Verified {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TransferResponse{false, "User not found with this phone number", nil})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error finding user", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Error finding user", nil})
+		return
+	}
+	if recipient.ID == userID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TransferResponse{false, "You already own this property", nil})
+		return
+	}
+
+	pending, err := stores.Transfers.HasPending(ctx, propertyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error checking pending transfers", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Error checking pending transfers", nil})
+		return
+	}
+	if pending {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(TransferResponse{false, "This property already has a pending transfer", nil})
+		return
+	}
+
+	property, err := stores.Properties.GetForUser(ctx, propertyID, userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error getting property", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Error getting property details", nil})
+		return
+	}
+
+	transfer := &models.Transfer{
+		PropertyID: propertyID,
+		FromUserID: userID,
+		ToUserID:   recipient.ID,
+		Status:     models.TransferPending,
+		CreatedBy:  userID,
+	}
+	err = stores.WithTx(ctx, func(tx *store.Store) error {
+		notification := &models.Notification{
+			Message:    fmt.Sprintf("Ownership transfer of %s", property.Name),
+			Sender:     &userID,
+			Receiver:   recipient.ID,
+			PropertyID: propertyID,
+			Status:     models.NotificationPending,
+			CreatedBy:  userID,
+		}
+		if err := tx.Notifications.Create(ctx, notification); err != nil {
+			return fmt.Errorf("creating notification: %v", err)
+		}
+		transfer.NotificationID = notification.ID
+		if err := tx.Transfers.Create(ctx, transfer); err != nil {
+			return fmt.Errorf("creating transfer: %v", err)
+		}
+		return tx.Audit.Record(ctx, &models.AuditEntry{
+			PropertyID: propertyID,
+			ActorID:    userID,
+			Action:     models.AuditTransferRequested,
+			Details:    fmt.Sprintf("transfer %d to user %d", transfer.ID, recipient.ID),
+		})
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error creating transfer", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TransferResponse{false, "Error creating transfer", nil})
+		return
+	}
+	logging.FromContext(ctx).Info("Ownership transfer requested", "property_id", propertyID, "transfer_id", transfer.ID, "recipient_id", recipient.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(TransferResponse{true, "Transfer sent, waiting for the recipient to accept", toTransfer(ctx, transfer)})
+}
+
+// ListTransfersHandler returns the ownership transfers of the property,
+// newest first
+func ListTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	propertyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TransfersResponse{false, "Invalid property ID", nil})
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := stores.Transfers.ListForProperty(ctx, propertyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing transfers", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TransfersResponse{false, "Error fetching transfers", nil})
+		return
+	}
+	transfers := []Transfer{}
+	for i := range stored {
+		transfers = append(transfers, *toTransfer(ctx, &stored[i]))
+	}
+	json.NewEncoder(w).Encode(TransfersResponse{true, "Transfers retrieved successfully", transfers})
+}
+
+// answerTransfer records the recipient's answer to a transfer. On
+// acceptance the recipient becomes an owner, keeping their membership if
+// they had one, and the sender leaves the property.
+func answerTransfer(ctx context.Context, tx *store.Store, t *models.Transfer, accept bool, userID int64) error {
+	status := models.TransferRejected
+	if accept {
+		status = models.TransferAccepted
+	}
+	if err := tx.Transfers.SetStatus(ctx, t.ID, status, userID); err != nil {
+		return fmt.Errorf("updating transfer: %v", err)
+	}
+	if !accept {
+		return tx.Audit.Record(ctx, &models.AuditEntry{
+			PropertyID: t.PropertyID,
+			ActorID:    userID,
+			Action:     models.AuditTransferRejected,
+			Details:    fmt.Sprintf("transfer %d from user %d", t.ID, t.FromUserID),
+		})
+	}
+
+	role, err := tx.Properties.Role(ctx, t.PropertyID, t.FromUserID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && role != models.RoleOwner) {
+		return errTransferStale
+	}
+	if err != nil {
+		return fmt.Errorf("checking sender role: %v", err)
+	}
+
+	err = tx.Properties.SetRole(ctx, t.PropertyID, t.ToUserID, models.RoleOwner, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		err = tx.Properties.AddMember(ctx, t.PropertyID, t.ToUserID, models.RoleOwner, t.FromUserID)
+	}
+	if err != nil {
+		return fmt.Errorf("making recipient owner: %v", err)
+	}
+	if err := tx.Properties.RemoveMember(ctx, t.PropertyID, t.FromUserID); err != nil {
+		return fmt.Errorf("removing sender: %v", err)
+	}
+	return tx.Audit.Record(ctx, &models.AuditEntry{
+		PropertyID: t.PropertyID,
+		ActorID:    userID,
+		Action:     models.AuditTransferAccepted,
+		Details:    fmt.Sprintf("transfer %d from user %d to user %d", t.ID, t.FromUserID, t.ToUserID),
+	})
+}
+
+// cancelTransfer cancels the transfer whose notification the sender
+// withdrew, if the notification belonged to one
+func cancelTransfer(ctx context.Context, tx *store.Store, notificationID, userID int64) error {
+	t, err := tx.Transfers.GetByNotification(ctx, notificationID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading transfer: %v", err)
+	}
+	if err := tx.Transfers.SetStatus(ctx, t.ID, models.TransferCancelled, userID); err != nil {
+		return fmt.Errorf("cancelling transfer: %v", err)
+	}
+	return tx.Audit.Record(ctx, &models.AuditEntry{
+		PropertyID: t.PropertyID,
+		ActorID:    userID,
+		Action:     models.AuditTransferCancelled,
+		Details:    fmt.Sprintf("transfer %d to user %d", t.ID, t.ToUserID),
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"testing"
+)
+
+func TestTransferAccepted(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	owner := seedUser(t, s, "+8801711000001")
+	recipient := seedUser(t, s, "+8801711000002")
+	propertyID := seedProperty(t, s, owner)
+	route := vars("id", propertyID)
+
+	status, resp := call(t, TransferPropertyHandler, "POST", route, owner, TransferPropertyRequest{"+880 1711-000002"})
+	expect(t, "offering the property", status, resp, http.StatusCreated)
+	status, resp = call(t, TransferPropertyHandler, "POST", route, owner, TransferPropertyRequest{"+880 1711-000002"})
+	expect(t, "offering it twice", status, resp, http.StatusConflict)
+
+	transfers, err := s.Transfers.ListForProperty(ctx, propertyID)
+	if err != nil || len(transfers) != 1 {
+		t.Fatalf("listing transfers: %v, %d found", err, len(transfers))
+	}
+	if transfers[0].Status != models.TransferPending {
+		t.Errorf("new transfer has status %q, want %q", transfers[0].Status, models.TransferPending)
+	}
+
+	status, resp = call(t, HandleTenantRequestAction, "POST", nil, recipient,
+		map[string]interface{}{"notification_id": transfers[0].NotificationID, "accept": true})
+	expect(t, "accepting the transfer", status, resp, http.StatusOK)
+
+	transfers, _ = s.Transfers.ListForProperty(ctx, propertyID)
+	if transfers[0].Status != models.TransferAccepted || transfers[0].UpdatedBy != recipient {
+		t.Errorf("accepted transfer stored as %+v", transfers[0])
+	}
+	if role, err := s.Properties.Role(ctx, propertyID, recipient); err != nil || role != models.RoleOwner {
+		t.Errorf("recipient after accepting: role %q, %v", role, err)
+	}
+	if _, err := s.Properties.Role(ctx, propertyID, owner); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("former owner is still a member: %v", err)
+	}
+
+	// The new owner can hand it on in turn
+	status, resp = call(t, TransferPropertyHandler, "POST", route, recipient, TransferPropertyRequest{"+880 1711-000001"})
+	expect(t, "offering it back", status, resp, http.StatusCreated)
+}
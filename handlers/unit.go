@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-rent/auth"
+	"go-rent/logging"
+	"go-rent/models"
+	"go-rent/store"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// A floor holds one or more units, the flats, rooms or shops that are let
+// out. Rent, tenants, tenant requests and payments belong to a unit. Every
+// floor gets a unit of the same name when it is created, so the tenant and
+// payment routes of a floor act on that unit until more are added; the
+// /unit/{unit_id} routes address a unit directly.
+
+// Unit is the JSON representation of a unit
+type Unit struct {
+	ID             int64  `json:"id"`
+	FloorID        int64  `json:"floor_id"`
+	Name           string `json:"name"`
+	Rent           int    `json:"rent"`
+	CreatedAt      string `json:"created_at"`
+	Tenant         *int64 `json:"tenant,omitempty"`
+	Status         string `json:"status,omitempty"`
+	NotificationID *int64 `json:"notification_id,omitempty"`
+}
+
+type UnitRequest struct {
+	Name string `json:"name"`
+	Rent int    `json:"rent"`
+}
+
+type UnitResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	UnitID  int64  `json:"unit_id,omitempty"`
+}
+
+// errSeveralUnits is returned by routeUnit for a floor route on a floor with
+// more than one unit
+var errSeveralUnits = errors.New("floor has several units")
+
+// routeUnit returns the unit a tenant or payment route acts on: unitVar if
+// the route has a {unit_id}, otherwise the only unit of the floor
+func routeUnit(ctx context.Context, propertyID, floorID int64, unitVar string) (*models.Unit, error) {
+	if unitVar != "" {
+		unitID, err := strconv.ParseInt(unitVar, 10, 64)
+		if err != nil {
+			return nil, store.ErrNotFound
+		}
+		return stores.Units.Get(ctx, propertyID, floorID, unitID)
+	}
+	units, err := stores.Units.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		return nil, err
+	}
+	switch len(units) {
+	case 0:
+		return nil, store.ErrNotFound
+	case 1:
+		return &units[0], nil
+	default:
+		return nil, errSeveralUnits
+	}
+}
+
+// validUnit checks the name and rent of a unit, returning the message to
+// send back if they are not valid
+func validUnit(req *UnitRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		return "Unit name is required"
+	case len(req.Name) > 100:
+		return "Unit name can't be longer than 100 characters"
+	case req.Rent < 0:
+		return "Rent can't be negative"
+	}
+	return ""
+}
+
+// unitNameTaken reports whether another unit of the floor has the name,
+// ignoring case
+func unitNameTaken(ctx context.Context, propertyID, floorID, unitID int64, name string) (bool, error) {
+	units, err := stores.Units.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range units {
+		if u.ID != unitID && strings.EqualFold(u.Name, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddUnitHandler adds a unit to the floor
+func AddUnitHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid property ID", 0})
+		return
+	}
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid floor ID", 0})
+		return
+	}
+
+	var req UnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid request body", 0})
+		return
+	}
+	if msg := validUnit(&req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, msg, 0})
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := stores.Floors.Get(ctx, propertyID, floorID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(UnitResponse{false, "Floor not found", 0})
+			return
+		}
+		logging.FromContext(ctx).Error("Error querying floor", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error adding unit", 0})
+		return
+	}
+	taken, err := unitNameTaken(ctx, propertyID, floorID, 0, req.Name)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing units", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error adding unit", 0})
+		return
+	}
+	if taken {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(UnitResponse{false, "A unit with this name already exists on the floor", 0})
+		return
+	}
+
+	unit := models.Unit{
+		FloorID:    floorID,
+		PropertyID: propertyID,
+		Name:       req.Name,
+		Rent:       req.Rent,
+		CreatedBy:  auth.UserID(ctx),
+	}
+	if err := stores.Units.Create(ctx, &unit); err != nil {
+		logging.FromContext(ctx).Error("Error inserting unit", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error adding unit", 0})
+		return
+	}
+	logging.FromContext(ctx).Info("Unit added", "unit_id", unit.ID, "floor_id", floorID, "property_id", propertyID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(UnitResponse{true, "Unit added successfully", unit.ID})
+}
+
+// UpdateUnitHandler changes the name and rent of a unit. Tenants are added
+// through tenant requests and removed with RemoveTenantHandler.
+func UpdateUnitHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	propertyID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid property ID", 0})
+		return
+	}
+	floorID, err := strconv.ParseInt(vars["floor_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid floor ID", 0})
+		return
+	}
+	unitID, err := strconv.ParseInt(vars["unit_id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid unit ID", 0})
+		return
+	}
+
+	var req UnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Invalid request body", 0})
+		return
+	}
+	if msg := validUnit(&req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UnitResponse{false, msg, 0})
+		return
+	}
+
+	ctx := r.Context()
+	unit, err := stores.Units.Get(ctx, propertyID, floorID, unitID)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Unit not found", 0})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Error querying unit", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error updating unit", 0})
+		return
+	}
+	taken, err := unitNameTaken(ctx, propertyID, floorID, unitID, req.Name)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error listing units", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error updating unit", 0})
+		return
+	}
+	if taken {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(UnitResponse{false, "A unit with this name already exists on the floor", 0})
+		return
+	}
+
+	unit.Name = req.Name
+	unit.Rent = req.Rent
+	unit.UpdatedBy = auth.UserID(ctx)
+	if err := stores.Units.Update(ctx, unit); err != nil {
+		logging.FromContext(ctx).Error("Error updating unit", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(UnitResponse{false, "Error updating unit", 0})
+		return
+	}
+	logging.FromContext(ctx).Info("Unit updated", "unit_id", unitID)
+
+	json.NewEncoder(w).Encode(UnitResponse{true, "Unit updated successfully", unitID})
+}
@@ -0,0 +1,118 @@
+// Package logging sets up the server's log/slog logger and the HTTP
+// middleware that logs requests without leaking secrets.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces the value of every redacted field
+const Redacted = "[REDACTED]"
+
+// New returns a logger that writes to w at the given level ("debug",
+// "info", "warn" or "error") as "text" or "json". Attributes whose key r
+// redacts are logged as Redacted.
+func New(w io.Writer, level, format string, r *Redactor) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{
+		Level: l,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Value.Kind() != slog.KindGroup && r.Redacts(a.Key) {
+				a.Value = slog.StringValue(Redacted)
+			}
+			return a
+		},
+	}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// Redactor decides which fields are too sensitive to log
+type Redactor struct {
+	names map[string]bool
+}
+
+// NewRedactor redacts the given names. A field matches if its name, or a
+// part of it separated by "_", "-" or ".", is one of them, ignoring case:
+// "password" also covers "new_password" and "token" covers "X-CSRF-Token".
+func NewRedactor(names []string) *Redactor {
+	r := &Redactor{names: make(map[string]bool)}
+	for _, name := range names {
+		r.names[strings.ToLower(name)] = true
+	}
+	return r
+}
+
+// Redacts reports whether the value of the named field must not be logged
+func (r *Redactor) Redacts(name string) bool {
+	name = strings.ToLower(name)
+	if r.names[name] {
+		return true
+	}
+	parts := strings.FieldsFunc(name, func(c rune) bool {
+		return c == '_' || c == '-' || c == '.'
+	})
+	for _, part := range parts {
+		if r.names[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns the header with redacted values replaced
+func (r *Redactor) Header(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if r.Redacts(name) {
+			out[name] = Redacted
+		} else {
+			out[name] = strings.Join(values, ", ")
+		}
+	}
+	return out
+}
+
+// JSON decodes a JSON body and redacts its fields at any depth. ok is false
+// if body is not JSON.
+func (r *Redactor) JSON(body []byte) (v interface{}, ok bool) {
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, false
+	}
+	return r.value(v), true
+}
+
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if r.Redacts(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.value(value)
+			}
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = r.value(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxBodyLog is the longest request or response body that is logged
+const maxBodyLog = 64 << 10
+
+// Middleware logs every request and its response at debug level, with
+// redacted headers and JSON bodies, through the request's logger (see
+// AccessLog). Nothing is read or buffered unless debug logging is enabled.
+func Middleware(r *Redactor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			logger := FromContext(req.Context())
+			if !logger.Enabled(req.Context(), slog.LevelDebug) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			var body []byte
+			if req.Body != nil {
+				// Read a prefix and put it back in front of the rest
+				body, _ = io.ReadAll(io.LimitReader(req.Body, maxBodyLog+1))
+				req.Body = readCloser{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+			}
+			logger.LogAttrs(req.Context(), slog.LevelDebug, "request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Any("header", r.Header(req.Header)),
+				r.bodyAttr(body),
+			)
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK, body: new(bytes.Buffer)}
+			next.ServeHTTP(rec, req)
+
+			logger.LogAttrs(req.Context(), slog.LevelDebug, "response",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", rec.status),
+				r.bodyAttr(rec.body.Bytes()),
+			)
+		})
+	}
+}
+
+// bodyAttr logs a redacted JSON body, or just the size of anything else
+func (r *Redactor) bodyAttr(body []byte) slog.Attr {
+	if len(body) == 0 {
+		return slog.Attr{}
+	}
+	if len(body) > maxBodyLog {
+		return slog.String("body", "[longer than 64 KiB]")
+	}
+	if v, ok := r.JSON(body); ok {
+		return slog.Any("body", v)
+	}
+	return slog.Int("body_bytes", len(body))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder keeps the status and size of the response written to w
+// and, if body is set, the start of the body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+	body        *bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.body != nil {
+		if room := maxBodyLog + 1 - w.body.Len(); room > 0 {
+			if len(b) < room {
+				room = len(b)
+			}
+			w.body.Write(b[:room])
+		}
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
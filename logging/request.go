@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader carries the ID that ties together the log lines of one
+// request. A valid incoming ID, e.g. from a proxy, is kept.
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+type contextKey struct{}
+
+// requestLog is what a request's context knows about its logging
+type requestLog struct {
+	logger *slog.Logger
+	id     string
+	userID int64
+}
+
+func fromContext(ctx context.Context) *requestLog {
+	l, _ := ctx.Value(contextKey{}).(*requestLog)
+	return l
+}
+
+// FromContext returns the logger of the request that ctx belongs to, which
+// adds its request ID and user ID to every line. Outside a request it
+// returns slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if l := fromContext(ctx); l != nil {
+		return l.logger
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID of the request that ctx belongs to, "" if none
+func RequestID(ctx context.Context) string {
+	if l := fromContext(ctx); l != nil {
+		return l.id
+	}
+	return ""
+}
+
+// SetUserID records who made the request once it is authenticated. The
+// access log and every later line logged through FromContext include it.
+func SetUserID(ctx context.Context, userID int64) {
+	if l := fromContext(ctx); l != nil && l.userID == 0 {
+		l.userID = userID
+		l.logger = l.logger.With("user_id", userID)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// AccessLog gives every request an ID, echoed in the X-Request-ID response
+// header, and a logger reachable through FromContext. When the request is
+// done it logs one line with the route template, status, latency and
+// response size. It belongs in front of every other middleware.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDRegex.MatchString(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			l := &requestLog{logger: logger.With("request_id", id), id: id}
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, l))
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			level := slog.LevelInfo
+			if rec.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			// l.logger has user_id once the request is authenticated
+			l.logger.LogAttrs(r.Context(), level, "access",
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", rec.status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int64("bytes", rec.bytes),
+			)
+		})
+	}
+}
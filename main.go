@@ -1,89 +1,207 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"go-rent/config"
 	"go-rent/handlers"
+	"go-rent/logging"
+	"go-rent/policy"
 	"go-rent/scheduler"
+	"go-rent/sms"
+	"go-rent/store"
+	"go-rent/utils"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 	"github.com/gorilla/mux"
 )
 
+// maxClockWait is how long the server waits at startup for a clock that is
+// behind the last issued ID before it gives up
+const maxClockWait = 10 * time.Second
+
 func main() {
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// go-rent migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
+	// go-rent unlock <phone number|IP address>
+	if len(os.Args) > 1 && os.Args[1] == "unlock" {
+		runUnlock(cfg, os.Args[2:])
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Everything logged through log or log/slog from here on goes through
+	// this logger and its redaction
+	redactor := logging.NewRedactor(cfg.Log.Redact)
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format, redactor)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	slog.SetDefault(logger)
+
 	// Initialize database connection
-	err := config.InitDB()
+	err = config.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	fmt.Println("Successfully connected to the database!")
+	slog.Info("Connected to the database")
+
+	// Handlers talk to MySQL through the store package
+	db, err := config.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to get database connection: %v", err)
+	}
+	stores := store.NewMySQL(db)
+	ids, err := utils.NewSnowflake(int64(cfg.Server.NodeID))
+	if err != nil {
+		log.Fatalf("Failed to create ID generator: %v", err)
+	}
+	// IDs are only unique while the clock is past the last one handed out
+	lastID, err := stores.IDs.Max(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to find the last issued ID: %v", err)
+	}
+	if err := ids.Resume(lastID, maxClockWait); err != nil {
+		log.Fatalf("Failed to start ID generator: %v", err)
+	}
+	utils.SetIDGenerator(ids)
+	handlers.SetStore(stores)
+	utils.InitJWT([]byte(cfg.Auth.JWTSecret), cfg.Auth.TokenTTL, cfg.Auth.RefreshTTL)
+	sender, err := sms.New(cfg.SMS.Sender, cfg.SMS.File)
+	if err != nil {
+		log.Fatalf("Failed to create SMS sender: %v", err)
+	}
+	handlers.SetSMSSender(sender)
+	handlers.SetOTPLimits(cfg.Auth.OTPTTL, cfg.Auth.OTPMaxAttempts)
+	handlers.SetLoginLimits(cfg.Auth.LoginMaxFailures, cfg.Auth.LoginLockout)
 
 	// Start scheduler
 	go scheduler.StartScheduler()
 
 	// ✅ Use gorilla/mux router, not net/http ServeMux
 	router := mux.NewRouter()
+	router.Use(logging.AccessLog(logger))
+	router.Use(logging.Middleware(redactor))
+	// Middleware doesn't run for requests that match no route
+	router.NotFoundHandler = logging.AccessLog(logger)(http.NotFoundHandler())
+	router.Use(handlers.CSRF)
 
-	// Register routes properly using gorilla/mux
+	// Register routes properly using gorilla/mux. Each handler is wrapped
+	// with the access it needs, see handlers/auth.go; /login, /register*,
+	// /token/refresh and /password/* are public.
 	router.HandleFunc("/login", handlers.LoginHandler).Methods("POST")
 	router.HandleFunc("/register", handlers.RegisterHandler).Methods("POST")
+	router.HandleFunc("/register/verify", handlers.VerifyRegistrationHandler).Methods("POST")
+	router.HandleFunc("/register/resend", handlers.ResendRegistrationCodeHandler).Methods("POST")
+	router.HandleFunc("/token/refresh", handlers.RefreshTokenHandler).Methods("POST")
+	router.HandleFunc("/password/forgot", handlers.ForgotPasswordHandler).Methods("POST")
+	router.HandleFunc("/password/reset", handlers.ResetPasswordHandler).Methods("POST")
+	router.HandleFunc("/logout", handlers.Authenticated(handlers.LogoutHandler)).Methods("POST")
+	router.HandleFunc("/logout/all", handlers.Authenticated(handlers.LogoutAllHandler)).Methods("POST")
+
+	// Profile routes
+	router.HandleFunc("/me", handlers.Authenticated(handlers.GetProfileHandler)).Methods("GET")
+	router.HandleFunc("/me", handlers.Authenticated(handlers.UpdateProfileHandler)).Methods("PATCH")
+	router.HandleFunc("/me/password", handlers.Authenticated(handlers.ChangePasswordHandler)).Methods("POST")
+	router.HandleFunc("/me/phone", handlers.Authenticated(handlers.RequestPhoneChangeHandler)).Methods("POST")
+	router.HandleFunc("/me/phone/verify", handlers.Authenticated(handlers.VerifyPhoneChangeHandler)).Methods("POST")
+
+	// Session routes
+	router.HandleFunc("/me/sessions", handlers.Authenticated(handlers.ListSessionsHandler)).Methods("GET")
+	router.HandleFunc("/me/sessions/{id:[0-9]+}", handlers.Authenticated(handlers.RevokeSessionHandler)).Methods("DELETE")
 
 	// Property routes
-	router.HandleFunc("/properties", handlers.GetUserPropertiesHandler).Methods("GET")
-	router.HandleFunc("/properties/tenant", handlers.GetUserTenantPropertiesHandler).Methods("GET")
-	router.HandleFunc("/property", handlers.AddPropertyHandler).Methods("POST")
-	router.HandleFunc("/property/{id:[0-9]+}", handlers.GetPropertyByIDHandler).Methods("GET")
-	router.HandleFunc("/property/{id:[0-9]+}/manager", handlers.CheckUserManagerHandler).Methods("GET")
+	router.HandleFunc("/properties", handlers.Authenticated(handlers.GetUserPropertiesHandler)).Methods("GET")
+	router.HandleFunc("/properties/tenant", handlers.Authenticated(handlers.GetUserTenantPropertiesHandler)).Methods("GET")
+	router.HandleFunc("/property", handlers.Authenticated(handlers.AddPropertyHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}", handlers.Authenticated(handlers.GetPropertyByIDHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}", handlers.PropertyAction(policy.EditProperty, handlers.UpdatePropertyHandler)).Methods("PUT", "PATCH")
+	router.HandleFunc("/property/{id:[0-9]+}", handlers.PropertyAction(policy.DeleteProperty, handlers.DeletePropertyHandler)).Methods("DELETE")
+	router.HandleFunc("/property/{id:[0-9]+}/manager", handlers.Authenticated(handlers.CheckUserManagerHandler)).Methods("GET")
+
+	// Member routes
+	router.HandleFunc("/property/{id:[0-9]+}/members", handlers.PropertyAction(policy.ViewProperty, handlers.ListMembersHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/members", handlers.PropertyAction(policy.ManageMembers, handlers.InviteMemberHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/members/{user_id:[0-9]+}", handlers.PropertyAction(policy.ManageMembers, handlers.RemoveMemberHandler)).Methods("DELETE")
+
+	// Ownership transfer routes
+	router.HandleFunc("/property/{id:[0-9]+}/transfer", handlers.PropertyAction(policy.TransferProperty, handlers.TransferPropertyHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/transfers", handlers.PropertyAction(policy.ManageMembers, handlers.ListTransfersHandler)).Methods("GET")
 
 	// Floor routes
-	router.HandleFunc("/property/{id:[0-9]+}/floor", handlers.AddFloorHandler).Methods("POST")
-	router.HandleFunc("/property/{id:[0-9]+}/floor", handlers.GetFloorsHandler).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/floor", handlers.PropertyAction(policy.ManageFloors, handlers.AddFloorHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor", handlers.PropertyAction(policy.ViewProperty, handlers.GetFloorsHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/bulk", handlers.PropertyAction(policy.ManageFloors, handlers.BulkAddFloorsHandler)).Methods("POST")
 
 	// Floor details and update routes
-	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handlers.GetFloorByIDHandler(w, r)
-		} else if r.Method == http.MethodPut {
-			handlers.UpdateFloorHandler(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}).Methods("GET", "PUT")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}", handlers.PropertyAction(policy.ViewProperty, handlers.GetFloorByIDHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}", handlers.PropertyAction(policy.ManageFloors, handlers.UpdateFloorHandler)).Methods("PUT")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}", handlers.PropertyAction(policy.ManageFloors, handlers.DeleteFloorHandler)).Methods("DELETE")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/restore", handlers.PropertyAction(policy.ManageFloors, handlers.RestoreFloorHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/tenancies", handlers.PropertyAction(policy.ViewProperty, handlers.GetFloorTenanciesHandler)).Methods("GET")
+
+	// Lease routes, the tenant reads their own leases through /tenant/leases
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/tenancies/{tenancy_id:[0-9]+}/leases", handlers.PropertyAction(policy.ManageTenants, handlers.CreateLeaseHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/leases", handlers.PropertyAction(policy.ViewProperty, handlers.ListLeasesHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/leases/{lease_id:[0-9]+}", handlers.PropertyAction(policy.ViewProperty, handlers.GetLeaseHandler)).Methods("GET")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/leases/{lease_id:[0-9]+}", handlers.PropertyAction(policy.ManageTenants, handlers.UpdateLeaseHandler)).Methods("PUT")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/leases/{lease_id:[0-9]+}", handlers.PropertyAction(policy.ManageTenants, handlers.DeleteLeaseHandler)).Methods("DELETE")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/tenant/leases", handlers.TenantOfFloor(handlers.ListTenantLeasesHandler)).Methods("GET")
 
 	// Tenant request route
-	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/request", handlers.SendTenantRequestHandler).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/request", handlers.PropertyAction(policy.ManageTenants, handlers.SendTenantRequestHandler)).Methods("POST")
 
 	// Payment route
-	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/payment", handlers.CreatePaymentHandler).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/payment", handlers.PropertyAction(policy.RecordPayments, handlers.CreatePaymentHandler)).Methods("POST")
+
+	// Unit routes, the tenant and payment routes of a floor act on its only unit
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/unit", handlers.PropertyAction(policy.ManageFloors, handlers.AddUnitHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/unit/{unit_id:[0-9]+}", handlers.PropertyAction(policy.ManageFloors, handlers.UpdateUnitHandler)).Methods("PUT")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/unit/{unit_id:[0-9]+}/request", handlers.PropertyAction(policy.ManageTenants, handlers.SendTenantRequestHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/unit/{unit_id:[0-9]+}/payment", handlers.PropertyAction(policy.RecordPayments, handlers.CreatePaymentHandler)).Methods("POST")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/unit/{unit_id:[0-9]+}/tenant", handlers.PropertyAction(policy.ManageTenants, handlers.RemoveTenantHandler)).Methods("DELETE")
 
 	// User phones route
-	router.HandleFunc("/users/phones", handlers.GetUserPhonesHandler).Methods("GET")
-	router.HandleFunc("/users/phones/{phone}", handlers.GetUserIDByPhoneHandler).Methods("GET")
+	router.HandleFunc("/users/phones", handlers.Authenticated(handlers.GetUserPhonesHandler)).Methods("GET")
+	router.HandleFunc("/users/phones/{phone}", handlers.Authenticated(handlers.GetUserIDByPhoneHandler)).Methods("GET")
 
-	router.HandleFunc("/notifications", handlers.GetUserNotificationsHandler).Methods("GET")
-	router.HandleFunc("/notifications/delete/{id}", handlers.DeleteNotificationHandler).Methods("DELETE")
-	router.HandleFunc("/notifications/action", handlers.HandleTenantRequestAction).Methods("POST")
+	router.HandleFunc("/notifications", handlers.Authenticated(handlers.GetUserNotificationsHandler)).Methods("GET")
+	router.HandleFunc("/notifications/delete/{id}", handlers.Authenticated(handlers.DeleteNotificationHandler)).Methods("DELETE")
+	router.HandleFunc("/notifications/action", handlers.Authenticated(handlers.HandleTenantRequestAction)).Methods("POST")
 
 	// Add this route to support DELETE /property/{id}/floor/{floor_id}/tenant
-	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/tenant", handlers.RemoveTenantHandler).Methods("DELETE")
+	router.HandleFunc("/property/{id:[0-9]+}/floor/{floor_id:[0-9]+}/tenant", handlers.PropertyAction(policy.ManageTenants, handlers.RemoveTenantHandler)).Methods("DELETE")
 
 	router.Walk(func(route *mux.Route, r *mux.Router, ancestors []*mux.Route) error {
 		path, _ := route.GetPathTemplate()
 		methods, _ := route.GetMethods()
-		fmt.Printf("Registered route: %s %v\n", path, methods)
+		slog.Debug("Registered route", "path", path, "methods", methods)
 		return nil
 	})
 	
 	// Create server with timeouts
 	server := &http.Server{
-		Addr:         ":8080",
+		Addr:         cfg.Server.Addr,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
-	fmt.Println("Server starting on http://0.0.0.0:8080")
+	slog.Info("Server starting", "addr", cfg.Server.Addr)
 	log.Fatal(server.ListenAndServe())
 }
\ No newline at end of file
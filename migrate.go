@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"go-rent/config"
+	"go-rent/migrations"
+	"log"
+	"os"
+)
+
+const migrateUsage = `usage: go-rent migrate <command>
+
+commands:
+  up      apply all pending migrations
+  down    revert the most recently applied migration
+  status  list migrations and whether they are applied`
+
+// runMigrate implements the "migrate" subcommand
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) != 1 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := config.InitDB(cfg.Database); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	db, err := config.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(db)
+		for _, m := range ran {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(ran) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		m, err := migrations.Down(db)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if m == nil {
+			fmt.Println("No migrations to revert")
+		} else {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := migrations.List(db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied to the database
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load returns every embedded migration ordered by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := files.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureTable creates the table that records applied versions
+func ensureTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INT          NOT NULL,
+			name       VARCHAR(255) NOT NULL,
+			applied_at DATETIME     NOT NULL,
+			PRIMARY KEY (version)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// applied returns the applied versions and when they were applied
+func applied(db *sql.DB) (map[int]time.Time, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+// MySQL commits DDL implicitly, so a failing migration can be left half
+// applied; its version is only recorded once all statements succeeded.
+func Up(db *sql.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		if err := execScript(db, m.Up); err != nil {
+			return ran, fmt.Errorf("migration %d_%s failed: %v", m.Version, m.Name, err)
+		}
+		_, err := db.Exec(
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC(),
+		)
+		if err != nil {
+			return ran, fmt.Errorf("error recording migration %d_%s: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down reverts the most recently applied migration. It returns nil when
+// nothing is applied.
+func Down(db *sql.DB) (*Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if err := execScript(db, m.Down); err != nil {
+			return nil, fmt.Errorf("reverting migration %d_%s failed: %v", m.Version, m.Name, err)
+		}
+		if _, err := db.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+			return nil, fmt.Errorf("error recording revert of %d_%s: %v", m.Version, m.Name, err)
+		}
+		return &m, nil
+	}
+	return nil, nil
+}
+
+// List returns every embedded migration together with its applied time
+func List(db *sql.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		s := Status{Migration: m}
+		if appliedAt, ok := done[m.Version]; ok {
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// execScript runs each statement of a migration file in turn. The MySQL
+// driver does not accept several statements in one Exec unless
+// multiStatements is enabled on the DSN, which we don't want for handlers.
+// All statements share one connection so that session settings such as
+// FOREIGN_KEY_CHECKS apply to the rest of the script.
+func execScript(db *sql.DB, script string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%v\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line and drops
+// "--" comment lines. Migrations must not put a semicolon at the end of a
+// line inside a string literal.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, stmt)
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
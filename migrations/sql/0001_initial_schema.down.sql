@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS notification;
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS floor;
+DROP TABLE IF EXISTS takes_care_of;
+DROP TABLE IF EXISTS property;
+DROP TABLE IF EXISTS user;
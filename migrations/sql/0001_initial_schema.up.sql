@@ -0,0 +1,99 @@
+-- Baseline schema. IF NOT EXISTS lets this run against databases that were
+-- created by hand before migrations existed; it then only records the version.
+-- The misspelled payment columns are kept as-is because the code uses them.
+
+CREATE TABLE IF NOT EXISTS user (
+    id           BIGINT       NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    phone_number VARCHAR(20)  NOT NULL,
+    email        VARCHAR(255) NULL,
+    NID          VARCHAR(17)  NULL,
+    password     VARCHAR(255) NOT NULL,
+    manager      TINYINT(1)   NULL,
+    created_at   DATETIME     NOT NULL,
+    created_by   BIGINT       NOT NULL,
+    updated_at   DATETIME     NOT NULL,
+    updated_by   BIGINT       NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_user_phone_number (phone_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS property (
+    id         BIGINT       NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    address    VARCHAR(500) NOT NULL DEFAULT '',
+    created_at DATETIME     NOT NULL,
+    created_by BIGINT       NOT NULL,
+    updated_at DATETIME     NOT NULL,
+    updated_by BIGINT       NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS takes_care_of (
+    id         BIGINT   NOT NULL,
+    uid        BIGINT   NOT NULL,
+    pid        BIGINT   NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by BIGINT   NOT NULL,
+    updated_at DATETIME NOT NULL,
+    updated_by BIGINT   NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_takes_care_of_pid_uid (pid, uid),
+    KEY idx_takes_care_of_uid (uid),
+    CONSTRAINT fk_takes_care_of_user FOREIGN KEY (uid) REFERENCES user (id),
+    CONSTRAINT fk_takes_care_of_property FOREIGN KEY (pid) REFERENCES property (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS floor (
+    id         BIGINT       NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    rent       INT          NOT NULL DEFAULT 0,
+    tenant     BIGINT       NULL,
+    pid        BIGINT       NOT NULL,
+    created_at DATETIME     NOT NULL,
+    created_by BIGINT       NOT NULL,
+    updated_at DATETIME     NOT NULL,
+    updated_by BIGINT       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_floor_pid (pid),
+    KEY idx_floor_tenant (tenant),
+    CONSTRAINT fk_floor_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_floor_tenant FOREIGN KEY (tenant) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payment (
+    id                   BIGINT     NOT NULL,
+    due_rent             INT        NOT NULL DEFAULT 0,
+    due_electrictiy_bill INT        NOT NULL DEFAULT 0,
+    recieved_money       INT        NOT NULL DEFAULT 0,
+    full_payment         TINYINT(1) NOT NULL DEFAULT 0,
+    fid                  BIGINT     NOT NULL,
+    uid                  BIGINT     NOT NULL,
+    created_at           DATETIME   NOT NULL,
+    created_by           BIGINT     NOT NULL,
+    updated_at           DATETIME   NOT NULL,
+    updated_by           BIGINT     NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_payment_fid_created_at (fid, created_at),
+    CONSTRAINT fk_payment_floor FOREIGN KEY (fid) REFERENCES floor (id),
+    CONSTRAINT fk_payment_user FOREIGN KEY (uid) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS notification (
+    id         BIGINT      NOT NULL,
+    message    TEXT        NOT NULL,
+    sender     BIGINT      NULL,
+    receiver   BIGINT      NOT NULL,
+    pid        BIGINT      NOT NULL,
+    fid        BIGINT      NULL,
+    status     VARCHAR(20) NULL,
+    created_at DATETIME    NOT NULL,
+    created_by BIGINT      NOT NULL,
+    updated_at DATETIME    NOT NULL,
+    updated_by BIGINT      NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_notification_receiver (receiver, created_at),
+    KEY idx_notification_fid_status (fid, status),
+    CONSTRAINT fk_notification_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_notification_floor FOREIGN KEY (fid) REFERENCES floor (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
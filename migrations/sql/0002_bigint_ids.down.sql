@@ -0,0 +1,2 @@
+-- Nothing to revert: narrowing the ID columns back to INT would truncate
+-- every ID issued since 0002 was applied.
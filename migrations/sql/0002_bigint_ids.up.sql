@@ -0,0 +1,48 @@
+-- IDs are now generated by utils.Snowflake and no longer fit in INT.
+-- Tables created by 0001 already use BIGINT and are unchanged; this widens
+-- the ID columns of databases created by hand before migrations existed.
+-- Existing 7-digit IDs keep their values: new IDs are always larger.
+
+SET FOREIGN_KEY_CHECKS = 0;
+
+ALTER TABLE user
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+ALTER TABLE property
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+ALTER TABLE takes_care_of
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY uid        BIGINT NOT NULL,
+    MODIFY pid        BIGINT NOT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+ALTER TABLE floor
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY tenant     BIGINT NULL,
+    MODIFY pid        BIGINT NOT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+ALTER TABLE payment
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY fid        BIGINT NOT NULL,
+    MODIFY uid        BIGINT NOT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+ALTER TABLE notification
+    MODIFY id         BIGINT NOT NULL,
+    MODIFY sender     BIGINT NULL,
+    MODIFY receiver   BIGINT NOT NULL,
+    MODIFY pid        BIGINT NOT NULL,
+    MODIFY fid        BIGINT NULL,
+    MODIFY created_by BIGINT NOT NULL,
+    MODIFY updated_by BIGINT NOT NULL;
+
+SET FOREIGN_KEY_CHECKS = 1;
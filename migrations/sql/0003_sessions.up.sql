@@ -0,0 +1,20 @@
+-- One row per login. Access tokens carry the session ID and are only
+-- accepted while the session is active; the refresh token is stored as a
+-- SHA-256 hash and replaced on every refresh. The previous hash is kept to
+-- detect a refresh token being used twice.
+
+CREATE TABLE IF NOT EXISTS session (
+    id                    BIGINT   NOT NULL,
+    uid                   BIGINT   NOT NULL,
+    refresh_hash          CHAR(64) NOT NULL,
+    previous_refresh_hash CHAR(64) NULL,
+    expires_at            DATETIME NOT NULL,
+    revoked_at            DATETIME NULL,
+    created_at            DATETIME NOT NULL,
+    updated_at            DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_session_refresh_hash (refresh_hash),
+    KEY idx_session_previous_refresh_hash (previous_refresh_hash),
+    KEY idx_session_uid (uid),
+    CONSTRAINT fk_session_user FOREIGN KEY (uid) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
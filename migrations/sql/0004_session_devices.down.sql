@@ -0,0 +1,5 @@
+ALTER TABLE session
+    DROP COLUMN last_seen_at,
+    DROP COLUMN ip_address,
+    DROP COLUMN user_agent,
+    DROP COLUMN device_name;
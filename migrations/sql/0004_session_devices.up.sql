@@ -0,0 +1,8 @@
+-- Where each session was started and last used from, for the session list
+-- under /me/sessions. last_seen_at is refreshed at most once a minute.
+
+ALTER TABLE session
+    ADD COLUMN device_name  VARCHAR(100) NULL AFTER previous_refresh_hash,
+    ADD COLUMN user_agent   VARCHAR(255) NULL AFTER device_name,
+    ADD COLUMN ip_address   VARCHAR(45)  NULL AFTER user_agent,
+    ADD COLUMN last_seen_at DATETIME     NULL AFTER ip_address;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS otp;
+ALTER TABLE user DROP COLUMN verified_at;
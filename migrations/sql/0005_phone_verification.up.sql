@@ -0,0 +1,22 @@
+-- Accounts are only usable once their phone number is verified by a
+-- one-time code. Existing accounts count as verified since they were
+-- created.
+
+ALTER TABLE user ADD COLUMN verified_at DATETIME NULL AFTER manager;
+UPDATE user SET verified_at = created_at;
+
+-- One row per code sent. The code is stored as an HMAC; attempts counts
+-- wrong guesses and consumed_at is set once the code has been used.
+CREATE TABLE IF NOT EXISTS otp (
+    id           BIGINT      NOT NULL,
+    purpose      VARCHAR(20) NOT NULL,
+    phone_number VARCHAR(20) NOT NULL,
+    uid          BIGINT      NULL,
+    code_hash    CHAR(64)    NOT NULL,
+    attempts     INT         NOT NULL DEFAULT 0,
+    expires_at   DATETIME    NOT NULL,
+    consumed_at  DATETIME    NULL,
+    created_at   DATETIME    NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_otp_phone_purpose (phone_number, purpose, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+-- Failed logins per phone number ("phone:+880...") and per client IP
+-- address ("ip:..."). blocked_until is when the next attempt is allowed;
+-- locked marks it as a lockout after too many failures.
+CREATE TABLE IF NOT EXISTS login_attempt (
+    login_key       VARCHAR(64) NOT NULL,
+    failures        INT         NOT NULL DEFAULT 0,
+    last_failure_at DATETIME    NOT NULL,
+    blocked_until   DATETIME    NULL,
+    locked          BOOLEAN     NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (login_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
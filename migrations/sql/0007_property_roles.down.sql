@@ -0,0 +1 @@
+ALTER TABLE takes_care_of DROP COLUMN role;
@@ -0,0 +1,4 @@
+-- Members of a property have a role: owner, manager, caretaker, accountant
+-- or read_only. Until now only the creator of a property was a member, so
+-- every existing row is an owner.
+ALTER TABLE takes_care_of ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'owner' AFTER pid;
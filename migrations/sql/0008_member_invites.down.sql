@@ -0,0 +1,2 @@
+ALTER TABLE notification DROP KEY idx_notification_pid_status;
+ALTER TABLE notification DROP COLUMN role;
@@ -0,0 +1,4 @@
+-- An invitation to become a member of a property is a pending notification
+-- without a floor that carries the offered role.
+ALTER TABLE notification ADD COLUMN role VARCHAR(20) NULL AFTER fid;
+ALTER TABLE notification ADD KEY idx_notification_pid_status (pid, status);
@@ -0,0 +1,35 @@
+-- An owner handing a property to another user. The recipient answers the
+-- notification nid; status follows it (pending, accepted, rejected) or
+-- becomes cancelled when the owner withdraws the notification.
+CREATE TABLE IF NOT EXISTS property_transfer (
+    id         BIGINT      NOT NULL,
+    pid        BIGINT      NOT NULL,
+    from_uid   BIGINT      NOT NULL,
+    to_uid     BIGINT      NOT NULL,
+    nid        BIGINT      NOT NULL,
+    status     VARCHAR(20) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    created_by BIGINT      NOT NULL,
+    updated_at DATETIME    NOT NULL,
+    updated_by BIGINT      NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_property_transfer_pid_status (pid, status),
+    KEY idx_property_transfer_nid (nid),
+    CONSTRAINT fk_property_transfer_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_property_transfer_from FOREIGN KEY (from_uid) REFERENCES user (id),
+    CONSTRAINT fk_property_transfer_to FOREIGN KEY (to_uid) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Who did what to a property, kept for good. Rows are never updated.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         BIGINT      NOT NULL,
+    pid        BIGINT      NOT NULL,
+    actor      BIGINT      NOT NULL,
+    action     VARCHAR(50) NOT NULL,
+    details    TEXT        NULL,
+    created_at DATETIME    NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_audit_log_pid (pid, created_at),
+    CONSTRAINT fk_audit_log_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_audit_log_actor FOREIGN KEY (actor) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
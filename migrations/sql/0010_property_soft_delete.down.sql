@@ -0,0 +1,2 @@
+ALTER TABLE property DROP COLUMN deleted_by;
+ALTER TABLE property DROP COLUMN deleted_at;
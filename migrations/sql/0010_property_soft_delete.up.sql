@@ -0,0 +1,5 @@
+-- Deleting a property only marks it, so that its floors, payments and
+-- notifications stay behind as history. Deleted properties are left out of
+-- every lookup.
+ALTER TABLE property ADD COLUMN deleted_at DATETIME NULL AFTER updated_by;
+ALTER TABLE property ADD COLUMN deleted_by BIGINT NULL AFTER deleted_at;
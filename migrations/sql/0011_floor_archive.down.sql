@@ -0,0 +1,2 @@
+ALTER TABLE floor DROP COLUMN archived_by;
+ALTER TABLE floor DROP COLUMN archived_at;
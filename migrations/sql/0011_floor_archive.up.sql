@@ -0,0 +1,4 @@
+-- Deleting a floor archives it: the row stays so that payments and
+-- notifications still point at it, and it can be restored later.
+ALTER TABLE floor ADD COLUMN archived_at DATETIME NULL AFTER updated_by;
+ALTER TABLE floor ADD COLUMN archived_by BIGINT NULL AFTER archived_at;
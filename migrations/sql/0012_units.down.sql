@@ -0,0 +1,17 @@
+-- Only the unit that took over a floor's ID goes back into the floor.
+-- Payments and requests of other units stay with their floor.
+ALTER TABLE floor ADD COLUMN rent INT NOT NULL DEFAULT 0 AFTER `name`;
+ALTER TABLE floor ADD COLUMN tenant BIGINT NULL AFTER rent;
+ALTER TABLE floor ADD KEY idx_floor_tenant (tenant);
+ALTER TABLE floor ADD CONSTRAINT fk_floor_tenant FOREIGN KEY (tenant) REFERENCES user (id);
+UPDATE floor f INNER JOIN unit u ON u.id = f.id SET f.rent = u.rent, f.tenant = u.tenant;
+
+ALTER TABLE notification DROP FOREIGN KEY fk_notification_unit;
+ALTER TABLE notification DROP INDEX idx_notification_unit_status;
+ALTER TABLE notification DROP COLUMN unit_id;
+
+ALTER TABLE payment DROP FOREIGN KEY fk_payment_unit;
+ALTER TABLE payment DROP INDEX idx_payment_unit_created_at;
+ALTER TABLE payment DROP COLUMN unit_id;
+
+DROP TABLE IF EXISTS unit;
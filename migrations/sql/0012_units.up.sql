@@ -0,0 +1,44 @@
+-- Floors are split into units (flats, rooms, shops). Rent and tenant move
+-- from the floor to its units, and payments and tenant requests point at a
+-- unit. Every existing floor becomes a floor with one unit that has the
+-- floor's ID, name, rent and tenant, so old payments and requests keep
+-- pointing at the same place.
+CREATE TABLE IF NOT EXISTS unit (
+    id         BIGINT       NOT NULL,
+    fid        BIGINT       NOT NULL,
+    pid        BIGINT       NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    rent       INT          NOT NULL DEFAULT 0,
+    tenant     BIGINT       NULL,
+    created_at DATETIME     NOT NULL,
+    created_by BIGINT       NOT NULL,
+    updated_at DATETIME     NOT NULL,
+    updated_by BIGINT       NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_unit_fid (fid),
+    KEY idx_unit_pid (pid),
+    KEY idx_unit_tenant (tenant),
+    CONSTRAINT fk_unit_floor FOREIGN KEY (fid) REFERENCES floor (id),
+    CONSTRAINT fk_unit_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_unit_tenant FOREIGN KEY (tenant) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO unit (id, fid, pid, name, rent, tenant, created_at, created_by, updated_at, updated_by)
+SELECT id, id, pid, name, rent, tenant, created_at, created_by, updated_at, updated_by
+FROM floor;
+
+ALTER TABLE payment ADD COLUMN unit_id BIGINT NULL AFTER fid;
+UPDATE payment SET unit_id = fid;
+ALTER TABLE payment MODIFY unit_id BIGINT NOT NULL;
+ALTER TABLE payment ADD KEY idx_payment_unit_created_at (unit_id, created_at);
+ALTER TABLE payment ADD CONSTRAINT fk_payment_unit FOREIGN KEY (unit_id) REFERENCES unit (id);
+
+ALTER TABLE notification ADD COLUMN unit_id BIGINT NULL AFTER fid;
+UPDATE notification SET unit_id = fid WHERE fid IS NOT NULL;
+ALTER TABLE notification ADD KEY idx_notification_unit_status (unit_id, status);
+ALTER TABLE notification ADD CONSTRAINT fk_notification_unit FOREIGN KEY (unit_id) REFERENCES unit (id);
+
+ALTER TABLE floor DROP FOREIGN KEY fk_floor_tenant;
+ALTER TABLE floor DROP INDEX idx_floor_tenant;
+ALTER TABLE floor DROP COLUMN tenant;
+ALTER TABLE floor DROP COLUMN rent;
@@ -0,0 +1,37 @@
+-- A tenant's stay in a unit. Accepting a tenant request opens an active
+-- tenancy; removing the tenant sets end_date and makes it ended. A unit
+-- has at most one active tenancy, the one of unit.tenant.
+CREATE TABLE IF NOT EXISTS tenancy (
+    id         BIGINT      NOT NULL,
+    unit_id    BIGINT      NOT NULL,
+    fid        BIGINT      NOT NULL,
+    pid        BIGINT      NOT NULL,
+    tenant     BIGINT      NOT NULL,
+    start_date DATE        NOT NULL,
+    end_date   DATE        NULL,
+    rent       INT         NOT NULL,
+    status     VARCHAR(20) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    created_by BIGINT      NOT NULL,
+    updated_at DATETIME    NOT NULL,
+    updated_by BIGINT      NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_tenancy_unit_status (unit_id, status),
+    KEY idx_tenancy_fid_start (fid, start_date),
+    KEY idx_tenancy_tenant (tenant),
+    CONSTRAINT fk_tenancy_unit FOREIGN KEY (unit_id) REFERENCES unit (id),
+    CONSTRAINT fk_tenancy_floor FOREIGN KEY (fid) REFERENCES floor (id),
+    CONSTRAINT fk_tenancy_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_tenancy_tenant FOREIGN KEY (tenant) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Current tenants get an active tenancy that takes the unit's ID. When they
+-- moved in wasn't recorded, the last change of the unit is the best guess.
+INSERT INTO tenancy (
+    id, unit_id, fid, pid, tenant, start_date, end_date, rent, status,
+    created_at, created_by, updated_at, updated_by
+)
+SELECT id, id, fid, pid, tenant, DATE(updated_at), NULL, rent, 'active',
+       updated_at, updated_by, updated_at, updated_by
+FROM unit
+WHERE tenant IS NOT NULL;
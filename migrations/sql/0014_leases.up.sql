@@ -0,0 +1,34 @@
+-- The terms agreed for a tenancy: its dates, monthly rent, advance or
+-- deposit, notice period in days, the day of the month rent is due and
+-- free-text clauses. The leases of a unit must not overlap, which the
+-- application checks. Deleted leases keep their row with deleted_at set.
+CREATE TABLE IF NOT EXISTS lease (
+    id          BIGINT   NOT NULL,
+    tenancy_id  BIGINT   NOT NULL,
+    unit_id     BIGINT   NOT NULL,
+    fid         BIGINT   NOT NULL,
+    pid         BIGINT   NOT NULL,
+    tenant      BIGINT   NOT NULL,
+    start_date  DATE     NOT NULL,
+    end_date    DATE     NOT NULL,
+    rent        INT      NOT NULL,
+    deposit     INT      NOT NULL DEFAULT 0,
+    notice_days INT      NOT NULL DEFAULT 0,
+    billing_day TINYINT  NOT NULL,
+    clauses     TEXT     NULL,
+    created_at  DATETIME NOT NULL,
+    created_by  BIGINT   NOT NULL,
+    updated_at  DATETIME NOT NULL,
+    updated_by  BIGINT   NOT NULL,
+    deleted_at  DATETIME NULL,
+    deleted_by  BIGINT   NULL,
+    PRIMARY KEY (id),
+    KEY idx_lease_unit_dates (unit_id, start_date, end_date),
+    KEY idx_lease_fid (fid),
+    KEY idx_lease_tenancy (tenancy_id),
+    CONSTRAINT fk_lease_tenancy FOREIGN KEY (tenancy_id) REFERENCES tenancy (id),
+    CONSTRAINT fk_lease_unit FOREIGN KEY (unit_id) REFERENCES unit (id),
+    CONSTRAINT fk_lease_floor FOREIGN KEY (fid) REFERENCES floor (id),
+    CONSTRAINT fk_lease_property FOREIGN KEY (pid) REFERENCES property (id),
+    CONSTRAINT fk_lease_tenant FOREIGN KEY (tenant) REFERENCES user (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,19 @@
+package models
+
+// Actions recorded in the audit log
+const (
+	AuditTransferRequested = "ownership_transfer_requested"
+	AuditTransferAccepted  = "ownership_transferred"
+	AuditTransferRejected  = "ownership_transfer_declined"
+	AuditTransferCancelled = "ownership_transfer_cancelled"
+)
+
+// AuditEntry records that a user did something to a property
+type AuditEntry struct {
+	ID         int64  `json:"id"`
+	PropertyID int64  `json:"pid"`
+	ActorID    int64  `json:"actor"`
+	Action     string `json:"action"`
+	Details    string `json:"details,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Floor groups the units on one level of a property. Rent and tenant are
+// kept on the units.
+type Floor struct {
+	ID         int64  `json:"id"`
+	PropertyID int64  `json:"pid"`
+	Name       string `json:"name"`
+	CreatedAt  string `json:"created_at"`
+	CreatedBy  int64  `json:"created_by"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  int64  `json:"updated_by"`
+	// ArchivedAt is set once the floor was deleted
+	ArchivedAt string `json:"archived_at,omitempty"`
+	ArchivedBy int64  `json:"archived_by,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Lease holds the terms a tenant agreed to for a tenancy. Dates are
+// YYYY-MM-DD and both ends are part of the lease; leases of the same unit
+// never overlap. A tenancy that is renewed gets a lease per term.
+type Lease struct {
+	ID         int64  `json:"id"`
+	TenancyID  int64  `json:"tenancy_id"`
+	UnitID     int64  `json:"unit_id"`
+	FloorID    int64  `json:"fid"`
+	PropertyID int64  `json:"pid"`
+	TenantID   int64  `json:"tenant"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	Rent       int    `json:"rent"`
+	Deposit    int    `json:"deposit"`
+	NoticeDays int    `json:"notice_days"`
+	// BillingDay is the day of the month the rent is due
+	BillingDay int    `json:"billing_day"`
+	Clauses    string `json:"clauses,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	CreatedBy  int64  `json:"created_by"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  int64  `json:"updated_by"`
+	// DeletedAt is set once the lease is deleted, see LeaseStore.Delete
+	DeletedAt string `json:"deleted_at,omitempty"`
+	DeletedBy int64  `json:"deleted_by,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the failed logins of a phone number or a client IP
+// address
+type LoginAttempt struct {
+	// Key is "phone:<number>" or "ip:<address>"
+	Key string `json:"key"`
+	// Failures counts the logins that didn't succeed. A login is counted
+	// before its password is checked and taken back if it succeeds.
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	// BlockedUntil is when the next login may be tried, zero if it may be
+	// tried now
+	BlockedUntil time.Time `json:"blocked_until"`
+	// Locked marks BlockedUntil as a lockout, which only ends when it runs
+	// out or an admin unlocks the key
+	Locked bool `json:"locked"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Member is a user with a role on a property, a row of takes_care_of joined
+// with the user
+type Member struct {
+	UserID      int64  `json:"user_id"`
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phone_number"`
+	Role        string `json:"role"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   int64  `json:"created_by"`
+}
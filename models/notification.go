@@ -0,0 +1,29 @@
+package models
+
+const (
+	NotificationPending  = "pending"
+	NotificationAccepted = "accepted"
+	NotificationRejected = "rejected"
+)
+
+type Notification struct {
+	ID         int64  `json:"id"`
+	Message    string `json:"message"`
+	Sender     *int64 `json:"sender,omitempty"`
+	Receiver   int64  `json:"receiver"`
+	PropertyID int64  `json:"pid"`
+	FloorID    *int64 `json:"fid,omitempty"`     // set on tenant requests
+	UnitID     *int64 `json:"unit_id,omitempty"` // set with FloorID
+	Role       string `json:"role,omitempty"`    // set on invitations to join the property
+	Status     string `json:"status,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	CreatedBy  int64  `json:"created_by"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  int64  `json:"updated_by"`
+
+	// PropertyName, FloorName and UnitName are filled in by listing queries
+	// that join the property, floor and unit tables.
+	PropertyName string `json:"property_name,omitempty"`
+	FloorName    string `json:"floor_name,omitempty"`
+	UnitName     string `json:"unit_name,omitempty"`
+}
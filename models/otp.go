@@ -0,0 +1,21 @@
+package models
+
+// OTP purposes
+const (
+	OTPRegister      = "register"
+	OTPPasswordReset = "password_reset"
+	OTPPhoneChange   = "phone_change"
+)
+
+// OTP is a one-time code sent by SMS to prove control of a phone number
+type OTP struct {
+	ID          int64  `json:"id"`
+	Purpose     string `json:"purpose"`
+	PhoneNumber string `json:"phone_number"`
+	// UserID is the account the code was sent for, 0 if none
+	UserID    int64  `json:"uid,omitempty"`
+	CodeHash  string `json:"-"`
+	Attempts  int    `json:"attempts"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
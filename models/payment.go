@@ -0,0 +1,16 @@
+package models
+
+type Payment struct {
+	ID                 int64  `json:"id"`
+	FloorID            int64  `json:"fid"`
+	UnitID             int64  `json:"unit_id"`
+	TenantID           int64  `json:"uid"`
+	DueRent            int    `json:"due_rent"`
+	DueElectricityBill int    `json:"due_electricity_bill"`
+	ReceivedMoney      int    `json:"received_money"`
+	FullPayment        bool   `json:"full_payment"`
+	CreatedAt          string `json:"created_at"`
+	CreatedBy          int64  `json:"created_by"`
+	UpdatedAt          string `json:"updated_at"`
+	UpdatedBy          int64  `json:"updated_by"`
+}
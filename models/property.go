@@ -0,0 +1,18 @@
+package models
+
+type Property struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy int64  `json:"created_by"`
+	UpdatedAt string `json:"updated_at"`
+	UpdatedBy int64  `json:"updated_by"`
+	// DeletedAt is set once the property is deleted, see
+	// PropertyStore.Delete
+	DeletedAt string `json:"deleted_at,omitempty"`
+	DeletedBy int64  `json:"deleted_by,omitempty"`
+
+	// Role is the listing user's role on the property, set by ListManaged
+	Role string `json:"role,omitempty"`
+}
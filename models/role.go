@@ -0,0 +1,11 @@
+package models
+
+// Roles a user can have on a property, stored in takes_care_of.role. What
+// each role may do is decided by the policy package.
+const (
+	RoleOwner      = "owner"
+	RoleManager    = "manager"
+	RoleCaretaker  = "caretaker"
+	RoleAccountant = "accountant"
+	RoleReadOnly   = "read_only"
+)
@@ -0,0 +1,19 @@
+package models
+
+// Session is one login of a user. RefreshHash and PreviousRefreshHash are
+// SHA-256 hashes; the tokens themselves are never stored.
+type Session struct {
+	ID                  int64  `json:"id"`
+	UserID              int64  `json:"uid"`
+	RefreshHash         string `json:"-"`
+	PreviousRefreshHash string `json:"-"`
+	// DeviceName is chosen by the client at login, UserAgent and IPAddress
+	// are taken from the request
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Tenancy statuses
+const (
+	TenancyActive = "active"
+	TenancyEnded  = "ended"
+)
+
+// Tenancy is a tenant's stay in a unit, from the day they moved in until
+// they were removed. Dates are YYYY-MM-DD and EndDate is empty while the
+// tenancy is active.
+type Tenancy struct {
+	ID         int64  `json:"id"`
+	UnitID     int64  `json:"unit_id"`
+	FloorID    int64  `json:"fid"`
+	PropertyID int64  `json:"pid"`
+	TenantID   int64  `json:"tenant"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date,omitempty"`
+	Rent       int    `json:"rent"`
+	Status     string `json:"status"`
+	CreatedAt  string `json:"created_at"`
+	CreatedBy  int64  `json:"created_by"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  int64  `json:"updated_by"`
+
+	// TenantName and UnitName are filled in by listings, they are not
+	// columns of the tenancy table
+	TenantName string `json:"tenant_name,omitempty"`
+	UnitName   string `json:"unit_name,omitempty"`
+}
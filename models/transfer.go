@@ -0,0 +1,25 @@
+package models
+
+// Statuses of a transfer. A transfer is pending until the recipient
+// answers its notification or the owner withdraws it.
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferRejected  = "rejected"
+	TransferCancelled = "cancelled"
+)
+
+// Transfer is an owner handing their ownership of a property to another
+// user, who has to accept it
+type Transfer struct {
+	ID             int64  `json:"id"`
+	PropertyID     int64  `json:"pid"`
+	FromUserID     int64  `json:"from_uid"`
+	ToUserID       int64  `json:"to_uid"`
+	NotificationID int64  `json:"nid"`
+	Status         string `json:"status"`
+	CreatedAt      string `json:"created_at"`
+	CreatedBy      int64  `json:"created_by"`
+	UpdatedAt      string `json:"updated_at"`
+	UpdatedBy      int64  `json:"updated_by"`
+}
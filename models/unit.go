@@ -0,0 +1,20 @@
+package models
+
+// Unit is the part of a floor that is rented out: a flat, a room or a
+// shop. A floor that is rented as a whole has a single unit.
+type Unit struct {
+	ID         int64  `json:"id"`
+	FloorID    int64  `json:"fid"`
+	PropertyID int64  `json:"pid"`
+	Name       string `json:"name"`
+	Rent       int    `json:"rent"`
+	Tenant     *int64 `json:"tenant,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	CreatedBy  int64  `json:"created_by"`
+	UpdatedAt  string `json:"updated_at"`
+	UpdatedBy  int64  `json:"updated_by"`
+
+	// PendingNotificationID is set when a tenant request for the unit is
+	// still waiting for an answer. It is not a column of the unit table.
+	PendingNotificationID *int64 `json:"pending_notification_id,omitempty"`
+}
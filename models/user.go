@@ -1,14 +1,16 @@
 package models
 
 type User struct {
-	ID          int    `json:"id"`
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
 	PhoneNumber string `json:"phone_number"`
 	Email       string `json:"email,omitempty"`
 	NID         string `json:"nid,omitempty"`
 	Password    string `json:"password"`
 	Manager     *bool  `json:"manager,omitempty"`
+	Verified    bool   `json:"verified"` // phone number confirmed by OTP
 	CreatedAt   string `json:"created_at"`
-	CreatedBy   int    `json:"created_by"`
+	CreatedBy   int64  `json:"created_by"`
 	UpdatedAt   string `json:"updated_at"`
-	UpdatedBy   int    `json:"updated_by"`
-} 
\ No newline at end of file
+	UpdatedBy   int64  `json:"updated_by"`
+}
@@ -0,0 +1,60 @@
+// Package policy decides what the members of a property may do with it,
+// depending on their role (see models.Role*). Every permission check on a
+// property goes through Allows.
+package policy
+
+import "go-rent/models"
+
+// Action is something a member can do with a property
+type Action string
+
+const (
+	// ViewProperty covers the property, its floors and their tenants
+	ViewProperty Action = "view_property"
+	// EditProperty changes the name and address of the property
+	EditProperty Action = "edit_property"
+	// ManageFloors adds floors and changes their name and rent
+	ManageFloors Action = "manage_floors"
+	// ManageTenants sends tenant requests and removes tenants
+	ManageTenants Action = "manage_tenants"
+	// RecordPayments records rent and bill payments
+	RecordPayments Action = "record_payments"
+	// ManageMembers invites, changes and removes members
+	ManageMembers Action = "manage_members"
+	// TransferProperty hands the caller's ownership to another user
+	TransferProperty Action = "transfer_property"
+	// DeleteProperty deletes the property once it has no tenants
+	DeleteProperty Action = "delete_property"
+)
+
+// actions lists what each role may do, in the order of the constants above
+var actions = map[string][]Action{
+	models.RoleOwner:      {ViewProperty, EditProperty, ManageFloors, ManageTenants, RecordPayments, ManageMembers, TransferProperty, DeleteProperty},
+	models.RoleManager:    {ViewProperty, EditProperty, ManageFloors, ManageTenants, RecordPayments},
+	models.RoleCaretaker:  {ViewProperty, ManageFloors},
+	models.RoleAccountant: {ViewProperty, RecordPayments},
+	models.RoleReadOnly:   {ViewProperty},
+}
+
+// Allows reports whether a member with the given role may take the action.
+// An unknown or empty role allows nothing.
+func Allows(role string, action Action) bool {
+	for _, a := range actions[role] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns every action the role allows, for clients that adapt
+// their UI to it
+func Permissions(role string) []Action {
+	return append([]Action{}, actions[role]...)
+}
+
+// ValidRole reports whether role is one of models.Role*
+func ValidRole(role string) bool {
+	_, ok := actions[role]
+	return ok
+}
@@ -0,0 +1,69 @@
+// Package sms sends text messages to phone numbers. No SMS gateway is wired
+// up yet; the senders here are for development and write the message
+// where the developer can read it.
+package sms
+
+import (
+	"context"
+	"fmt"
+	"go-rent/logging"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Sender delivers a text message to a phone number in +880XXXXXXXXXX form
+type Sender interface {
+	Send(ctx context.Context, phoneNumber, message string) error
+}
+
+// New returns the sender named by kind: "log" or "file", which appends to
+// path
+func New(kind, path string) (Sender, error) {
+	switch kind {
+	case "log":
+		return LogSender{}, nil
+	case "file":
+		return &FileSender{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS sender %q", kind)
+	}
+}
+
+// codePattern matches the one-time codes in a message
+var codePattern = regexp.MustCompile(`\b\d{4,8}\b`)
+
+// LogSender writes messages to the server log. Codes in them are masked so
+// the log never holds one that works; use FileSender to read them.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, phoneNumber, message string) error {
+	logging.FromContext(ctx).Info("SMS sent", "phone_number", phoneNumber, "text", codePattern.ReplaceAllString(message, "******"))
+	return nil
+}
+
+// FileSender appends one line per message to the file at Path
+type FileSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileSender) Send(ctx context.Context, phoneNumber, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening SMS file: %v", err)
+	}
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phoneNumber, message)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing SMS file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogSenderMasksCodes(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	err := LogSender{}.Send(context.Background(), "+8801711000001", "Your go-rent verification code is 482913. It expires in 5 minutes.")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "482913") || !strings.Contains(out, "expires in 5 minutes") {
+		t.Errorf("logged %q, want the message with the code masked", out)
+	}
+}
@@ -0,0 +1,1591 @@
+package store
+
+import (
+	"context"
+	"go-rent/models"
+	"go-rent/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryDB holds every table of the in-memory store. Rows are kept in
+// insertion order so that "newest first" listings can walk them backwards.
+type memoryDB struct {
+	// txMu serializes units of work, mu guards the tables
+	txMu          sync.Mutex
+	mu            sync.RWMutex
+	users         []models.User
+	properties    []models.Property
+	managers      []memoryManager
+	floors        []models.Floor
+	units         []models.Unit
+	tenancies     []models.Tenancy
+	leases        []models.Lease
+	payments      []models.Payment
+	notifications []models.Notification
+	sessions      []memorySession
+	otps          []memoryOTP
+	loginAttempts []models.LoginAttempt
+	transfers     []models.Transfer
+	audit         []models.AuditEntry
+}
+
+type memoryManager struct {
+	PropertyID int64
+	UserID     int64
+	Role       string
+	CreatedAt  string
+	CreatedBy  int64
+}
+
+type memorySession struct {
+	models.Session
+	revoked bool
+}
+
+type memoryOTP struct {
+	models.OTP
+	consumed bool
+}
+
+// NewMemory returns a Store that keeps everything in process memory.
+// It is meant for tests and local experiments; nothing is persisted.
+func NewMemory() *Store {
+	m := &memoryDB{}
+	s := &Store{
+		Users:         &memoryUsers{m},
+		Properties:    &memoryProperties{m},
+		Floors:        &memoryFloors{m},
+		Units:         &memoryUnits{m},
+		Tenancies:     &memoryTenancies{m},
+		Leases:        &memoryLeases{m},
+		Payments:      &memoryPayments{m},
+		Notifications: &memoryNotifications{m},
+		Sessions:      &memorySessions{m},
+		OTPs:          &memoryOTPs{m},
+		LoginAttempts: &memoryLoginAttempts{m},
+		Transfers:     &memoryTransfers{m},
+		Audit:         &memoryAudit{m},
+		IDs:           &memoryIDs{m},
+	}
+	s.tx = memoryTx{m, s}
+	return s
+}
+
+// snapshot copies every table. Rows are values and pointer fields are
+// never mutated in place, so copying the slices is enough.
+func (m *memoryDB) snapshot() *memoryDB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &memoryDB{
+		users:         append([]models.User(nil), m.users...),
+		properties:    append([]models.Property(nil), m.properties...),
+		managers:      append([]memoryManager(nil), m.managers...),
+		floors:        append([]models.Floor(nil), m.floors...),
+		units:         append([]models.Unit(nil), m.units...),
+		tenancies:     append([]models.Tenancy(nil), m.tenancies...),
+		leases:        append([]models.Lease(nil), m.leases...),
+		payments:      append([]models.Payment(nil), m.payments...),
+		notifications: append([]models.Notification(nil), m.notifications...),
+		sessions:      append([]memorySession(nil), m.sessions...),
+		otps:          append([]memoryOTP(nil), m.otps...),
+		loginAttempts: append([]models.LoginAttempt(nil), m.loginAttempts...),
+		transfers:     append([]models.Transfer(nil), m.transfers...),
+		audit:         append([]models.AuditEntry(nil), m.audit...),
+	}
+}
+
+// restore puts back the tables of a snapshot
+func (m *memoryDB) restore(s *memoryDB) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users = s.users
+	m.properties = s.properties
+	m.managers = s.managers
+	m.floors = s.floors
+	m.units = s.units
+	m.tenancies = s.tenancies
+	m.leases = s.leases
+	m.payments = s.payments
+	m.notifications = s.notifications
+	m.sessions = s.sessions
+	m.otps = s.otps
+	m.loginAttempts = s.loginAttempts
+	m.transfers = s.transfers
+	m.audit = s.audit
+}
+
+func (m *memoryDB) floorIndex(floorID int64) int {
+	for i := range m.floors {
+		if m.floors[i].ID == floorID {
+			return i
+		}
+	}
+	return -1
+}
+
+// propertyIndex returns the index of the property, -1 if it doesn't exist
+// or was deleted
+func (m *memoryDB) propertyIndex(propertyID int64) int {
+	for i := range m.properties {
+		if m.properties[i].ID == propertyID && m.properties[i].DeletedAt == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// role returns the user's role on the property, "" if the user is not a
+// member or the property was deleted
+func (m *memoryDB) role(propertyID, userID int64) string {
+	if m.propertyIndex(propertyID) < 0 {
+		return ""
+	}
+	for _, t := range m.managers {
+		if t.PropertyID == propertyID && t.UserID == userID {
+			return t.Role
+		}
+	}
+	return ""
+}
+
+func (m *memoryDB) isManager(propertyID, userID int64) bool {
+	return m.role(propertyID, userID) != ""
+}
+
+func (m *memoryDB) isTenant(propertyID, userID int64) bool {
+	for _, u := range m.units {
+		if u.PropertyID == propertyID && u.Tenant != nil && *u.Tenant == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// unitIndex returns the index of the unit, -1 if it doesn't exist or its
+// floor was archived
+func (m *memoryDB) unitIndex(unitID int64) int {
+	for i := range m.units {
+		if m.units[i].ID == unitID {
+			if f := m.floorIndex(m.units[i].FloorID); f < 0 || m.floors[f].ArchivedAt != "" {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// occupied reports whether a unit of the floor, or of the whole property if
+// floorID is 0, has a tenant or a pending tenant request
+func (m *memoryDB) occupied(propertyID, floorID int64) bool {
+	for _, u := range m.units {
+		if u.PropertyID != propertyID || (floorID != 0 && u.FloorID != floorID) {
+			continue
+		}
+		if u.Tenant != nil || m.pendingNotification(u.ID) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingNotification returns the ID of the pending tenant request for the
+// unit, nil if there is none
+func (m *memoryDB) pendingNotification(unitID int64) *int64 {
+	for _, n := range m.notifications {
+		if n.UnitID != nil && *n.UnitID == unitID && n.Status == models.NotificationPending {
+			id := n.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+func copyInt64(p *int64) *int64 {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+type memoryUsers struct{ m *memoryDB }
+
+func (s *memoryUsers) Create(ctx context.Context, u *models.User) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for _, existing := range s.m.users {
+		if existing.PhoneNumber == u.PhoneNumber {
+			return ErrConflict
+		}
+	}
+	if u.CreatedBy == 0 {
+		u.CreatedBy = id
+	}
+	if u.UpdatedBy == 0 {
+		u.UpdatedBy = u.CreatedBy
+	}
+	u.ID = id
+	u.CreatedAt = timestamp()
+	u.UpdatedAt = u.CreatedAt
+	s.m.users = append(s.m.users, *u)
+	return nil
+}
+
+func (s *memoryUsers) Get(ctx context.Context, id int64) (*models.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, u := range s.m.users {
+		if u.ID == id {
+			u.Password = ""
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryUsers) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, u := range s.m.users {
+		if u.PhoneNumber == phone {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryUsers) UpdateUnverified(ctx context.Context, u *models.User) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.users {
+		existing := &s.m.users[i]
+		if existing.ID != u.ID {
+			continue
+		}
+		if existing.Verified {
+			return ErrConflict
+		}
+		existing.Name = u.Name
+		existing.Email = u.Email
+		existing.NID = u.NID
+		existing.Password = u.Password
+		existing.Manager = u.Manager
+		existing.UpdatedAt = timestamp()
+		u.UpdatedAt = existing.UpdatedAt
+		return nil
+	}
+	return ErrConflict
+}
+
+func (s *memoryUsers) Verify(ctx context.Context, id int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.users {
+		u := &s.m.users[i]
+		if u.ID == id && !u.Verified {
+			u.Verified = true
+			u.UpdatedAt = timestamp()
+			u.UpdatedBy = id
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+func (s *memoryUsers) ListWithPhone(ctx context.Context) ([]models.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var users []models.User
+	for _, u := range s.m.users {
+		if u.PhoneNumber != "" && u.Verified {
+			users = append(users, models.User{ID: u.ID, PhoneNumber: u.PhoneNumber})
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID > users[j].ID })
+	return users, nil
+}
+
+func (s *memoryUsers) UpdateProfile(ctx context.Context, u *models.User) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.users {
+		existing := &s.m.users[i]
+		if existing.ID == u.ID {
+			existing.Name = u.Name
+			existing.Email = u.Email
+			existing.NID = u.NID
+			existing.Manager = u.Manager
+			existing.UpdatedAt = timestamp()
+			existing.UpdatedBy = u.UpdatedBy
+			u.UpdatedAt = existing.UpdatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryUsers) SetPhone(ctx context.Context, id int64, phone string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	index := -1
+	for i, u := range s.m.users {
+		if u.PhoneNumber == phone && u.ID != id {
+			return ErrConflict
+		}
+		if u.ID == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		return ErrNotFound
+	}
+	u := &s.m.users[index]
+	u.PhoneNumber = phone
+	u.UpdatedAt = timestamp()
+	u.UpdatedBy = id
+	return nil
+}
+
+func (s *memoryUsers) DeleteUnverified(ctx context.Context, phone string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i, u := range s.m.users {
+		if u.PhoneNumber == phone && !u.Verified {
+			s.m.users = append(s.m.users[:i:i], s.m.users[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memoryUsers) SetPassword(ctx context.Context, id int64, hash string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.users {
+		u := &s.m.users[i]
+		if u.ID == id {
+			u.Password = hash
+			u.UpdatedAt = timestamp()
+			u.UpdatedBy = id
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryProperties struct{ m *memoryDB }
+
+func (s *memoryProperties) Create(ctx context.Context, p *models.Property) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	p.ID = id
+	p.CreatedAt = timestamp()
+	p.UpdatedAt = p.CreatedAt
+	p.UpdatedBy = p.CreatedBy
+	s.m.properties = append(s.m.properties, *p)
+	return nil
+}
+
+func (s *memoryProperties) Update(ctx context.Context, p *models.Property) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.propertyIndex(p.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	row := &s.m.properties[i]
+	row.Name = p.Name
+	row.Address = p.Address
+	row.UpdatedAt = timestamp()
+	row.UpdatedBy = p.UpdatedBy
+	p.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (s *memoryProperties) Delete(ctx context.Context, id, deletedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.propertyIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if s.m.occupied(id, 0) {
+		return ErrConflict
+	}
+	row := &s.m.properties[i]
+	row.DeletedAt = timestamp()
+	row.DeletedBy = deletedBy
+	row.UpdatedAt = row.DeletedAt
+	row.UpdatedBy = deletedBy
+	return nil
+}
+
+func (s *memoryProperties) AddMember(ctx context.Context, propertyID, userID int64, role string, createdBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if s.m.isManager(propertyID, userID) {
+		return ErrConflict
+	}
+	s.m.managers = append(s.m.managers, memoryManager{
+		PropertyID: propertyID,
+		UserID:     userID,
+		Role:       role,
+		CreatedAt:  timestamp(),
+		CreatedBy:  createdBy,
+	})
+	return nil
+}
+
+func (s *memoryProperties) Role(ctx context.Context, propertyID, userID int64) (string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	if role := s.m.role(propertyID, userID); role != "" {
+		return role, nil
+	}
+	return "", ErrNotFound
+}
+
+func (s *memoryProperties) ListMembers(ctx context.Context, propertyID int64) ([]models.Member, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var members []models.Member
+	for _, t := range s.m.managers {
+		if t.PropertyID != propertyID {
+			continue
+		}
+		for _, u := range s.m.users {
+			if u.ID == t.UserID {
+				members = append(members, models.Member{
+					UserID:      u.ID,
+					Name:        u.Name,
+					PhoneNumber: u.PhoneNumber,
+					Role:        t.Role,
+					CreatedAt:   t.CreatedAt,
+					CreatedBy:   t.CreatedBy,
+				})
+				break
+			}
+		}
+	}
+	return members, nil
+}
+
+func (s *memoryProperties) RemoveMember(ctx context.Context, propertyID, userID int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	owners := 0
+	for _, t := range s.m.managers {
+		if t.PropertyID == propertyID && t.Role == models.RoleOwner {
+			owners++
+		}
+	}
+	for i, t := range s.m.managers {
+		if t.PropertyID != propertyID || t.UserID != userID {
+			continue
+		}
+		if t.Role == models.RoleOwner && owners <= 1 {
+			return ErrConflict
+		}
+		s.m.managers = append(s.m.managers[:i:i], s.m.managers[i+1:]...)
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *memoryProperties) SetRole(ctx context.Context, propertyID, userID int64, role string, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.managers {
+		if s.m.managers[i].PropertyID == propertyID && s.m.managers[i].UserID == userID {
+			s.m.managers[i].Role = role
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryProperties) GetForUser(ctx context.Context, propertyID, userID int64) (*models.Property, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.propertyIndex(propertyID)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	if s.m.isManager(propertyID, userID) || s.m.isTenant(propertyID, userID) {
+		p := s.m.properties[i]
+		return &p, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryProperties) ListManaged(ctx context.Context, userID int64) ([]models.Property, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var properties []models.Property
+	for i := len(s.m.properties) - 1; i >= 0; i-- {
+		if role := s.m.role(s.m.properties[i].ID, userID); role != "" {
+			p := s.m.properties[i]
+			p.Role = role
+			properties = append(properties, p)
+		}
+	}
+	return properties, nil
+}
+
+func (s *memoryProperties) ListTenanted(ctx context.Context, userID int64) ([]models.Property, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var properties []models.Property
+	for i := len(s.m.properties) - 1; i >= 0; i-- {
+		if s.m.properties[i].DeletedAt == "" && s.m.isTenant(s.m.properties[i].ID, userID) {
+			properties = append(properties, s.m.properties[i])
+		}
+	}
+	return properties, nil
+}
+
+type memoryFloors struct{ m *memoryDB }
+
+func (s *memoryFloors) Create(ctx context.Context, f *models.Floor) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	f.ID = id
+	f.CreatedAt = timestamp()
+	f.UpdatedAt = f.CreatedAt
+	f.UpdatedBy = f.CreatedBy
+	s.m.floors = append(s.m.floors, *f)
+	return nil
+}
+
+func (s *memoryFloors) Get(ctx context.Context, propertyID, floorID int64) (*models.Floor, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.floorIndex(floorID)
+	if i < 0 || s.m.floors[i].PropertyID != propertyID || s.m.floors[i].ArchivedAt != "" {
+		return nil, ErrNotFound
+	}
+	f := s.m.floors[i]
+	return &f, nil
+}
+
+func (s *memoryFloors) ListByProperty(ctx context.Context, propertyID int64) ([]models.Floor, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var floors []models.Floor
+	for i := len(s.m.floors) - 1; i >= 0; i-- {
+		f := s.m.floors[i]
+		if f.PropertyID != propertyID || f.ArchivedAt != "" {
+			continue
+		}
+		floors = append(floors, f)
+	}
+	return floors, nil
+}
+
+func (s *memoryFloors) Update(ctx context.Context, f *models.Floor) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.floorIndex(f.ID)
+	if i < 0 || s.m.floors[i].PropertyID != f.PropertyID || s.m.floors[i].ArchivedAt != "" {
+		return nil
+	}
+	row := &s.m.floors[i]
+	row.Name = f.Name
+	row.UpdatedAt = timestamp()
+	row.UpdatedBy = f.UpdatedBy
+	f.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (s *memoryFloors) ListNames(ctx context.Context, propertyID int64) ([]string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var names []string
+	for _, f := range s.m.floors {
+		if f.PropertyID == propertyID && f.ArchivedAt == "" {
+			names = append(names, f.Name)
+		}
+	}
+	return names, nil
+}
+
+func (s *memoryFloors) ListArchived(ctx context.Context, propertyID int64) ([]models.Floor, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var floors []models.Floor
+	for _, f := range s.m.floors {
+		if f.PropertyID == propertyID && f.ArchivedAt != "" {
+			floors = append(floors, f)
+		}
+	}
+	sort.SliceStable(floors, func(i, j int) bool {
+		return floors[i].ArchivedAt > floors[j].ArchivedAt
+	})
+	return floors, nil
+}
+
+func (s *memoryFloors) Archive(ctx context.Context, propertyID, floorID, archivedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.floorIndex(floorID)
+	if i < 0 || s.m.floors[i].PropertyID != propertyID || s.m.floors[i].ArchivedAt != "" {
+		return ErrNotFound
+	}
+	if s.m.occupied(propertyID, floorID) {
+		return ErrConflict
+	}
+	row := &s.m.floors[i]
+	row.ArchivedAt = timestamp()
+	row.ArchivedBy = archivedBy
+	row.UpdatedAt = row.ArchivedAt
+	row.UpdatedBy = archivedBy
+	return nil
+}
+
+func (s *memoryFloors) Restore(ctx context.Context, propertyID, floorID, restoredBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.floorIndex(floorID)
+	if i < 0 || s.m.floors[i].PropertyID != propertyID || s.m.floors[i].ArchivedAt == "" {
+		return ErrNotFound
+	}
+	row := &s.m.floors[i]
+	for _, f := range s.m.floors {
+		if f.PropertyID == propertyID && f.ArchivedAt == "" && strings.EqualFold(f.Name, row.Name) {
+			return ErrConflict
+		}
+	}
+	row.ArchivedAt = ""
+	row.ArchivedBy = 0
+	row.UpdatedAt = timestamp()
+	row.UpdatedBy = restoredBy
+	return nil
+}
+
+type memoryUnits struct{ m *memoryDB }
+
+func (s *memoryUnits) Create(ctx context.Context, u *models.Unit) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u.ID = id
+	u.CreatedAt = timestamp()
+	u.UpdatedAt = u.CreatedAt
+	u.UpdatedBy = u.CreatedBy
+	row := *u
+	row.Tenant = copyInt64(u.Tenant)
+	row.PendingNotificationID = nil
+	s.m.units = append(s.m.units, row)
+	return nil
+}
+
+func (s *memoryUnits) Get(ctx context.Context, propertyID, floorID, unitID int64) (*models.Unit, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.unitIndex(unitID)
+	if i < 0 || s.m.units[i].PropertyID != propertyID || s.m.units[i].FloorID != floorID {
+		return nil, ErrNotFound
+	}
+	u := s.m.units[i]
+	u.Tenant = copyInt64(u.Tenant)
+	return &u, nil
+}
+
+func (s *memoryUnits) ListByProperty(ctx context.Context, propertyID int64) ([]models.Unit, error) {
+	return s.list(func(u models.Unit) bool { return u.PropertyID == propertyID }), nil
+}
+
+func (s *memoryUnits) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Unit, error) {
+	return s.list(func(u models.Unit) bool { return u.PropertyID == propertyID && u.FloorID == floorID }), nil
+}
+
+func (s *memoryUnits) list(match func(u models.Unit) bool) []models.Unit {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var units []models.Unit
+	for _, u := range s.m.units {
+		if !match(u) || s.m.unitIndex(u.ID) < 0 {
+			continue
+		}
+		u.Tenant = copyInt64(u.Tenant)
+		u.PendingNotificationID = s.m.pendingNotification(u.ID)
+		units = append(units, u)
+	}
+	return units
+}
+
+func (s *memoryUnits) Update(ctx context.Context, u *models.Unit) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.unitIndex(u.ID)
+	if i < 0 || s.m.units[i].PropertyID != u.PropertyID || s.m.units[i].FloorID != u.FloorID {
+		return nil
+	}
+	row := &s.m.units[i]
+	row.Name = u.Name
+	row.Rent = u.Rent
+	row.UpdatedAt = timestamp()
+	row.UpdatedBy = u.UpdatedBy
+	u.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (s *memoryUnits) AssignTenant(ctx context.Context, unitID, tenantID, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.unitIndex(unitID)
+	if i < 0 || s.m.units[i].Tenant != nil {
+		return ErrConflict
+	}
+	s.m.units[i].Tenant = &tenantID
+	s.m.units[i].UpdatedAt = timestamp()
+	s.m.units[i].UpdatedBy = updatedBy
+	return nil
+}
+
+func (s *memoryUnits) RemoveTenant(ctx context.Context, propertyID, floorID, unitID, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.unitIndex(unitID)
+	if i < 0 || s.m.units[i].PropertyID != propertyID || s.m.units[i].FloorID != floorID || s.m.units[i].Tenant == nil {
+		return ErrNotFound
+	}
+	s.m.units[i].Tenant = nil
+	s.m.units[i].UpdatedAt = timestamp()
+	s.m.units[i].UpdatedBy = updatedBy
+	return nil
+}
+
+func (s *memoryUnits) ListOccupied(ctx context.Context) ([]models.Unit, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var units []models.Unit
+	for _, u := range s.m.units {
+		if u.Tenant != nil {
+			u.Tenant = copyInt64(u.Tenant)
+			units = append(units, u)
+		}
+	}
+	return units, nil
+}
+
+type memoryTenancies struct{ m *memoryDB }
+
+func (s *memoryTenancies) Create(ctx context.Context, t *models.Tenancy) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	t.ID = id
+	t.CreatedAt = timestamp()
+	t.UpdatedAt = t.CreatedAt
+	t.UpdatedBy = t.CreatedBy
+	row := *t
+	row.TenantName = ""
+	row.UnitName = ""
+	s.m.tenancies = append(s.m.tenancies, row)
+	return nil
+}
+
+func (s *memoryTenancies) Get(ctx context.Context, propertyID, floorID, tenancyID int64) (*models.Tenancy, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, t := range s.m.tenancies {
+		if t.ID == tenancyID && t.PropertyID == propertyID && t.FloorID == floorID {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryTenancies) End(ctx context.Context, unitID int64, endDate string, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.tenancies {
+		t := &s.m.tenancies[i]
+		if t.UnitID == unitID && t.Status == models.TenancyActive {
+			t.EndDate = endDate
+			t.Status = models.TenancyEnded
+			t.UpdatedAt = timestamp()
+			t.UpdatedBy = updatedBy
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryTenancies) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Tenancy, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var tenancies []models.Tenancy
+	for i := len(s.m.tenancies) - 1; i >= 0; i-- {
+		t := s.m.tenancies[i]
+		if t.PropertyID != propertyID || t.FloorID != floorID {
+			continue
+		}
+		for _, u := range s.m.units {
+			if u.ID == t.UnitID {
+				t.UnitName = u.Name
+			}
+		}
+		for _, u := range s.m.users {
+			if u.ID == t.TenantID {
+				t.TenantName = u.Name
+			}
+		}
+		tenancies = append(tenancies, t)
+	}
+	sort.SliceStable(tenancies, func(i, j int) bool {
+		return tenancies[i].StartDate > tenancies[j].StartDate
+	})
+	return tenancies, nil
+}
+
+type memoryLeases struct{ m *memoryDB }
+
+func (s *memoryLeases) Create(ctx context.Context, l *models.Lease) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	l.ID = id
+	l.CreatedAt = timestamp()
+	l.UpdatedAt = l.CreatedAt
+	l.UpdatedBy = l.CreatedBy
+	s.m.leases = append(s.m.leases, *l)
+	return nil
+}
+
+// leaseIndex returns the index of the lease if it wasn't deleted, -1
+// otherwise
+func (m *memoryDB) leaseIndex(propertyID, floorID, leaseID int64) int {
+	for i, l := range m.leases {
+		if l.ID == leaseID && l.PropertyID == propertyID && l.FloorID == floorID && l.DeletedAt == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryLeases) Get(ctx context.Context, propertyID, floorID, leaseID int64) (*models.Lease, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.leaseIndex(propertyID, floorID, leaseID)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	l := s.m.leases[i]
+	return &l, nil
+}
+
+func (s *memoryLeases) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Lease, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var leases []models.Lease
+	for i := len(s.m.leases) - 1; i >= 0; i-- {
+		l := s.m.leases[i]
+		if l.PropertyID == propertyID && l.FloorID == floorID && l.DeletedAt == "" {
+			leases = append(leases, l)
+		}
+	}
+	sort.SliceStable(leases, func(i, j int) bool {
+		return leases[i].StartDate > leases[j].StartDate
+	})
+	return leases, nil
+}
+
+func (s *memoryLeases) ListByTenant(ctx context.Context, propertyID, floorID, tenantID int64) ([]models.Lease, error) {
+	leases, err := s.ListByFloor(ctx, propertyID, floorID)
+	if err != nil {
+		return nil, err
+	}
+	var own []models.Lease
+	for _, l := range leases {
+		if l.TenantID == tenantID {
+			own = append(own, l)
+		}
+	}
+	return own, nil
+}
+
+func (s *memoryLeases) Update(ctx context.Context, l *models.Lease) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.leaseIndex(l.PropertyID, l.FloorID, l.ID)
+	if i < 0 {
+		return nil
+	}
+	row := &s.m.leases[i]
+	row.StartDate = l.StartDate
+	row.EndDate = l.EndDate
+	row.Rent = l.Rent
+	row.Deposit = l.Deposit
+	row.NoticeDays = l.NoticeDays
+	row.BillingDay = l.BillingDay
+	row.Clauses = l.Clauses
+	row.UpdatedAt = timestamp()
+	row.UpdatedBy = l.UpdatedBy
+	l.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (s *memoryLeases) Delete(ctx context.Context, propertyID, floorID, leaseID, deletedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.leaseIndex(propertyID, floorID, leaseID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	row := &s.m.leases[i]
+	row.DeletedAt = timestamp()
+	row.DeletedBy = deletedBy
+	row.UpdatedAt = row.DeletedAt
+	row.UpdatedBy = deletedBy
+	return nil
+}
+
+func (s *memoryLeases) Overlapping(ctx context.Context, unitID, excludeID int64, start, end string) (*models.Lease, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var found *models.Lease
+	for _, l := range s.m.leases {
+		if l.UnitID != unitID || l.ID == excludeID || l.DeletedAt != "" {
+			continue
+		}
+		if l.StartDate <= end && l.EndDate >= start && (found == nil || l.StartDate < found.StartDate) {
+			l := l
+			found = &l
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+type memoryPayments struct{ m *memoryDB }
+
+func (s *memoryPayments) Create(ctx context.Context, p *models.Payment) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	p.ID = id
+	p.CreatedAt = timestamp()
+	p.UpdatedAt = p.CreatedAt
+	p.UpdatedBy = p.CreatedBy
+	s.m.payments = append(s.m.payments, *p)
+	return nil
+}
+
+func (s *memoryPayments) LatestForUnit(ctx context.Context, unitID int64) (*models.Payment, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for i := len(s.m.payments) - 1; i >= 0; i-- {
+		if s.m.payments[i].UnitID == unitID {
+			p := s.m.payments[i]
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryNotifications struct{ m *memoryDB }
+
+func (s *memoryNotifications) Create(ctx context.Context, n *models.Notification) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	n.ID = id
+	n.CreatedAt = timestamp()
+	n.UpdatedAt = n.CreatedAt
+	n.UpdatedBy = n.CreatedBy
+	row := *n
+	row.Sender = copyInt64(n.Sender)
+	row.FloorID = copyInt64(n.FloorID)
+	row.UnitID = copyInt64(n.UnitID)
+	s.m.notifications = append(s.m.notifications, row)
+	return nil
+}
+
+func (s *memoryNotifications) GetForReceiver(ctx context.Context, id, receiverID int64) (*models.Notification, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, n := range s.m.notifications {
+		if n.ID == id && n.Receiver == receiverID {
+			n.Sender = copyInt64(n.Sender)
+			n.FloorID = copyInt64(n.FloorID)
+			n.UnitID = copyInt64(n.UnitID)
+			return &n, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryNotifications) ListForReceiver(ctx context.Context, receiverID int64) ([]models.Notification, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var notifications []models.Notification
+	for i := len(s.m.notifications) - 1; i >= 0; i-- {
+		n := s.m.notifications[i]
+		if n.Receiver != receiverID {
+			continue
+		}
+		floorName := ""
+		if n.FloorID != nil {
+			fi := s.m.floorIndex(*n.FloorID)
+			if fi < 0 {
+				continue
+			}
+			floorName = s.m.floors[fi].Name
+		}
+		unitName := ""
+		if n.UnitID != nil {
+			for _, u := range s.m.units {
+				if u.ID == *n.UnitID {
+					unitName = u.Name
+				}
+			}
+		}
+		for _, p := range s.m.properties {
+			if p.ID == n.PropertyID {
+				n.PropertyName = p.Name
+				n.FloorName = floorName
+				n.UnitName = unitName
+				n.Sender = copyInt64(n.Sender)
+				n.FloorID = copyInt64(n.FloorID)
+				n.UnitID = copyInt64(n.UnitID)
+				notifications = append(notifications, n)
+				break
+			}
+		}
+	}
+	return notifications, nil
+}
+
+func (s *memoryNotifications) HasPendingForUnit(ctx context.Context, unitID int64) (bool, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	return s.m.pendingNotification(unitID) != nil, nil
+}
+
+func (s *memoryNotifications) HasPendingInvite(ctx context.Context, propertyID, receiverID int64) (bool, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, n := range s.m.notifications {
+		if n.PropertyID == propertyID && n.Receiver == receiverID && n.Role != "" && n.Status == models.NotificationPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryNotifications) ListPendingInvites(ctx context.Context, propertyID int64) ([]models.Notification, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var invites []models.Notification
+	for i := len(s.m.notifications) - 1; i >= 0; i-- {
+		n := s.m.notifications[i]
+		if n.PropertyID == propertyID && n.Role != "" && n.Status == models.NotificationPending {
+			n.Sender = copyInt64(n.Sender)
+			n.FloorID = copyInt64(n.FloorID)
+			invites = append(invites, n)
+		}
+	}
+	return invites, nil
+}
+
+func (s *memoryNotifications) Answer(ctx context.Context, id int64, status string, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.notifications {
+		n := &s.m.notifications[i]
+		if n.ID != id {
+			continue
+		}
+		if n.Status != models.NotificationPending {
+			return ErrConflict
+		}
+		n.Status = status
+		n.UpdatedAt = timestamp()
+		n.UpdatedBy = updatedBy
+		return nil
+	}
+	return ErrConflict
+}
+
+func (s *memoryNotifications) DeletePending(ctx context.Context, id, senderID int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i, n := range s.m.notifications {
+		if n.ID == id && n.Sender != nil && *n.Sender == senderID && n.Status == models.NotificationPending {
+			s.m.notifications = append(s.m.notifications[:i], s.m.notifications[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memorySessions struct{ m *memoryDB }
+
+func (s *memorySessions) Create(ctx context.Context, session *models.Session, expiresAt time.Time) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	session.ID = id
+	session.ExpiresAt = formatTime(expiresAt)
+	session.CreatedAt = timestamp()
+	session.UpdatedAt = session.CreatedAt
+	session.LastSeenAt = session.CreatedAt
+	s.m.sessions = append(s.m.sessions, memorySession{Session: *session})
+	return nil
+}
+
+// activeSession returns the index of the first active session matching
+// match, -1 if there is none
+func (m *memoryDB) activeSession(match func(models.Session) bool) int {
+	now := timestamp()
+	for i, session := range m.sessions {
+		if !session.revoked && session.ExpiresAt > now && match(session.Session) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memorySessions) GetActive(ctx context.Context, id int64) (*models.Session, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.activeSession(func(session models.Session) bool { return session.ID == id })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	session := s.m.sessions[i].Session
+	return &session, nil
+}
+
+func (s *memorySessions) GetActiveByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.m.activeSession(func(session models.Session) bool {
+		return session.RefreshHash == hash || session.PreviousRefreshHash == hash
+	})
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	session := s.m.sessions[i].Session
+	return &session, nil
+}
+
+func (s *memorySessions) Rotate(ctx context.Context, id int64, oldHash, newHash string, expiresAt time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.activeSession(func(session models.Session) bool {
+		return session.ID == id && session.RefreshHash == oldHash
+	})
+	if i < 0 {
+		return ErrConflict
+	}
+	session := &s.m.sessions[i]
+	session.PreviousRefreshHash = oldHash
+	session.RefreshHash = newHash
+	session.ExpiresAt = formatTime(expiresAt)
+	session.UpdatedAt = timestamp()
+	return nil
+}
+
+func (s *memorySessions) Revoke(ctx context.Context, id, userID int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.m.activeSession(func(session models.Session) bool {
+		return session.ID == id && session.UserID == userID
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.m.sessions[i].revoked = true
+	s.m.sessions[i].UpdatedAt = timestamp()
+	return nil
+}
+
+func (s *memorySessions) RevokeOthers(ctx context.Context, userID, keepID int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.sessions {
+		session := &s.m.sessions[i]
+		if session.UserID == userID && session.ID != keepID && !session.revoked {
+			session.revoked = true
+			session.UpdatedAt = timestamp()
+		}
+	}
+	return nil
+}
+
+func (s *memorySessions) RevokeAll(ctx context.Context, userID int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.sessions {
+		if s.m.sessions[i].UserID == userID && !s.m.sessions[i].revoked {
+			s.m.sessions[i].revoked = true
+			s.m.sessions[i].UpdatedAt = timestamp()
+		}
+	}
+	return nil
+}
+
+func (s *memorySessions) ListActive(ctx context.Context, userID int64) ([]models.Session, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var sessions []models.Session
+	now := timestamp()
+	for _, session := range s.m.sessions {
+		if session.UserID == userID && !session.revoked && session.ExpiresAt > now {
+			sessions = append(sessions, session.Session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if sessions[i].LastSeenAt != sessions[j].LastSeenAt {
+			return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (s *memorySessions) Touch(ctx context.Context, id int64, ip string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	now := time.Now()
+	stale := formatTime(now.Add(-SessionTouchInterval))
+	for i := range s.m.sessions {
+		session := &s.m.sessions[i]
+		if session.ID == id && session.LastSeenAt < stale {
+			session.LastSeenAt = formatTime(now)
+			session.IPAddress = ip
+		}
+	}
+	return nil
+}
+
+type memoryOTPs struct{ m *memoryDB }
+
+func (s *memoryOTPs) Create(ctx context.Context, o *models.OTP, expiresAt time.Time) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	o.ID = id
+	o.Attempts = 0
+	o.ExpiresAt = formatTime(expiresAt)
+	o.CreatedAt = timestamp()
+	s.m.otps = append(s.m.otps, memoryOTP{OTP: *o})
+	return nil
+}
+
+func (s *memoryOTPs) GetActive(ctx context.Context, phone, purpose string) (*models.OTP, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	now := timestamp()
+	for i := len(s.m.otps) - 1; i >= 0; i-- {
+		o := s.m.otps[i]
+		if o.PhoneNumber == phone && o.Purpose == purpose && !o.consumed && o.ExpiresAt > now {
+			return &o.OTP, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryOTPs) CountSince(ctx context.Context, phone, purpose string, since time.Time) (int, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	after := formatTime(since)
+	n := 0
+	for _, o := range s.m.otps {
+		if o.PhoneNumber == phone && o.Purpose == purpose && o.CreatedAt > after {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryOTPs) Attempt(ctx context.Context, id int64, maxAttempts int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.otps {
+		o := &s.m.otps[i]
+		if o.ID == id && o.Attempts < maxAttempts && !o.consumed {
+			o.Attempts++
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+func (s *memoryOTPs) Consume(ctx context.Context, id int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.otps {
+		o := &s.m.otps[i]
+		if o.ID == id && !o.consumed {
+			o.consumed = true
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+type memoryLoginAttempts struct{ m *memoryDB }
+
+func (s *memoryLoginAttempts) index(key string) int {
+	for i := range s.m.loginAttempts {
+		if s.m.loginAttempts[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryLoginAttempts) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	i := s.index(key)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	a := s.m.loginAttempts[i]
+	return &a, nil
+}
+
+func (s *memoryLoginAttempts) Attempt(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (*models.LoginAttempt, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.index(key)
+	if i < 0 {
+		s.m.loginAttempts = append(s.m.loginAttempts, models.LoginAttempt{Key: key})
+		i = len(s.m.loginAttempts) - 1
+	}
+	a := &s.m.loginAttempts[i]
+	if a.BlockedUntil.After(now) {
+		result := *a
+		return &result, ErrConflict
+	}
+	if a.LastFailureAt.Before(now.Add(-resetAfter)) || a.Locked {
+		a.Failures = 0
+	}
+	a.Locked = false
+	a.Failures++
+	a.LastFailureAt = now
+	result := *a
+	return &result, nil
+}
+
+func (s *memoryLoginAttempts) Forgive(ctx context.Context, key string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if i := s.index(key); i >= 0 && s.m.loginAttempts[i].Failures > 0 {
+		s.m.loginAttempts[i].Failures--
+	}
+	return nil
+}
+
+func (s *memoryLoginAttempts) Delay(ctx context.Context, key string, until time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if i := s.index(key); i >= 0 && !s.m.loginAttempts[i].Locked {
+		s.m.loginAttempts[i].BlockedUntil = until
+	}
+	return nil
+}
+
+func (s *memoryLoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.index(key)
+	if i < 0 || s.m.loginAttempts[i].Locked {
+		return ErrConflict
+	}
+	s.m.loginAttempts[i].BlockedUntil = until
+	s.m.loginAttempts[i].Locked = true
+	return nil
+}
+
+func (s *memoryLoginAttempts) Clear(ctx context.Context, key string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	i := s.index(key)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.m.loginAttempts = append(s.m.loginAttempts[:i:i], s.m.loginAttempts[i+1:]...)
+	return nil
+}
+
+type memoryTransfers struct{ m *memoryDB }
+
+func (s *memoryTransfers) Create(ctx context.Context, t *models.Transfer) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	t.ID = id
+	t.CreatedAt = timestamp()
+	t.UpdatedAt = t.CreatedAt
+	t.UpdatedBy = t.CreatedBy
+	s.m.transfers = append(s.m.transfers, *t)
+	return nil
+}
+
+func (s *memoryTransfers) GetByNotification(ctx context.Context, notificationID int64) (*models.Transfer, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, t := range s.m.transfers {
+		if t.NotificationID == notificationID {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryTransfers) HasPending(ctx context.Context, propertyID int64) (bool, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, t := range s.m.transfers {
+		if t.PropertyID == propertyID && t.Status == models.TransferPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTransfers) SetStatus(ctx context.Context, id int64, status string, updatedBy int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	for i := range s.m.transfers {
+		t := &s.m.transfers[i]
+		if t.ID != id {
+			continue
+		}
+		if t.Status != models.TransferPending {
+			return ErrConflict
+		}
+		t.Status = status
+		t.UpdatedAt = timestamp()
+		t.UpdatedBy = updatedBy
+		return nil
+	}
+	return ErrConflict
+}
+
+func (s *memoryTransfers) ListForProperty(ctx context.Context, propertyID int64) ([]models.Transfer, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var transfers []models.Transfer
+	for i := len(s.m.transfers) - 1; i >= 0; i-- {
+		if s.m.transfers[i].PropertyID == propertyID {
+			transfers = append(transfers, s.m.transfers[i])
+		}
+	}
+	return transfers, nil
+}
+
+type memoryAudit struct{ m *memoryDB }
+
+func (s *memoryAudit) Record(ctx context.Context, e *models.AuditEntry) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	e.ID = id
+	e.CreatedAt = timestamp()
+	s.m.audit = append(s.m.audit, *e)
+	return nil
+}
+
+type memoryIDs struct{ m *memoryDB }
+
+func (s *memoryIDs) Max(ctx context.Context) (int64, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var ids []int64
+	for _, r := range s.m.users {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.properties {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.floors {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.units {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.tenancies {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.leases {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.payments {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.notifications {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.sessions {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.otps {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.transfers {
+		ids = append(ids, r.ID)
+	}
+	for _, r := range s.m.audit {
+		ids = append(ids, r.ID)
+	}
+
+	var max int64
+	for _, id := range ids {
+		if id > max {
+			max = id
+		}
+	}
+	return max, nil
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-rent/models"
+	"testing"
+)
+
+func TestMemoryWithTxRollsBack(t *testing.T) {
+	s := NewMemory()
+	ctx := context.Background()
+	owner := models.User{Name: "Owner", PhoneNumber: "+880 1711-000001", Verified: true}
+	if err := s.Users.Create(ctx, &owner); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	failed := errors.New("failed")
+	var property models.Property
+	err := s.WithTx(ctx, func(tx *Store) error {
+		property = models.Property{Name: "Rose Villa", CreatedBy: owner.ID}
+		if err := tx.Properties.Create(ctx, &property); err != nil {
+			return err
+		}
+		if err := tx.Properties.AddMember(ctx, property.ID, owner.ID, models.RoleOwner, owner.ID); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTx returned %v, want the error of fn", err)
+	}
+	if _, err := s.Properties.GetForUser(ctx, property.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("property of a failed unit of work: got %v, want ErrNotFound", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("WithTx swallowed the panic")
+			}
+		}()
+		s.WithTx(ctx, func(tx *Store) error {
+			property = models.Property{Name: "Lake View", CreatedBy: owner.ID}
+			tx.Properties.Create(ctx, &property)
+			tx.Properties.AddMember(ctx, property.ID, owner.ID, models.RoleOwner, owner.ID)
+			panic("boom")
+		})
+	}()
+	if managed, _ := s.Properties.ListManaged(ctx, owner.ID); len(managed) != 0 {
+		t.Errorf("properties of a panicking unit of work were kept: %+v", managed)
+	}
+
+	err = s.WithTx(ctx, func(tx *Store) error {
+		property = models.Property{Name: "Kept", CreatedBy: owner.ID}
+		if err := tx.Properties.Create(ctx, &property); err != nil {
+			return err
+		}
+		// A nested WithTx joins the running unit of work
+		return tx.WithTx(ctx, func(tx *Store) error {
+			return tx.Properties.AddMember(ctx, property.ID, owner.ID, models.RoleOwner, owner.ID)
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if _, err := s.Properties.GetForUser(ctx, property.ID, owner.ID); err != nil {
+		t.Errorf("property of a committed unit of work: %v", err)
+	}
+}
+
+func TestMemoryErrors(t *testing.T) {
+	s := NewMemory()
+	ctx := context.Background()
+	user := models.User{Name: "A", PhoneNumber: "+880 1711-000002"}
+	if err := s.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	if err := s.Users.Create(ctx, &models.User{Name: "B", PhoneNumber: user.PhoneNumber}); !errors.Is(err, ErrConflict) {
+		t.Errorf("second user with the phone number: got %v, want ErrConflict", err)
+	}
+	if err := s.Users.Verify(ctx, user.ID); err != nil {
+		t.Fatalf("verifying: %v", err)
+	}
+	if err := s.Users.Verify(ctx, user.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("verifying twice: got %v, want ErrConflict", err)
+	}
+	if _, err := s.Users.Get(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown user: got %v, want ErrNotFound", err)
+	}
+
+	property := models.Property{Name: "Rose Villa", CreatedBy: user.ID}
+	if err := s.Properties.Create(ctx, &property); err != nil {
+		t.Fatalf("creating property: %v", err)
+	}
+	if _, err := s.Floors.Get(ctx, property.ID, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown floor: got %v, want ErrNotFound", err)
+	}
+	floor := models.Floor{PropertyID: property.ID, Name: "1A"}
+	if err := s.Floors.Create(ctx, &floor); err != nil {
+		t.Fatalf("creating floor: %v", err)
+	}
+	unit := models.Unit{PropertyID: property.ID, FloorID: floor.ID, Name: "1A"}
+	if err := s.Units.Create(ctx, &unit); err != nil {
+		t.Fatalf("creating unit: %v", err)
+	}
+	if err := s.Units.AssignTenant(ctx, unit.ID, user.ID, user.ID); err != nil {
+		t.Fatalf("assigning tenant: %v", err)
+	}
+	if err := s.Units.AssignTenant(ctx, unit.ID, user.ID, user.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("assigning a tenant to an occupied unit: got %v, want ErrConflict", err)
+	}
+	if err := s.Floors.Archive(ctx, property.ID, floor.ID, user.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("archiving an occupied floor: got %v, want ErrConflict", err)
+	}
+	if err := s.Units.RemoveTenant(ctx, property.ID, floor.ID, unit.ID, user.ID); err != nil {
+		t.Fatalf("removing tenant: %v", err)
+	}
+	if err := s.Units.RemoveTenant(ctx, property.ID, floor.ID, unit.ID, user.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removing the tenant of an empty unit: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,1814 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-rent/models"
+	"go-rent/utils"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// querier is the subset of *sql.DB used by the MySQL stores
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewMySQL returns a Store backed by the given MySQL connection pool
+func NewMySQL(db *sql.DB) *Store {
+	s := newMySQL(db)
+	s.tx = mysqlTx{db}
+	return s
+}
+
+// newMySQL builds the repositories on top of a pool or a transaction
+func newMySQL(db querier) *Store {
+	return &Store{
+		Users:         &mysqlUsers{db},
+		Properties:    &mysqlProperties{db},
+		Floors:        &mysqlFloors{db},
+		Units:         &mysqlUnits{db},
+		Tenancies:     &mysqlTenancies{db},
+		Leases:        &mysqlLeases{db},
+		Payments:      &mysqlPayments{db},
+		Notifications: &mysqlNotifications{db},
+		Sessions:      &mysqlSessions{db},
+		OTPs:          &mysqlOTPs{db},
+		LoginAttempts: &mysqlLoginAttempts{db},
+		Transfers:     &mysqlTransfers{db},
+		Audit:         &mysqlAudit{db},
+		IDs:           &mysqlIDs{db},
+	}
+}
+
+// nullable converts the zero value to NULL
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullableBool converts a nil *bool to NULL
+func nullableBool(b *bool) interface{} {
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
+// duplicate maps a unique key violation to ErrConflict
+func duplicate(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrConflict
+	}
+	return err
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type mysqlUsers struct{ db querier }
+
+func (s *mysqlUsers) Create(ctx context.Context, u *models.User) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	if u.CreatedBy == 0 {
+		u.CreatedBy = id
+	}
+	if u.UpdatedBy == 0 {
+		u.UpdatedBy = u.CreatedBy
+	}
+	now := timestamp()
+	var verifiedAt interface{}
+	if u.Verified {
+		verifiedAt = now
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO user (id, name, phone_number, email, NID, password, manager, verified_at, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, u.Name, u.PhoneNumber, nullable(u.Email), nullable(u.NID), u.Password, nullableBool(u.Manager),
+		verifiedAt, now, u.CreatedBy, now, u.UpdatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	u.ID = id
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	return nil
+}
+
+func (s *mysqlUsers) Get(ctx context.Context, id int64) (*models.User, error) {
+	var u models.User
+	var manager sql.NullBool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, name, phone_number, COALESCE(email, ''), COALESCE(NID, ''), manager, verified_at IS NOT NULL,
+			created_at, updated_at, updated_by
+		FROM user WHERE id = ?`, id,
+	).Scan(&u.ID, &u.Name, &u.PhoneNumber, &u.Email, &u.NID, &manager, &u.Verified, &u.CreatedAt, &u.UpdatedAt, &u.UpdatedBy)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if manager.Valid {
+		u.Manager = &manager.Bool
+	}
+	return &u, nil
+}
+
+func (s *mysqlUsers) UpdateProfile(ctx context.Context, u *models.User) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user
+		SET name = ?, email = ?, NID = ?, manager = ?, updated_at = ?, updated_by = ?
+		WHERE id = ?`,
+		u.Name, nullable(u.Email), nullable(u.NID), nullableBool(u.Manager), now, u.UpdatedBy, u.ID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	u.UpdatedAt = now
+	return nil
+}
+
+func (s *mysqlUsers) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	var u models.User
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, name, phone_number, password, verified_at IS NOT NULL FROM user WHERE phone_number = ?`, phone,
+	).Scan(&u.ID, &u.Name, &u.PhoneNumber, &u.Password, &u.Verified)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &u, nil
+}
+
+func (s *mysqlUsers) ListWithPhone(ctx context.Context) ([]models.User, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, phone_number
+		FROM user
+		WHERE phone_number IS NOT NULL AND phone_number != '' AND verified_at IS NOT NULL
+		ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.PhoneNumber); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *mysqlUsers) UpdateUnverified(ctx context.Context, u *models.User) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user
+		SET name = ?, email = ?, NID = ?, password = ?, manager = ?, updated_at = ?
+		WHERE id = ? AND verified_at IS NULL`,
+		u.Name, nullable(u.Email), nullable(u.NID), u.Password, nullableBool(u.Manager), now, u.ID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	u.UpdatedAt = now
+	return nil
+}
+
+func (s *mysqlUsers) Verify(ctx context.Context, id int64) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user
+		SET verified_at = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND verified_at IS NULL`,
+		now, now, id, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlUsers) SetPassword(ctx context.Context, id int64, hash string) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user SET password = ?, updated_at = ?, updated_by = ? WHERE id = ?`,
+		hash, now, id, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlUsers) SetPhone(ctx context.Context, id int64, phone string) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user SET phone_number = ?, updated_at = ?, updated_by = ? WHERE id = ?`,
+		phone, now, id, id)
+	if err != nil {
+		return duplicate(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlUsers) DeleteUnverified(ctx context.Context, phone string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM user WHERE phone_number = ? AND verified_at IS NULL`, phone)
+	return err
+}
+
+type mysqlProperties struct{ db querier }
+
+func (s *mysqlProperties) Create(ctx context.Context, p *models.Property) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO property (id, name, address, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, p.Name, p.Address, now, p.CreatedBy, now, p.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	p.UpdatedBy = p.CreatedBy
+	return nil
+}
+
+func (s *mysqlProperties) Update(ctx context.Context, p *models.Property) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE property
+		SET name = ?, address = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND deleted_at IS NULL`,
+		p.Name, p.Address, now, p.UpdatedBy, p.ID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// Delete checks for tenants in the same statement, so that a tenant
+// request accepted meanwhile can't slip past it
+func (s *mysqlProperties) Delete(ctx context.Context, id, deletedBy int64) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE property
+		SET deleted_at = ?, deleted_by = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND deleted_at IS NULL
+			AND id NOT IN (
+				SELECT pid FROM unit WHERE tenant IS NOT NULL
+			) AND id NOT IN (
+				SELECT pid FROM notification WHERE fid IS NOT NULL AND status = 'pending'
+			)`,
+		now, deletedBy, now, deletedBy, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	var exists bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM property WHERE id = ? AND deleted_at IS NULL
+		)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (s *mysqlProperties) AddMember(ctx context.Context, propertyID, userID int64, role string, createdBy int64) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO takes_care_of (id, uid, pid, role, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, userID, propertyID, role, now, createdBy, now, createdBy,
+	)
+	return duplicate(err)
+}
+
+func (s *mysqlProperties) Role(ctx context.Context, propertyID, userID int64) (string, error) {
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT t.role
+		FROM takes_care_of t
+		INNER JOIN property p ON p.id = t.pid
+		WHERE t.pid = ? AND t.uid = ? AND p.deleted_at IS NULL`, propertyID, userID).Scan(&role)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return role, nil
+}
+
+func (s *mysqlProperties) ListMembers(ctx context.Context, propertyID int64) ([]models.Member, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.uid, u.name, u.phone_number, t.role, t.created_at, t.created_by
+		FROM takes_care_of t
+		INNER JOIN user u ON u.id = t.uid
+		WHERE t.pid = ?
+		ORDER BY t.created_at, t.id`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.Member
+	for rows.Next() {
+		var m models.Member
+		if err := rows.Scan(&m.UserID, &m.Name, &m.PhoneNumber, &m.Role, &m.CreatedAt, &m.CreatedBy); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// RemoveMember counts the owners in the same statement as the delete, so
+// that two owners removing each other can't leave the property without one.
+// MySQL doesn't let a DELETE read its own table in a subquery unless the
+// subquery is wrapped in a derived table.
+func (s *mysqlProperties) RemoveMember(ctx context.Context, propertyID, userID int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM takes_care_of
+		WHERE pid = ? AND uid = ? AND (
+			role <> ? OR (
+				SELECT COUNT(*) FROM (
+					SELECT uid FROM takes_care_of WHERE pid = ? AND role = ?
+				) owners
+			) > 1
+		)`,
+		propertyID, userID, models.RoleOwner, propertyID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := s.Role(ctx, propertyID, userID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (s *mysqlProperties) SetRole(ctx context.Context, propertyID, userID int64, role string, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE takes_care_of
+		SET role = ?, updated_at = ?, updated_by = ?
+		WHERE pid = ? AND uid = ?`,
+		role, timestamp(), updatedBy, propertyID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Setting the role a member already has changes nothing either
+		if _, err := s.Role(ctx, propertyID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *mysqlProperties) GetForUser(ctx context.Context, propertyID, userID int64) (*models.Property, error) {
+	var p models.Property
+	err := s.db.QueryRowContext(ctx, `
+		SELECT p.id, p.name, p.address, p.created_at
+		FROM property p
+		WHERE p.id = ? AND p.deleted_at IS NULL AND (
+			EXISTS (
+				SELECT 1 FROM takes_care_of t
+				WHERE t.pid = p.id AND t.uid = ?
+			) OR EXISTS (
+				SELECT 1 FROM unit u
+				WHERE u.pid = p.id AND u.tenant = ?
+			)
+		)`, propertyID, userID, userID).Scan(&p.ID, &p.Name, &p.Address, &p.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+func (s *mysqlProperties) ListManaged(ctx context.Context, userID int64) ([]models.Property, error) {
+	return s.list(ctx, `
+		SELECT p.id, p.name, p.address, p.created_at, t.role
+		FROM property p
+		INNER JOIN takes_care_of t ON p.id = t.pid
+		WHERE t.uid = ? AND p.deleted_at IS NULL
+		ORDER BY p.created_at DESC`, userID)
+}
+
+func (s *mysqlProperties) ListTenanted(ctx context.Context, userID int64) ([]models.Property, error) {
+	return s.list(ctx, `
+		SELECT DISTINCT p.id, p.name, p.address, p.created_at, ''
+		FROM property p
+		INNER JOIN unit u ON p.id = u.pid
+		WHERE u.tenant = ? AND p.deleted_at IS NULL
+		ORDER BY p.created_at DESC`, userID)
+}
+
+func (s *mysqlProperties) list(ctx context.Context, query string, args ...interface{}) ([]models.Property, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var properties []models.Property
+	for rows.Next() {
+		var p models.Property
+		if err := rows.Scan(&p.ID, &p.Name, &p.Address, &p.CreatedAt, &p.Role); err != nil {
+			return nil, err
+		}
+		properties = append(properties, p)
+	}
+	return properties, rows.Err()
+}
+
+type mysqlFloors struct{ db querier }
+
+func (s *mysqlFloors) Create(ctx context.Context, f *models.Floor) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO floor (id, name, created_at, created_by, updated_at, updated_by, pid)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, f.Name, now, f.CreatedBy, now, f.CreatedBy, f.PropertyID,
+	)
+	if err != nil {
+		return err
+	}
+	f.ID = id
+	f.CreatedAt = now
+	f.UpdatedAt = now
+	f.UpdatedBy = f.CreatedBy
+	return nil
+}
+
+func (s *mysqlFloors) Get(ctx context.Context, propertyID, floorID int64) (*models.Floor, error) {
+	var f models.Floor
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, pid, name, created_at
+		FROM floor
+		WHERE id = ? AND pid = ? AND archived_at IS NULL`, floorID, propertyID).Scan(
+		&f.ID, &f.PropertyID, &f.Name, &f.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &f, nil
+}
+
+func (s *mysqlFloors) ListByProperty(ctx context.Context, propertyID int64) ([]models.Floor, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pid, name, created_at
+		FROM floor
+		WHERE pid = ? AND archived_at IS NULL
+		ORDER BY created_at DESC`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var floors []models.Floor
+	for rows.Next() {
+		var f models.Floor
+		if err := rows.Scan(&f.ID, &f.PropertyID, &f.Name, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		floors = append(floors, f)
+	}
+	return floors, rows.Err()
+}
+
+func (s *mysqlFloors) Update(ctx context.Context, f *models.Floor) error {
+	now := timestamp()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE floor
+		SET name = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND pid = ? AND archived_at IS NULL`,
+		f.Name, now, f.UpdatedBy, f.ID, f.PropertyID)
+	if err != nil {
+		return err
+	}
+	f.UpdatedAt = now
+	return nil
+}
+
+// ListNames locks the floor rows of the property, and the gaps next to
+// them, so a concurrent insert for the property waits for the transaction
+func (s *mysqlFloors) ListNames(ctx context.Context, propertyID int64) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name FROM floor
+		WHERE pid = ? AND archived_at IS NULL
+		FOR UPDATE`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *mysqlFloors) ListArchived(ctx context.Context, propertyID int64) ([]models.Floor, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pid, name, created_at, archived_at, archived_by
+		FROM floor
+		WHERE pid = ? AND archived_at IS NOT NULL
+		ORDER BY archived_at DESC`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var floors []models.Floor
+	for rows.Next() {
+		var f models.Floor
+		if err := rows.Scan(&f.ID, &f.PropertyID, &f.Name, &f.CreatedAt, &f.ArchivedAt, &f.ArchivedBy); err != nil {
+			return nil, err
+		}
+		floors = append(floors, f)
+	}
+	return floors, rows.Err()
+}
+
+// Archive checks for tenants and pending requests in the same statement,
+// so that a request accepted meanwhile can't slip past it
+func (s *mysqlFloors) Archive(ctx context.Context, propertyID, floorID, archivedBy int64) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE floor
+		SET archived_at = ?, archived_by = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND pid = ? AND archived_at IS NULL
+			AND id NOT IN (
+				SELECT fid FROM unit WHERE tenant IS NOT NULL
+			) AND id NOT IN (
+				SELECT fid FROM notification WHERE fid IS NOT NULL AND status = 'pending'
+			)`,
+		now, archivedBy, now, archivedBy, floorID, propertyID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	var exists bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM floor WHERE id = ? AND pid = ? AND archived_at IS NULL
+		)`, floorID, propertyID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (s *mysqlFloors) Restore(ctx context.Context, propertyID, floorID, restoredBy int64) error {
+	var name string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT name FROM floor
+		WHERE id = ? AND pid = ? AND archived_at IS NOT NULL
+		FOR UPDATE`, floorID, propertyID).Scan(&name)
+	if err != nil {
+		return notFound(err)
+	}
+	active, err := s.ListNames(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if strings.EqualFold(other, name) {
+			return ErrConflict
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE floor
+		SET archived_at = NULL, archived_by = NULL, updated_at = ?, updated_by = ?
+		WHERE id = ? AND pid = ?`,
+		timestamp(), restoredBy, floorID, propertyID)
+	return err
+}
+
+type mysqlUnits struct{ db querier }
+
+func (s *mysqlUnits) Create(ctx context.Context, u *models.Unit) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO unit (id, fid, pid, name, rent, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, u.FloorID, u.PropertyID, u.Name, u.Rent, now, u.CreatedBy, now, u.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	u.ID = id
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	u.UpdatedBy = u.CreatedBy
+	return nil
+}
+
+func (s *mysqlUnits) Get(ctx context.Context, propertyID, floorID, unitID int64) (*models.Unit, error) {
+	var u models.Unit
+	var tenant sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.id, u.fid, u.pid, u.name, u.rent, u.created_at, u.tenant
+		FROM unit u
+		INNER JOIN floor f ON f.id = u.fid
+		WHERE u.id = ? AND u.fid = ? AND u.pid = ? AND f.archived_at IS NULL`,
+		unitID, floorID, propertyID).Scan(
+		&u.ID, &u.FloorID, &u.PropertyID, &u.Name, &u.Rent, &u.CreatedAt, &tenant)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if tenant.Valid {
+		u.Tenant = &tenant.Int64
+	}
+	return &u, nil
+}
+
+func (s *mysqlUnits) ListByProperty(ctx context.Context, propertyID int64) ([]models.Unit, error) {
+	return s.list(ctx, `
+		SELECT u.id, u.fid, u.pid, u.name, u.rent, u.created_at, u.tenant,
+		       (
+		           SELECT n.id
+		           FROM notification n
+		           WHERE n.unit_id = u.id AND n.status = 'pending'
+		           LIMIT 1
+		       ) as notification_id
+		FROM unit u
+		INNER JOIN floor f ON f.id = u.fid
+		WHERE u.pid = ? AND f.archived_at IS NULL
+		ORDER BY u.created_at, u.id`, propertyID)
+}
+
+func (s *mysqlUnits) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Unit, error) {
+	return s.list(ctx, `
+		SELECT u.id, u.fid, u.pid, u.name, u.rent, u.created_at, u.tenant,
+		       (
+		           SELECT n.id
+		           FROM notification n
+		           WHERE n.unit_id = u.id AND n.status = 'pending'
+		           LIMIT 1
+		       ) as notification_id
+		FROM unit u
+		INNER JOIN floor f ON f.id = u.fid
+		WHERE u.fid = ? AND u.pid = ? AND f.archived_at IS NULL
+		ORDER BY u.created_at, u.id`, floorID, propertyID)
+}
+
+func (s *mysqlUnits) list(ctx context.Context, query string, args ...interface{}) ([]models.Unit, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []models.Unit
+	for rows.Next() {
+		var u models.Unit
+		var tenant, notificationID sql.NullInt64
+		if err := rows.Scan(&u.ID, &u.FloorID, &u.PropertyID, &u.Name, &u.Rent, &u.CreatedAt, &tenant, &notificationID); err != nil {
+			return nil, err
+		}
+		if tenant.Valid {
+			u.Tenant = &tenant.Int64
+		}
+		if notificationID.Valid {
+			u.PendingNotificationID = &notificationID.Int64
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+func (s *mysqlUnits) Update(ctx context.Context, u *models.Unit) error {
+	now := timestamp()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE unit
+		SET name = ?, rent = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND fid = ? AND pid = ?`,
+		u.Name, u.Rent, now, u.UpdatedBy, u.ID, u.FloorID, u.PropertyID)
+	if err != nil {
+		return err
+	}
+	u.UpdatedAt = now
+	return nil
+}
+
+// AssignTenant only fills units of floors that are not archived
+func (s *mysqlUnits) AssignTenant(ctx context.Context, unitID, tenantID, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE unit
+		SET tenant = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND tenant IS NULL
+			AND fid NOT IN (SELECT id FROM floor WHERE archived_at IS NOT NULL)`,
+		tenantID, timestamp(), updatedBy, unitID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlUnits) RemoveTenant(ctx context.Context, propertyID, floorID, unitID, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE unit
+		SET tenant = NULL, updated_at = ?, updated_by = ?
+		WHERE id = ? AND fid = ? AND pid = ? AND tenant IS NOT NULL`,
+		timestamp(), updatedBy, unitID, floorID, propertyID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlUnits) ListOccupied(ctx context.Context) ([]models.Unit, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, fid, pid, name, rent, created_at, tenant
+		FROM unit
+		WHERE tenant IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []models.Unit
+	for rows.Next() {
+		var u models.Unit
+		var tenant int64
+		if err := rows.Scan(&u.ID, &u.FloorID, &u.PropertyID, &u.Name, &u.Rent, &u.CreatedAt, &tenant); err != nil {
+			return nil, err
+		}
+		u.Tenant = &tenant
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// dateLayout is how DATE columns are passed to and from the models
+const dateLayout = "2006-01-02"
+
+type mysqlTenancies struct{ db querier }
+
+func (s *mysqlTenancies) Create(ctx context.Context, t *models.Tenancy) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO tenancy (
+			id, unit_id, fid, pid, tenant, start_date, end_date, rent, status,
+			created_at, created_by, updated_at, updated_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, t.UnitID, t.FloorID, t.PropertyID, t.TenantID, t.StartDate, nullable(t.EndDate), t.Rent, t.Status,
+		now, t.CreatedBy, now, t.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.UpdatedBy = t.CreatedBy
+	return nil
+}
+
+const tenancyColumns = `t.id, t.unit_id, t.fid, t.pid, t.tenant, t.start_date, t.end_date, t.rent, t.status,
+	t.created_at, t.created_by, t.updated_at, t.updated_by`
+
+// scanTenancy scans tenancyColumns followed by the extra columns
+func scanTenancy(row interface{ Scan(...interface{}) error }, t *models.Tenancy, extra ...interface{}) error {
+	var start time.Time
+	var end sql.NullTime
+	dest := []interface{}{&t.ID, &t.UnitID, &t.FloorID, &t.PropertyID, &t.TenantID, &start, &end, &t.Rent, &t.Status,
+		&t.CreatedAt, &t.CreatedBy, &t.UpdatedAt, &t.UpdatedBy}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	t.StartDate = start.Format(dateLayout)
+	if end.Valid {
+		t.EndDate = end.Time.Format(dateLayout)
+	}
+	return nil
+}
+
+func (s *mysqlTenancies) Get(ctx context.Context, propertyID, floorID, tenancyID int64) (*models.Tenancy, error) {
+	var t models.Tenancy
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+tenancyColumns+`
+		FROM tenancy t
+		WHERE t.id = ? AND t.pid = ? AND t.fid = ?`, tenancyID, propertyID, floorID)
+	if err := scanTenancy(row, &t); err != nil {
+		return nil, notFound(err)
+	}
+	return &t, nil
+}
+
+func (s *mysqlTenancies) End(ctx context.Context, unitID int64, endDate string, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE tenancy
+		SET end_date = ?, status = 'ended', updated_at = ?, updated_by = ?
+		WHERE unit_id = ? AND status = 'active'`,
+		endDate, timestamp(), updatedBy, unitID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlTenancies) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Tenancy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+tenancyColumns+`, u.name, usr.name
+		FROM tenancy t
+		INNER JOIN unit u ON u.id = t.unit_id
+		INNER JOIN user usr ON usr.id = t.tenant
+		WHERE t.pid = ? AND t.fid = ?
+		ORDER BY t.start_date DESC, t.created_at DESC, t.id DESC`, propertyID, floorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenancies []models.Tenancy
+	for rows.Next() {
+		var t models.Tenancy
+		if err := scanTenancy(rows, &t, &t.UnitName, &t.TenantName); err != nil {
+			return nil, err
+		}
+		tenancies = append(tenancies, t)
+	}
+	return tenancies, rows.Err()
+}
+
+type mysqlLeases struct{ db querier }
+
+func (s *mysqlLeases) Create(ctx context.Context, l *models.Lease) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO lease (
+			id, tenancy_id, unit_id, fid, pid, tenant, start_date, end_date,
+			rent, deposit, notice_days, billing_day, clauses,
+			created_at, created_by, updated_at, updated_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, l.TenancyID, l.UnitID, l.FloorID, l.PropertyID, l.TenantID, l.StartDate, l.EndDate,
+		l.Rent, l.Deposit, l.NoticeDays, l.BillingDay, nullable(l.Clauses),
+		now, l.CreatedBy, now, l.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	l.ID = id
+	l.CreatedAt = now
+	l.UpdatedAt = now
+	l.UpdatedBy = l.CreatedBy
+	return nil
+}
+
+const leaseColumns = `id, tenancy_id, unit_id, fid, pid, tenant, start_date, end_date,
+	rent, deposit, notice_days, billing_day, clauses, created_at, created_by, updated_at, updated_by`
+
+func scanLease(row interface{ Scan(...interface{}) error }, l *models.Lease) error {
+	var start, end time.Time
+	var clauses sql.NullString
+	err := row.Scan(&l.ID, &l.TenancyID, &l.UnitID, &l.FloorID, &l.PropertyID, &l.TenantID, &start, &end,
+		&l.Rent, &l.Deposit, &l.NoticeDays, &l.BillingDay, &clauses, &l.CreatedAt, &l.CreatedBy, &l.UpdatedAt, &l.UpdatedBy)
+	if err != nil {
+		return err
+	}
+	l.StartDate = start.Format(dateLayout)
+	l.EndDate = end.Format(dateLayout)
+	l.Clauses = clauses.String
+	return nil
+}
+
+func (s *mysqlLeases) Get(ctx context.Context, propertyID, floorID, leaseID int64) (*models.Lease, error) {
+	var l models.Lease
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+leaseColumns+`
+		FROM lease
+		WHERE id = ? AND pid = ? AND fid = ? AND deleted_at IS NULL`, leaseID, propertyID, floorID)
+	if err := scanLease(row, &l); err != nil {
+		return nil, notFound(err)
+	}
+	return &l, nil
+}
+
+func (s *mysqlLeases) ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Lease, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+leaseColumns+`
+		FROM lease
+		WHERE pid = ? AND fid = ? AND deleted_at IS NULL
+		ORDER BY start_date DESC, id DESC`, propertyID, floorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []models.Lease
+	for rows.Next() {
+		var l models.Lease
+		if err := scanLease(rows, &l); err != nil {
+			return nil, err
+		}
+		leases = append(leases, l)
+	}
+	return leases, rows.Err()
+}
+
+func (s *mysqlLeases) ListByTenant(ctx context.Context, propertyID, floorID, tenantID int64) ([]models.Lease, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+leaseColumns+`
+		FROM lease
+		WHERE pid = ? AND fid = ? AND tenant = ? AND deleted_at IS NULL
+		ORDER BY start_date DESC, id DESC`, propertyID, floorID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []models.Lease
+	for rows.Next() {
+		var l models.Lease
+		if err := scanLease(rows, &l); err != nil {
+			return nil, err
+		}
+		leases = append(leases, l)
+	}
+	return leases, rows.Err()
+}
+
+func (s *mysqlLeases) Update(ctx context.Context, l *models.Lease) error {
+	now := timestamp()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE lease
+		SET start_date = ?, end_date = ?, rent = ?, deposit = ?, notice_days = ?,
+			billing_day = ?, clauses = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND pid = ? AND fid = ? AND deleted_at IS NULL`,
+		l.StartDate, l.EndDate, l.Rent, l.Deposit, l.NoticeDays,
+		l.BillingDay, nullable(l.Clauses), now, l.UpdatedBy,
+		l.ID, l.PropertyID, l.FloorID)
+	if err != nil {
+		return err
+	}
+	l.UpdatedAt = now
+	return nil
+}
+
+func (s *mysqlLeases) Delete(ctx context.Context, propertyID, floorID, leaseID, deletedBy int64) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE lease
+		SET deleted_at = ?, deleted_by = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND pid = ? AND fid = ? AND deleted_at IS NULL`,
+		now, deletedBy, now, deletedBy, leaseID, propertyID, floorID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlLeases) Overlapping(ctx context.Context, unitID, excludeID int64, start, end string) (*models.Lease, error) {
+	var l models.Lease
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+leaseColumns+`
+		FROM lease
+		WHERE unit_id = ? AND id <> ? AND deleted_at IS NULL
+			AND start_date <= ? AND end_date >= ?
+		ORDER BY start_date
+		LIMIT 1
+		FOR UPDATE`, unitID, excludeID, end, start)
+	if err := scanLease(row, &l); err != nil {
+		return nil, notFound(err)
+	}
+	return &l, nil
+}
+
+type mysqlPayments struct{ db querier }
+
+func (s *mysqlPayments) Create(ctx context.Context, p *models.Payment) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO payment (
+			id, due_rent, due_electrictiy_bill, recieved_money,
+			full_payment, created_at, created_by, updated_at, updated_by,
+			fid, unit_id, uid
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, p.DueRent, p.DueElectricityBill, p.ReceivedMoney,
+		p.FullPayment, now, p.CreatedBy, now, p.CreatedBy,
+		p.FloorID, p.UnitID, p.TenantID,
+	)
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	p.UpdatedBy = p.CreatedBy
+	return nil
+}
+
+func (s *mysqlPayments) LatestForUnit(ctx context.Context, unitID int64) (*models.Payment, error) {
+	var p models.Payment
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, fid, unit_id, uid, due_rent, due_electrictiy_bill, recieved_money, full_payment, created_at
+		FROM payment
+		WHERE unit_id = ?
+		ORDER BY created_at DESC
+		LIMIT 1`, unitID).Scan(
+		&p.ID, &p.FloorID, &p.UnitID, &p.TenantID, &p.DueRent, &p.DueElectricityBill, &p.ReceivedMoney, &p.FullPayment, &p.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &p, nil
+}
+
+type mysqlNotifications struct{ db querier }
+
+func (s *mysqlNotifications) Create(ctx context.Context, n *models.Notification) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO notification (
+			id, message, sender, receiver, pid, fid, unit_id, role,
+			status, created_at, created_by, updated_at, updated_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, n.Message, n.Sender, n.Receiver, n.PropertyID, n.FloorID, n.UnitID, nullable(n.Role),
+		nullable(n.Status), now, n.CreatedBy, now, n.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	n.ID = id
+	n.CreatedAt = now
+	n.UpdatedAt = now
+	n.UpdatedBy = n.CreatedBy
+	return nil
+}
+
+func (s *mysqlNotifications) GetForReceiver(ctx context.Context, id, receiverID int64) (*models.Notification, error) {
+	var n models.Notification
+	var sender, floorID, unitID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, message, COALESCE(status, ''), fid, unit_id, COALESCE(role, ''), pid, sender, receiver, created_by
+		FROM notification
+		WHERE id = ? AND receiver = ?`, id, receiverID).Scan(
+		&n.ID, &n.Message, &n.Status, &floorID, &unitID, &n.Role, &n.PropertyID, &sender, &n.Receiver, &n.CreatedBy)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if sender.Valid {
+		n.Sender = &sender.Int64
+	}
+	if floorID.Valid {
+		n.FloorID = &floorID.Int64
+	}
+	if unitID.Valid {
+		n.UnitID = &unitID.Int64
+	}
+	return &n, nil
+}
+
+func (s *mysqlNotifications) ListForReceiver(ctx context.Context, receiverID int64) ([]models.Notification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			n.id, n.message, COALESCE(n.status, ''), COALESCE(n.role, ''), n.created_at,
+			p.id, p.name, f.id, COALESCE(f.name, ''), u.id, COALESCE(u.name, '')
+		FROM notification n
+		JOIN property p ON n.pid = p.id
+		LEFT JOIN floor f ON n.fid = f.id
+		LEFT JOIN unit u ON n.unit_id = u.id
+		WHERE n.receiver = ? AND (n.fid IS NULL OR f.id IS NOT NULL)
+		ORDER BY n.created_at DESC`, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		var floorID, unitID sql.NullInt64
+		if err := rows.Scan(
+			&n.ID, &n.Message, &n.Status, &n.Role, &n.CreatedAt,
+			&n.PropertyID, &n.PropertyName, &floorID, &n.FloorName, &unitID, &n.UnitName,
+		); err != nil {
+			return nil, err
+		}
+		n.Receiver = receiverID
+		if floorID.Valid {
+			n.FloorID = &floorID.Int64
+		}
+		if unitID.Valid {
+			n.UnitID = &unitID.Int64
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (s *mysqlNotifications) HasPendingForUnit(ctx context.Context, unitID int64) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM notification
+			WHERE unit_id = ? AND status = 'pending'
+		)`, unitID).Scan(&pending)
+	return pending, err
+}
+
+func (s *mysqlNotifications) HasPendingInvite(ctx context.Context, propertyID, receiverID int64) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM notification
+			WHERE pid = ? AND receiver = ? AND role IS NOT NULL AND status = 'pending'
+		)`, propertyID, receiverID).Scan(&pending)
+	return pending, err
+}
+
+func (s *mysqlNotifications) ListPendingInvites(ctx context.Context, propertyID int64) ([]models.Notification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, message, sender, receiver, role, status, created_at, created_by
+		FROM notification
+		WHERE pid = ? AND role IS NOT NULL AND status = 'pending'
+		ORDER BY created_at DESC`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		var sender sql.NullInt64
+		if err := rows.Scan(
+			&n.ID, &n.Message, &sender, &n.Receiver, &n.Role, &n.Status, &n.CreatedAt, &n.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		n.PropertyID = propertyID
+		if sender.Valid {
+			n.Sender = &sender.Int64
+		}
+		invites = append(invites, n)
+	}
+	return invites, rows.Err()
+}
+
+func (s *mysqlNotifications) Answer(ctx context.Context, id int64, status string, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notification
+		SET status = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND status = 'pending'`,
+		status, timestamp(), updatedBy, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlNotifications) DeletePending(ctx context.Context, id, senderID int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM notification
+		WHERE id = ? AND sender = ? AND status = 'pending'`,
+		id, senderID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type mysqlSessions struct{ db querier }
+
+func (s *mysqlSessions) Create(ctx context.Context, session *models.Session, expiresAt time.Time) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	expires := formatTime(expiresAt)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO session (id, uid, refresh_hash, device_name, user_agent, ip_address, last_seen_at, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, session.UserID, session.RefreshHash, nullable(session.DeviceName), nullable(session.UserAgent),
+		nullable(session.IPAddress), now, expires, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	session.ID = id
+	session.LastSeenAt = now
+	session.ExpiresAt = expires
+	session.CreatedAt = now
+	session.UpdatedAt = now
+	return nil
+}
+
+func (s *mysqlSessions) GetActive(ctx context.Context, id int64) (*models.Session, error) {
+	return s.get(ctx, `id = ?`, id)
+}
+
+func (s *mysqlSessions) GetActiveByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	return s.get(ctx, `(refresh_hash = ? OR previous_refresh_hash = ?)`, hash, hash)
+}
+
+const sessionColumns = `id, uid, refresh_hash, COALESCE(previous_refresh_hash, ''),
+	COALESCE(device_name, ''), COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+	COALESCE(last_seen_at, created_at), expires_at, created_at, updated_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }, session *models.Session) error {
+	return row.Scan(
+		&session.ID, &session.UserID, &session.RefreshHash, &session.PreviousRefreshHash,
+		&session.DeviceName, &session.UserAgent, &session.IPAddress,
+		&session.LastSeenAt, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+}
+
+func (s *mysqlSessions) get(ctx context.Context, where string, args ...interface{}) (*models.Session, error) {
+	var session models.Session
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+sessionColumns+`
+		FROM session
+		WHERE `+where+` AND revoked_at IS NULL AND expires_at > ?`,
+		append(args, timestamp())...,
+	)
+	if err := scanSession(row, &session); err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (s *mysqlSessions) ListActive(ctx context.Context, userID int64) ([]models.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+`
+		FROM session
+		WHERE uid = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY COALESCE(last_seen_at, created_at) DESC, id DESC`,
+		userID, timestamp())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *mysqlSessions) Touch(ctx context.Context, id int64, ip string) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE session
+		SET last_seen_at = ?, ip_address = ?
+		WHERE id = ? AND (last_seen_at IS NULL OR last_seen_at < ?)`,
+		formatTime(now), nullable(ip), id, formatTime(now.Add(-SessionTouchInterval)))
+	return err
+}
+
+func (s *mysqlSessions) Rotate(ctx context.Context, id int64, oldHash, newHash string, expiresAt time.Time) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE session
+		SET refresh_hash = ?, previous_refresh_hash = ?, expires_at = ?, updated_at = ?
+		WHERE id = ? AND refresh_hash = ? AND revoked_at IS NULL AND expires_at > ?`,
+		newHash, oldHash, formatTime(expiresAt), now, id, oldHash, now)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlSessions) Revoke(ctx context.Context, id, userID int64) error {
+	now := timestamp()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE session
+		SET revoked_at = ?, updated_at = ?
+		WHERE id = ? AND uid = ? AND revoked_at IS NULL AND expires_at > ?`,
+		now, now, id, userID, now)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mysqlSessions) RevokeOthers(ctx context.Context, userID, keepID int64) error {
+	now := timestamp()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE session
+		SET revoked_at = ?, updated_at = ?
+		WHERE uid = ? AND id != ? AND revoked_at IS NULL`,
+		now, now, userID, keepID)
+	return err
+}
+
+func (s *mysqlSessions) RevokeAll(ctx context.Context, userID int64) error {
+	now := timestamp()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE session
+		SET revoked_at = ?, updated_at = ?
+		WHERE uid = ? AND revoked_at IS NULL`,
+		now, now, userID)
+	return err
+}
+
+type mysqlOTPs struct{ db querier }
+
+func (s *mysqlOTPs) Create(ctx context.Context, o *models.OTP, expiresAt time.Time) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	expires := formatTime(expiresAt)
+	var userID interface{}
+	if o.UserID != 0 {
+		userID = o.UserID
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO otp (id, purpose, phone_number, uid, code_hash, attempts, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?)`,
+		id, o.Purpose, o.PhoneNumber, userID, o.CodeHash, expires, now,
+	)
+	if err != nil {
+		return err
+	}
+	o.ID = id
+	o.Attempts = 0
+	o.ExpiresAt = expires
+	o.CreatedAt = now
+	return nil
+}
+
+func (s *mysqlOTPs) GetActive(ctx context.Context, phone, purpose string) (*models.OTP, error) {
+	var o models.OTP
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, purpose, phone_number, COALESCE(uid, 0), code_hash, attempts, expires_at, created_at
+		FROM otp
+		WHERE phone_number = ? AND purpose = ? AND consumed_at IS NULL AND expires_at > ?
+		ORDER BY id DESC
+		LIMIT 1`,
+		phone, purpose, timestamp(),
+	).Scan(&o.ID, &o.Purpose, &o.PhoneNumber, &o.UserID, &o.CodeHash, &o.Attempts, &o.ExpiresAt, &o.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &o, nil
+}
+
+func (s *mysqlOTPs) CountSince(ctx context.Context, phone, purpose string, since time.Time) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM otp
+		WHERE phone_number = ? AND purpose = ? AND created_at > ?`,
+		phone, purpose, formatTime(since),
+	).Scan(&n)
+	return n, err
+}
+
+func (s *mysqlOTPs) Attempt(ctx context.Context, id int64, maxAttempts int) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE otp
+		SET attempts = attempts + 1
+		WHERE id = ? AND attempts < ? AND consumed_at IS NULL`,
+		id, maxAttempts)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlOTPs) Consume(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE otp SET consumed_at = ? WHERE id = ? AND consumed_at IS NULL`,
+		timestamp(), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// storedTime reads back a DATETIME written by formatTime. The driver
+// returns it as UTC, while the stored wall clock is BDT.
+func storedTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, bdt)
+}
+
+type mysqlLoginAttempts struct{ db querier }
+
+func (s *mysqlLoginAttempts) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	var blockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT login_key, failures, last_failure_at, blocked_until, locked
+		FROM login_attempt WHERE login_key = ?`, key,
+	).Scan(&a.Key, &a.Failures, &a.LastFailureAt, &blockedUntil, &a.Locked)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	a.LastFailureAt = storedTime(a.LastFailureAt)
+	if blockedUntil.Valid {
+		a.BlockedUntil = storedTime(blockedUntil.Time)
+	}
+	return &a, nil
+}
+
+func (s *mysqlLoginAttempts) Attempt(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (*models.LoginAttempt, error) {
+	at := formatTime(now)
+	// The check and the count are one statement, and the upsert keeps the
+	// row locked in a transaction, so concurrent attempts on several
+	// instances are counted one after another
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO login_attempt (login_key, failures, last_failure_at, locked)
+		VALUES (?, 1, ?, FALSE)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(blocked_until > ?, failures, IF(last_failure_at < ? OR locked, 1, failures + 1)),
+			locked = locked AND blocked_until > ?,
+			last_failure_at = IF(blocked_until > ?, last_failure_at, ?)`,
+		key, at, at, formatTime(now.Add(-resetAfter)), at, at, at)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if a.BlockedUntil.After(now) {
+		return a, ErrConflict
+	}
+	return a, nil
+}
+
+func (s *mysqlLoginAttempts) Forgive(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE login_attempt SET failures = failures - 1
+		WHERE login_key = ? AND failures > 0`, key)
+	return err
+}
+
+func (s *mysqlLoginAttempts) Delay(ctx context.Context, key string, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE login_attempt SET blocked_until = ? WHERE login_key = ? AND NOT locked`,
+		formatTime(until), key)
+	return err
+}
+
+func (s *mysqlLoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE login_attempt SET blocked_until = ?, locked = TRUE
+		WHERE login_key = ? AND NOT locked`,
+		formatTime(until), key)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlLoginAttempts) Clear(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM login_attempt WHERE login_key = ?`, key)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type mysqlTransfers struct{ db querier }
+
+func (s *mysqlTransfers) Create(ctx context.Context, t *models.Transfer) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO property_transfer (
+			id, pid, from_uid, to_uid, nid, status,
+			created_at, created_by, updated_at, updated_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, t.PropertyID, t.FromUserID, t.ToUserID, t.NotificationID, t.Status,
+		now, t.CreatedBy, now, t.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.UpdatedBy = t.CreatedBy
+	return nil
+}
+
+const transferColumns = `id, pid, from_uid, to_uid, nid, status, created_at, created_by, updated_at, updated_by`
+
+func scanTransfer(row interface{ Scan(...interface{}) error }, t *models.Transfer) error {
+	return row.Scan(&t.ID, &t.PropertyID, &t.FromUserID, &t.ToUserID, &t.NotificationID, &t.Status,
+		&t.CreatedAt, &t.CreatedBy, &t.UpdatedAt, &t.UpdatedBy)
+}
+
+func (s *mysqlTransfers) GetByNotification(ctx context.Context, notificationID int64) (*models.Transfer, error) {
+	var t models.Transfer
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+transferColumns+`
+		FROM property_transfer
+		WHERE nid = ?`, notificationID)
+	if err := scanTransfer(row, &t); err != nil {
+		return nil, notFound(err)
+	}
+	return &t, nil
+}
+
+func (s *mysqlTransfers) HasPending(ctx context.Context, propertyID int64) (bool, error) {
+	var pending bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM property_transfer
+			WHERE pid = ? AND status = 'pending'
+		)`, propertyID).Scan(&pending)
+	return pending, err
+}
+
+func (s *mysqlTransfers) SetStatus(ctx context.Context, id int64, status string, updatedBy int64) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE property_transfer
+		SET status = ?, updated_at = ?, updated_by = ?
+		WHERE id = ? AND status = 'pending'`,
+		status, timestamp(), updatedBy, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mysqlTransfers) ListForProperty(ctx context.Context, propertyID int64) ([]models.Transfer, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+transferColumns+`
+		FROM property_transfer
+		WHERE pid = ?
+		ORDER BY created_at DESC, id DESC`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.Transfer
+	for rows.Next() {
+		var t models.Transfer
+		if err := scanTransfer(rows, &t); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+type mysqlAudit struct{ db querier }
+
+func (s *mysqlAudit) Record(ctx context.Context, e *models.AuditEntry) error {
+	id, err := utils.NextID()
+	if err != nil {
+		return err
+	}
+	now := timestamp()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (id, pid, actor, action, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, e.PropertyID, e.ActorID, e.Action, nullable(e.Details), now,
+	)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	e.CreatedAt = now
+	return nil
+}
+
+// idTables are the tables whose primary key comes from utils.NextID
+var idTables = []string{
+	"user", "property", "takes_care_of", "floor", "unit", "tenancy", "lease",
+	"payment", "notification", "session", "otp", "property_transfer", "audit_log",
+}
+
+type mysqlIDs struct{ db querier }
+
+func (s *mysqlIDs) Max(ctx context.Context) (int64, error) {
+	maxima := make([]string, len(idTables))
+	for i, table := range idTables {
+		maxima[i] = "SELECT MAX(id) AS id FROM " + table
+	}
+	var id sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MAX(id) FROM (`+strings.Join(maxima, " UNION ALL ")+`) AS ids`).Scan(&id)
+	return id.Int64, err
+}
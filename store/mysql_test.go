@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-rent/migrations"
+	"go-rent/models"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// The MySQL tests run the queries against a real schema. They need a
+// database migrated by this tree, given as a go-sql-driver/mysql DSN with
+// parseTime=true in GORENT_TEST_DSN, and are skipped without one.
+func testMySQL(t *testing.T) *Store {
+	t.Helper()
+	dsn := os.Getenv("GORENT_TEST_DSN")
+	if dsn == "" {
+		t.Skip("GORENT_TEST_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return NewMySQL(db)
+}
+
+func TestMySQLLeases(t *testing.T) {
+	s := testMySQL(t)
+	ctx := context.Background()
+
+	// Phone numbers are unique, so every run signs up new users
+	suffix := time.Now().UnixNano() % 100000000
+	var users [2]models.User
+	for i := range users {
+		users[i] = models.User{Name: "Lease test", PhoneNumber: fmt.Sprintf("+880171%08d", suffix+int64(i)), Password: "hash"}
+		if err := s.Users.Create(ctx, &users[i]); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+	}
+	owner, tenant := users[0].ID, users[1].ID
+
+	property := models.Property{Name: "Rose Villa", Address: "Dhanmondi", CreatedBy: owner}
+	if err := s.Properties.Create(ctx, &property); err != nil {
+		t.Fatalf("creating property: %v", err)
+	}
+	floor := models.Floor{PropertyID: property.ID, Name: "1A", CreatedBy: owner}
+	if err := s.Floors.Create(ctx, &floor); err != nil {
+		t.Fatalf("creating floor: %v", err)
+	}
+	unit := models.Unit{PropertyID: property.ID, FloorID: floor.ID, Name: "1A", Rent: 8000, CreatedBy: owner}
+	if err := s.Units.Create(ctx, &unit); err != nil {
+		t.Fatalf("creating unit: %v", err)
+	}
+	tenancy := models.Tenancy{UnitID: unit.ID, FloorID: floor.ID, PropertyID: property.ID, TenantID: tenant,
+		StartDate: "2026-01-01", Rent: 8000, Status: models.TenancyActive, CreatedBy: owner}
+	if err := s.Tenancies.Create(ctx, &tenancy); err != nil {
+		t.Fatalf("creating tenancy: %v", err)
+	}
+
+	lease := models.Lease{TenancyID: tenancy.ID, UnitID: unit.ID, FloorID: floor.ID, PropertyID: property.ID, TenantID: tenant,
+		StartDate: "2026-01-01", EndDate: "2026-12-31", Rent: 8000, BillingDay: 5, CreatedBy: owner}
+	if err := s.Leases.Create(ctx, &lease); err != nil {
+		t.Fatalf("creating lease: %v", err)
+	}
+	got, err := s.Leases.Get(ctx, property.ID, floor.ID, lease.ID)
+	if err != nil || got.TenantID != tenant || got.StartDate != lease.StartDate || got.EndDate != lease.EndDate {
+		t.Fatalf("stored lease is %+v, %v", got, err)
+	}
+
+	leases, err := s.Leases.ListByTenant(ctx, property.ID, floor.ID, tenant)
+	if err != nil || len(leases) != 1 || leases[0].ID != lease.ID {
+		t.Errorf("leases of the tenant: %+v, %v", leases, err)
+	}
+	if leases, err = s.Leases.ListByTenant(ctx, property.ID, floor.ID, owner); err != nil || len(leases) != 0 {
+		t.Errorf("leases of someone else: %+v, %v", leases, err)
+	}
+	if leases, err = s.Leases.ListByFloor(ctx, property.ID, floor.ID); err != nil || len(leases) != 1 {
+		t.Errorf("leases of the floor: %+v, %v", leases, err)
+	}
+
+	if clash, err := s.Leases.Overlapping(ctx, unit.ID, 0, "2026-12-31", "2027-12-30"); err != nil || clash.ID != lease.ID {
+		t.Errorf("lease sharing the last day: got %+v, %v", clash, err)
+	}
+	if _, err := s.Leases.Overlapping(ctx, unit.ID, 0, "2027-01-01", "2027-12-31"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("renewal after the lease: got %v, want ErrNotFound", err)
+	}
+
+	lease.EndDate = "2026-06-30"
+	lease.UpdatedBy = owner
+	if err := s.Leases.Update(ctx, &lease); err != nil {
+		t.Fatalf("updating lease: %v", err)
+	}
+	if got, err = s.Leases.Get(ctx, property.ID, floor.ID, lease.ID); err != nil || got.EndDate != "2026-06-30" {
+		t.Errorf("updated lease is %+v, %v", got, err)
+	}
+	if err := s.Leases.Delete(ctx, property.ID, floor.ID, lease.ID, owner); err != nil {
+		t.Fatalf("deleting lease: %v", err)
+	}
+	if leases, err = s.Leases.ListByTenant(ctx, property.ID, floor.ID, tenant); err != nil || len(leases) != 0 {
+		t.Errorf("leases of the tenant after deleting: %+v, %v", leases, err)
+	}
+}
+
+func TestMySQLFloorRestore(t *testing.T) {
+	s := testMySQL(t)
+	ctx := context.Background()
+	owner := models.User{Name: "Restore test", PhoneNumber: fmt.Sprintf("+880181%08d", time.Now().UnixNano()%100000000), Password: "hash"}
+	if err := s.Users.Create(ctx, &owner); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	property := models.Property{Name: "Rose Villa", Address: "Dhanmondi", CreatedBy: owner.ID}
+	if err := s.Properties.Create(ctx, &property); err != nil {
+		t.Fatalf("creating property: %v", err)
+	}
+	archived := models.Floor{PropertyID: property.ID, Name: "1A", CreatedBy: owner.ID}
+	if err := s.Floors.Create(ctx, &archived); err != nil {
+		t.Fatalf("creating floor: %v", err)
+	}
+	if err := s.Floors.Archive(ctx, property.ID, archived.ID, owner.ID); err != nil {
+		t.Fatalf("archiving floor: %v", err)
+	}
+	reused := models.Floor{PropertyID: property.ID, Name: "1a", CreatedBy: owner.ID}
+	if err := s.Floors.Create(ctx, &reused); err != nil {
+		t.Fatalf("creating floor: %v", err)
+	}
+
+	restore := func() error {
+		return s.WithTx(ctx, func(tx *Store) error {
+			return tx.Floors.Restore(ctx, property.ID, archived.ID, owner.ID)
+		})
+	}
+	if err := restore(); !errors.Is(err, ErrConflict) {
+		t.Errorf("restoring over an active name: got %v, want ErrConflict", err)
+	}
+	if err := s.Floors.Archive(ctx, property.ID, reused.ID, owner.ID); err != nil {
+		t.Fatalf("archiving floor: %v", err)
+	}
+	if err := restore(); err != nil {
+		t.Errorf("restoring: %v", err)
+	}
+	if err := restore(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring an active floor: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMySQLMaxID(t *testing.T) {
+	s := testMySQL(t)
+	ctx := context.Background()
+	user := models.User{Name: "ID test", PhoneNumber: fmt.Sprintf("+880191%08d", time.Now().UnixNano()%100000000), Password: "hash"}
+	if err := s.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	if max, err := s.IDs.Max(ctx); err != nil || max < user.ID {
+		t.Errorf("largest ID: got %d, %v, want at least %d", max, err, user.ID)
+	}
+}
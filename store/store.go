@@ -0,0 +1,330 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-rent/models"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a lookup matches no row
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write would violate the current state,
+	// e.g. assigning a tenant to a unit that is already occupied
+	ErrConflict = errors.New("conflict")
+)
+
+// Store groups the repositories used by the handlers
+type Store struct {
+	Users         UserStore
+	Properties    PropertyStore
+	Floors        FloorStore
+	Units         UnitStore
+	Tenancies     TenancyStore
+	Leases        LeaseStore
+	Payments      PaymentStore
+	Notifications NotificationStore
+	Sessions      SessionStore
+	OTPs          OTPStore
+	LoginAttempts LoginAttemptStore
+	Transfers     TransferStore
+	Audit         AuditStore
+	IDs           IDStore
+
+	tx txRunner
+}
+
+// IDStore looks at the primary keys of every table
+type IDStore interface {
+	// Max returns the largest primary key of any table, 0 while they are
+	// all empty. It is the last ID utils.NextID handed out.
+	Max(ctx context.Context) (int64, error)
+}
+
+// UserStore persists rows of the user table
+type UserStore interface {
+	// Create inserts the user and sets u.ID
+	Create(ctx context.Context, u *models.User) error
+	// Get returns the user without the password hash
+	Get(ctx context.Context, id int64) (*models.User, error)
+	// UpdateProfile writes name, email, NID and manager flag of the user
+	UpdateProfile(ctx context.Context, u *models.User) error
+	// GetByPhone returns the user including the password hash
+	GetByPhone(ctx context.Context, phone string) (*models.User, error)
+	// ListWithPhone returns every verified user that has a phone number,
+	// newest ID first
+	ListWithPhone(ctx context.Context) ([]models.User, error)
+	// UpdateUnverified overwrites the name, email, NID, password and manager
+	// flag of a user whose phone number is not verified yet, ErrConflict if
+	// it is
+	UpdateUnverified(ctx context.Context, u *models.User) error
+	// Verify marks the phone number of the user as verified, ErrConflict if
+	// it already is
+	Verify(ctx context.Context, id int64) error
+	// SetPassword replaces the password hash of the user
+	SetPassword(ctx context.Context, id int64, hash string) error
+	// SetPhone changes the phone number of the user, ErrConflict if another
+	// user has it
+	SetPhone(ctx context.Context, id int64, phone string) error
+	// DeleteUnverified removes a registration for the phone number that was
+	// never verified, if there is one
+	DeleteUnverified(ctx context.Context, phone string) error
+}
+
+// PropertyStore persists properties and their members (takes_care_of).
+// Deleted properties are left out of every lookup.
+type PropertyStore interface {
+	// Create inserts the property and sets p.ID
+	Create(ctx context.Context, p *models.Property) error
+	// Update writes the name and address of the property and sets
+	// p.UpdatedAt, ErrNotFound if it doesn't exist
+	Update(ctx context.Context, p *models.Property) error
+	// Delete marks the property as deleted, ErrNotFound if it doesn't exist
+	// and ErrConflict while a unit has a tenant or a pending tenant request
+	Delete(ctx context.Context, id, deletedBy int64) error
+	// AddMember gives the user a role on the property, ErrConflict if the
+	// user already has one
+	AddMember(ctx context.Context, propertyID, userID int64, role string, createdBy int64) error
+	// Role returns the user's role on the property, ErrNotFound if the user
+	// is not a member
+	Role(ctx context.Context, propertyID, userID int64) (string, error)
+	// SetRole changes the role of a member, ErrNotFound if the user is not
+	// a member
+	SetRole(ctx context.Context, propertyID, userID int64, role string, updatedBy int64) error
+	// GetForUser returns the property if the user manages it or rents a unit in it
+	GetForUser(ctx context.Context, propertyID, userID int64) (*models.Property, error)
+	// ListMembers returns the members of the property, longest-standing first
+	ListMembers(ctx context.Context, propertyID int64) ([]models.Member, error)
+	// RemoveMember takes the user's role on the property away, ErrNotFound
+	// if the user is not a member and ErrConflict if they are its last owner
+	RemoveMember(ctx context.Context, propertyID, userID int64) error
+	// ListManaged returns the properties the user is a member of, with Role set
+	ListManaged(ctx context.Context, userID int64) ([]models.Property, error)
+	ListTenanted(ctx context.Context, userID int64) ([]models.Property, error)
+}
+
+// FloorStore persists floors. Archived floors are left out of every lookup
+// except ListArchived.
+type FloorStore interface {
+	// Create inserts the floor and sets f.ID
+	Create(ctx context.Context, f *models.Floor) error
+	Get(ctx context.Context, propertyID, floorID int64) (*models.Floor, error)
+	// ListByProperty returns the floors newest first
+	ListByProperty(ctx context.Context, propertyID int64) ([]models.Floor, error)
+	// ListNames returns the names of the property's floors. Inside WithTx
+	// it also keeps other units of work from adding floors to the property
+	// until this one ends.
+	ListNames(ctx context.Context, propertyID int64) ([]string, error)
+	// ListArchived returns the archived floors of the property, most
+	// recently archived first
+	ListArchived(ctx context.Context, propertyID int64) ([]models.Floor, error)
+	// Update writes the name of the floor
+	Update(ctx context.Context, f *models.Floor) error
+	// Archive marks the floor as deleted, ErrNotFound if it doesn't exist
+	// and ErrConflict while one of its units has a tenant or a pending
+	// tenant request
+	Archive(ctx context.Context, propertyID, floorID, archivedBy int64) error
+	// Restore brings an archived floor back, ErrNotFound if the property
+	// has no such archived floor and ErrConflict if an active floor has its
+	// name, ignoring case. Inside WithTx no floor of that name can be added
+	// until the unit of work ends, see ListNames.
+	Restore(ctx context.Context, propertyID, floorID, restoredBy int64) error
+}
+
+// UnitStore persists the units of floors. Units of archived floors are
+// left out of every lookup.
+type UnitStore interface {
+	// Create inserts the unit and sets u.ID
+	Create(ctx context.Context, u *models.Unit) error
+	Get(ctx context.Context, propertyID, floorID, unitID int64) (*models.Unit, error)
+	// ListByProperty returns the units of the property oldest first, with
+	// PendingNotificationID set
+	ListByProperty(ctx context.Context, propertyID int64) ([]models.Unit, error)
+	// ListByFloor returns the units of the floor oldest first, with
+	// PendingNotificationID set
+	ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Unit, error)
+	// Update writes name and rent of the unit. The tenant is only changed
+	// by AssignTenant and RemoveTenant.
+	Update(ctx context.Context, u *models.Unit) error
+	// AssignTenant sets the tenant of an empty unit, ErrConflict if it is occupied
+	AssignTenant(ctx context.Context, unitID, tenantID, updatedBy int64) error
+	// RemoveTenant clears the tenant of a unit, ErrNotFound if it has none
+	RemoveTenant(ctx context.Context, propertyID, floorID, unitID, updatedBy int64) error
+	// ListOccupied returns every unit that has a tenant
+	ListOccupied(ctx context.Context) ([]models.Unit, error)
+}
+
+// TenancyStore persists tenancies, the occupancy history of the units
+type TenancyStore interface {
+	// Create inserts the tenancy and sets t.ID
+	Create(ctx context.Context, t *models.Tenancy) error
+	Get(ctx context.Context, propertyID, floorID, tenancyID int64) (*models.Tenancy, error)
+	// End closes the active tenancy of the unit on endDate, ErrNotFound if
+	// it has none
+	End(ctx context.Context, unitID int64, endDate string, updatedBy int64) error
+	// ListByFloor returns the tenancies of the units of the floor, latest
+	// start first, with TenantName and UnitName set
+	ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Tenancy, error)
+}
+
+// LeaseStore persists the leases of tenancies. Deleted leases are left out
+// of every lookup.
+type LeaseStore interface {
+	// Create inserts the lease and sets l.ID
+	Create(ctx context.Context, l *models.Lease) error
+	Get(ctx context.Context, propertyID, floorID, leaseID int64) (*models.Lease, error)
+	// ListByFloor returns the leases of the units of the floor, latest
+	// start first
+	ListByFloor(ctx context.Context, propertyID, floorID int64) ([]models.Lease, error)
+	// ListByTenant returns the leases of the floor made out to the tenant,
+	// whether or not they still live there, latest start first
+	ListByTenant(ctx context.Context, propertyID, floorID, tenantID int64) ([]models.Lease, error)
+	// Update writes the dates and terms of the lease
+	Update(ctx context.Context, l *models.Lease) error
+	// Delete marks the lease deleted, ErrNotFound if it doesn't exist
+	Delete(ctx context.Context, propertyID, floorID, leaseID, deletedBy int64) error
+	// Overlapping returns a lease of the unit other than excludeID that
+	// shares a day with start..end, ErrNotFound if there is none. In a
+	// transaction it locks the leases it looked at, so a lease inserted
+	// meanwhile can't overlap either.
+	Overlapping(ctx context.Context, unitID, excludeID int64, start, end string) (*models.Lease, error)
+}
+
+// PaymentStore persists payment records
+type PaymentStore interface {
+	// Create inserts the payment and sets p.ID
+	Create(ctx context.Context, p *models.Payment) error
+	LatestForUnit(ctx context.Context, unitID int64) (*models.Payment, error)
+}
+
+// NotificationStore persists notifications, tenant requests and
+// invitations to join a property
+type NotificationStore interface {
+	// Create inserts the notification and sets n.ID
+	Create(ctx context.Context, n *models.Notification) error
+	// GetForReceiver returns the notification if it was sent to the user
+	GetForReceiver(ctx context.Context, id, receiverID int64) (*models.Notification, error)
+	// ListForReceiver returns the user's notifications newest first,
+	// with PropertyName, FloorName and UnitName set
+	ListForReceiver(ctx context.Context, receiverID int64) ([]models.Notification, error)
+	HasPendingForUnit(ctx context.Context, unitID int64) (bool, error)
+	// HasPendingInvite reports whether the user has an unanswered
+	// invitation to join the property
+	HasPendingInvite(ctx context.Context, propertyID, receiverID int64) (bool, error)
+	// ListPendingInvites returns the unanswered invitations to join the
+	// property, newest first
+	ListPendingInvites(ctx context.Context, propertyID int64) ([]models.Notification, error)
+	// Answer moves a pending notification to status, ErrConflict if it is not pending
+	Answer(ctx context.Context, id int64, status string, updatedBy int64) error
+	// DeletePending removes a pending notification sent by senderID,
+	// ErrNotFound if there is no such notification
+	DeletePending(ctx context.Context, id, senderID int64) error
+}
+
+// SessionStore persists login sessions and their refresh tokens. A session
+// is active until it is revoked or its expiry passes.
+type SessionStore interface {
+	// Create inserts the session, valid until expiresAt, and sets s.ID
+	Create(ctx context.Context, s *models.Session, expiresAt time.Time) error
+	// GetActive returns the session, ErrNotFound if it is not active
+	GetActive(ctx context.Context, id int64) (*models.Session, error)
+	// GetActiveByRefreshHash returns the active session whose current or
+	// previous refresh token has the given hash
+	GetActiveByRefreshHash(ctx context.Context, hash string) (*models.Session, error)
+	// Rotate replaces the refresh token of an active session and extends it
+	// to expiresAt, ErrConflict if oldHash is no longer its current token
+	Rotate(ctx context.Context, id int64, oldHash, newHash string, expiresAt time.Time) error
+	// Revoke ends an active session of the user, ErrNotFound if there is none
+	Revoke(ctx context.Context, id, userID int64) error
+	// RevokeAll ends every active session of the user
+	RevokeAll(ctx context.Context, userID int64) error
+	// RevokeOthers ends every active session of the user except keepID
+	RevokeOthers(ctx context.Context, userID, keepID int64) error
+	// ListActive returns the active sessions of the user, most recently
+	// seen first
+	ListActive(ctx context.Context, userID int64) ([]models.Session, error)
+	// Touch records that the session was just used from ip. It writes at
+	// most once per SessionTouchInterval.
+	Touch(ctx context.Context, id int64, ip string) error
+}
+
+// SessionTouchInterval is how stale last_seen_at may get, so that
+// authenticated requests don't all write to the session table
+const SessionTouchInterval = time.Minute
+
+// OTPStore persists one-time codes
+type OTPStore interface {
+	// Create inserts the code, valid until expiresAt, and sets o.ID
+	Create(ctx context.Context, o *models.OTP, expiresAt time.Time) error
+	// GetActive returns the newest unused, unexpired code sent to the phone
+	// number for purpose, ErrNotFound if there is none
+	GetActive(ctx context.Context, phone, purpose string) (*models.OTP, error)
+	// CountSince returns how many codes were sent to the phone number for
+	// purpose since the given time
+	CountSince(ctx context.Context, phone, purpose string, since time.Time) (int, error)
+	// Attempt counts a guess at the code, ErrConflict if maxAttempts
+	// guesses were already made or the code has been used
+	Attempt(ctx context.Context, id int64, maxAttempts int) error
+	// Consume marks the code as used, ErrConflict if it already was
+	Consume(ctx context.Context, id int64) error
+}
+
+// LoginAttemptStore counts failed logins per key, see models.LoginAttempt
+type LoginAttemptStore interface {
+	// Get returns the failures counted for key, ErrNotFound if there are none
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// Attempt counts a login at now, before its password is checked, and
+	// returns the updated record. If key has to wait until after now
+	// nothing is counted and the record is returned with ErrConflict.
+	// Earlier attempts are forgotten if the last one is older than
+	// resetAfter or a lockout has run out. Inside WithTx the record stays
+	// locked until the unit of work ends, so a Delay or Lock that follows
+	// applies before the next attempt on key is counted.
+	Attempt(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (*models.LoginAttempt, error)
+	// Forgive takes back one attempt of key, after its login succeeded
+	Forgive(ctx context.Context, key string) error
+	// Delay makes key wait until the given time before its next login,
+	// unless it is locked
+	Delay(ctx context.Context, key string, until time.Time) error
+	// Lock locks key out until the given time, ErrConflict if it already is
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Clear forgets the failures of key, ErrNotFound if there are none
+	Clear(ctx context.Context, key string) error
+}
+
+// TransferStore persists ownership transfers
+type TransferStore interface {
+	// Create inserts the transfer and sets t.ID
+	Create(ctx context.Context, t *models.Transfer) error
+	// GetByNotification returns the transfer that the notification asks the
+	// recipient to accept, ErrNotFound if there is none
+	GetByNotification(ctx context.Context, notificationID int64) (*models.Transfer, error)
+	// HasPending reports whether the property has a transfer waiting for an
+	// answer
+	HasPending(ctx context.Context, propertyID int64) (bool, error)
+	// SetStatus moves a pending transfer to status, ErrConflict if it is
+	// not pending
+	SetStatus(ctx context.Context, id int64, status string, updatedBy int64) error
+	// ListForProperty returns the transfers of the property newest first
+	ListForProperty(ctx context.Context, propertyID int64) ([]models.Transfer, error)
+}
+
+// AuditStore appends to the audit log. Entries are never changed.
+type AuditStore interface {
+	// Record inserts the entry and sets e.ID
+	Record(ctx context.Context, e *models.AuditEntry) error
+}
+
+var bdt = time.FixedZone("BDT", 6*60*60)
+
+// timestamp returns the current time in the format stored in created_at/updated_at
+func timestamp() string {
+	return formatTime(time.Now())
+}
+
+// formatTime formats t like timestamp. Strings in this format sort in time
+// order, which the in-memory store relies on.
+func formatTime(t time.Time) string {
+	return t.In(bdt).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// txRunner starts a unit of work for a Store
+type txRunner interface {
+	run(ctx context.Context, fn func(tx *Store) error) error
+}
+
+// WithTx runs fn as one unit of work. Every repository of the Store passed
+// to fn takes part in it: if fn returns an error or panics nothing it wrote
+// is kept, otherwise all of it is committed. Calling WithTx on the Store
+// passed to fn just runs the inner function inside the same unit of work.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) error {
+	return s.tx.run(ctx, fn)
+}
+
+type mysqlTx struct{ db *sql.DB }
+
+func (t mysqlTx) run(ctx context.Context, fn func(tx *Store) error) (err error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	txStore := newMySQL(tx)
+	txStore.tx = nestedTx{txStore}
+	if err = fn(txStore); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+// nestedTx joins the unit of work that is already running
+type nestedTx struct{ s *Store }
+
+func (t nestedTx) run(ctx context.Context, fn func(tx *Store) error) error {
+	return fn(t.s)
+}
+
+type memoryTx struct {
+	m     *memoryDB
+	store *Store
+}
+
+// run serializes units of work and restores a snapshot of every table if fn
+// fails. Writes made outside WithTx while fn runs are lost on rollback,
+// which is acceptable for a store meant for tests.
+func (t memoryTx) run(ctx context.Context, fn func(tx *Store) error) (err error) {
+	t.m.txMu.Lock()
+	defer t.m.txMu.Unlock()
+
+	snapshot := t.m.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			t.m.restore(snapshot)
+			panic(p)
+		}
+		if err != nil {
+			t.m.restore(snapshot)
+		}
+	}()
+
+	txStore := *t.store
+	txStore.tx = nestedTx{&txStore}
+	return fn(&txStore)
+}
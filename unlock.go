@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rent/config"
+	"go-rent/handlers"
+	"go-rent/store"
+	"log"
+	"os"
+)
+
+const unlockUsage = `usage: go-rent unlock <phone number|IP address>
+
+Lifts the lockout and login backoff of a phone number (+880 XXXX-XXXXXX or
++880XXXXXXXXXX) or a client IP address after failed logins.`
+
+// runUnlock implements the "unlock" subcommand
+func runUnlock(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, unlockUsage)
+		os.Exit(2)
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := config.InitDB(cfg.Database); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	db, err := config.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to get database connection: %v", err)
+	}
+	handlers.SetStore(store.NewMySQL(db))
+
+	err = handlers.UnlockLogin(context.Background(), args[0])
+	if errors.Is(err, store.ErrNotFound) {
+		fmt.Printf("%s has no failed logins\n", args[0])
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to unlock: %v", err)
+	}
+	fmt.Printf("Unlocked %s\n", args[0])
+}
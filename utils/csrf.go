@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 )
@@ -18,7 +19,12 @@ func GenerateCSRFToken() (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-// ValidateCSRFToken validates if the provided token matches the expected token
+// ValidateCSRFToken validates if the provided token matches the expected
+// token. The comparison takes the same time wherever the tokens differ, and
+// an empty token never matches.
 func ValidateCSRFToken(providedToken, expectedToken string) bool {
-	return providedToken == expectedToken
-} 
\ No newline at end of file
+	if providedToken == "" || expectedToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(providedToken), []byte(expectedToken)) == 1
+}
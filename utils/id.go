@@ -1,16 +1,125 @@
 package utils
 
 import (
-	"crypto/rand"
-	"math/big"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 )
 
-// GenerateRandomID generates a random 7-digit number
-func GenerateRandomID() (int64, error) {
-	max := big.NewInt(8999999)
-	n, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return 0, err
+// IDGenerator hands out primary keys. Implementations must never return the
+// same ID twice and must be safe for concurrent use.
+type IDGenerator interface {
+	NextID() (int64, error)
+}
+
+var idGenerator IDGenerator = &Snowflake{}
+
+// SetIDGenerator replaces the generator used by NextID. It must be called
+// before any row is inserted.
+func SetIDGenerator(g IDGenerator) {
+	idGenerator = g
+}
+
+// NextID returns a new primary key from the configured generator
+func NextID() (int64, error) {
+	return idGenerator.NextID()
+}
+
+// Snowflake IDs are laid out as 41 bits of milliseconds since idEpoch, 5 bits
+// of node and 7 bits of sequence. That is 53 bits, so IDs stay exact when
+// the Flutter client decodes them as JSON numbers on the web. Each node can
+// issue 128 IDs per millisecond for about 69 years.
+const (
+	nodeBits     = 5
+	sequenceBits = 7
+	timeBits     = 41
+
+	// MaxNodeID is the largest node number a Snowflake accepts
+	MaxNodeID   = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+	maxMillis   = 1<<timeBits - 1
+)
+
+// idEpoch is the zero point of the timestamp. Every ID issued after it is
+// far above 9,999,999, the largest of the 7-digit random IDs used before, so
+// old rows keep their IDs and can never collide with new ones.
+var idEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var errIDOverflow = errors.New("ID timestamp is out of range")
+
+// Snowflake generates time-ordered IDs that are unique as long as no two
+// processes share a node number. The zero value is node 0.
+type Snowflake struct {
+	mu       sync.Mutex
+	node     int64
+	last     int64
+	sequence int64
+
+	// now is time.Now unless a test replaces it
+	now func() time.Time
+}
+
+// NewSnowflake returns a generator for the given node, 0 to MaxNodeID
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNodeID {
+		return nil, fmt.Errorf("snowflake node must be between 0 and %d", MaxNodeID)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// NextID returns the next ID. If the clock goes backwards or the sequence
+// runs out within one millisecond, it keeps counting from the last
+// timestamp it used instead of waiting, so IDs only ever increase.
+func (s *Snowflake) NextID() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.millis()
+	if now > s.last {
+		s.last = now
+		s.sequence = 0
+	} else if s.sequence < maxSequence {
+		s.sequence++
+	} else {
+		s.last++
+		s.sequence = 0
+	}
+
+	if s.last < 0 || s.last > maxMillis {
+		return 0, errIDOverflow
+	}
+	return s.last<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence, nil
+}
+
+// Resume makes s issue only IDs above lastID, the largest ID handed out
+// before this process started. Without it a clock that was set back across
+// a restart would hand out timestamps that are already taken. If the clock
+// is not past the timestamp of lastID yet, Resume waits for it, or fails if
+// that would take longer than maxWait.
+func (s *Snowflake) Resume(lastID int64, maxWait time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := lastID >> (nodeBits + sequenceBits)
+	if behind := time.Duration(last-s.millis()) * time.Millisecond; behind >= 0 {
+		if behind > maxWait {
+			return fmt.Errorf("clock is %v behind the last issued ID", behind)
+		}
+		time.Sleep(behind + time.Millisecond)
+	}
+	if last > s.last {
+		s.last = last
+		s.sequence = maxSequence
+	}
+	return nil
+}
+
+// millis returns the milliseconds since idEpoch
+func (s *Snowflake) millis() int64 {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
 	}
-	return n.Int64() + 1000000, nil
-} 
\ No newline at end of file
+	return now().Sub(idEpoch).Milliseconds()
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedClock returns a clock that stays at t until it is moved
+func fixedClock(t time.Time) (now func() time.Time, set func(time.Time)) {
+	var mu sync.Mutex
+	now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return t
+	}
+	set = func(to time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		t = to
+	}
+	return now, set
+}
+
+func nextID(t *testing.T, s *Snowflake) int64 {
+	t.Helper()
+	id, err := s.NextID()
+	if err != nil {
+		t.Fatalf("NextID: %v", err)
+	}
+	return id
+}
+
+// split takes an ID apart into its milliseconds, node and sequence
+func split(id int64) (millis, node, sequence int64) {
+	return id >> (nodeBits + sequenceBits), id >> sequenceBits & MaxNodeID, id & maxSequence
+}
+
+func TestSnowflakeIncreases(t *testing.T) {
+	s, _ := NewSnowflake(3)
+	last := nextID(t, s)
+	if last <= 9999999 {
+		t.Errorf("first ID %d is in the range of the old 7-digit IDs", last)
+	}
+	if last >= 1<<53 {
+		t.Errorf("ID %d isn't exact as a JSON number", last)
+	}
+	for i := 0; i < 10000; i++ {
+		id := nextID(t, s)
+		if id <= last {
+			t.Fatalf("ID %d after %d", id, last)
+		}
+		last = id
+	}
+}
+
+func TestSnowflakeConcurrentIDsAreUnique(t *testing.T) {
+	s, _ := NewSnowflake(0)
+	const workers, perWorker = 8, 2000
+	ids := make(chan int64, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				id, err := s.NextID()
+				if err != nil {
+					t.Errorf("NextID: %v", err)
+					return
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool, workers*perWorker)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("ID %d was issued twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSnowflakeNode(t *testing.T) {
+	now, _ := fixedClock(idEpoch.Add(time.Hour))
+	for _, node := range []int64{0, 1, 17, MaxNodeID} {
+		s, err := NewSnowflake(node)
+		if err != nil {
+			t.Fatalf("node %d: %v", node, err)
+		}
+		s.now = now
+		millis, got, sequence := split(nextID(t, s))
+		if got != node || millis != time.Hour.Milliseconds() || sequence != 0 {
+			t.Errorf("node %d: got millis %d, node %d, sequence %d", node, millis, got, sequence)
+		}
+	}
+	for _, node := range []int64{-1, MaxNodeID + 1} {
+		if _, err := NewSnowflake(node); err == nil {
+			t.Errorf("node %d was accepted", node)
+		}
+	}
+}
+
+func TestSnowflakeSequenceOverflow(t *testing.T) {
+	start := idEpoch.Add(time.Hour)
+	now, set := fixedClock(start)
+	s, _ := NewSnowflake(5)
+	s.now = now
+
+	// The sequence runs out within one millisecond, after which the
+	// generator borrows the next one instead of waiting
+	var last int64
+	for i := int64(0); i <= maxSequence+1; i++ {
+		id := nextID(t, s)
+		millis, node, sequence := split(id)
+		wantMillis, wantSequence := time.Hour.Milliseconds(), i
+		if i > maxSequence {
+			wantMillis, wantSequence = wantMillis+1, 0
+		}
+		if millis != wantMillis || node != 5 || sequence != wantSequence || id <= last {
+			t.Fatalf("ID %d: got millis %d, node %d, sequence %d after %d", i, millis, node, sequence, last)
+		}
+		last = id
+	}
+
+	// A clock set back in the running process can't repeat an ID either
+	set(start.Add(-time.Minute))
+	if id := nextID(t, s); id <= last {
+		t.Errorf("ID %d after the clock went back, last was %d", id, last)
+	}
+}
+
+func TestSnowflakeResume(t *testing.T) {
+	// The last run issued IDs up to 50ms ahead of this clock
+	ahead := time.Since(idEpoch).Milliseconds() + 50
+	lastID := ahead<<(nodeBits+sequenceBits) | maxSequence
+
+	s, _ := NewSnowflake(0)
+	if err := s.Resume(lastID, time.Millisecond); err == nil {
+		t.Errorf("Resume didn't refuse a clock behind by more than it may wait")
+	}
+	start := time.Now()
+	if err := s.Resume(lastID, time.Second); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if millis := time.Since(idEpoch).Milliseconds(); millis <= ahead {
+		t.Errorf("Resume returned after %v with the clock at %d, not past %d", time.Since(start), millis, ahead)
+	}
+	if id := nextID(t, s); id <= lastID {
+		t.Errorf("ID %d after resuming from %d", id, lastID)
+	}
+
+	// An older last ID doesn't hold the generator back
+	s, _ = NewSnowflake(0)
+	if err := s.Resume(1<<(nodeBits+sequenceBits), 0); err != nil {
+		t.Errorf("Resume after an old ID: %v", err)
+	}
+}
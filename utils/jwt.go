@@ -8,21 +8,52 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("your-secret-key") // In production, use environment variable
+var (
+	jwtKey     []byte
+	tokenTTL   = 15 * time.Minute
+	refreshTTL = 30 * 24 * time.Hour
+)
+
+var errNoJWTKey = errors.New("JWT signing key not configured")
+
+// InitJWT sets the key used to sign and verify tokens, how long issued
+// access tokens stay valid and how long a session lasts without being
+// refreshed. It must be called before any token is generated.
+func InitJWT(key []byte, ttl, refresh time.Duration) {
+	jwtKey = key
+	tokenTTL = ttl
+	refreshTTL = refresh
+}
+
+// TokenTTL returns how long newly issued tokens stay valid
+func TokenTTL() time.Duration {
+	return tokenTTL
+}
+
+// RefreshTTL returns how long newly issued refresh tokens stay valid
+func RefreshTTL() time.Duration {
+	return refreshTTL
+}
 
 type Claims struct {
 	UserID int64 `json:"user_id"`
+	// SessionID is the server-side session the token was issued for
+	SessionID int64 `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for the given user ID
-func GenerateToken(userID int64) (string, error) {
-	// Set expiration time to 24 hours from now
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateToken creates a new JWT token for the given user and session
+func GenerateToken(userID, sessionID int64) (string, error) {
+	if len(jwtKey) == 0 {
+		return "", errNoJWTKey
+	}
+
+	expirationTime := time.Now().Add(tokenTTL)
 
 	// Create the Claims
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -41,8 +72,11 @@ func GenerateToken(userID int64) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates the JWT token and returns the user ID
-func ValidateToken(tokenString string) (int64, error) {
+// ValidateToken validates the JWT token and returns its claims
+func ValidateToken(tokenString string) (*Claims, error) {
+	if len(jwtKey) == 0 {
+		return nil, errNoJWTKey
+	}
 	claims := &Claims{}
 
 	// Parse the token
@@ -54,12 +88,12 @@ func ValidateToken(tokenString string) (int64, error) {
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return 0, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 } 
\ No newline at end of file